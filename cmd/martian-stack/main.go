@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -44,6 +45,13 @@ var (
 	flagVersion = flag.Bool("version", false, "Print version and exit")
 )
 
+// subcommands run on existing projects instead of generating a new one.
+var subcommands = map[string]func(args []string) error{
+	"migrate":  runMigrate,
+	"generate": runGenerate,
+	"routes":   runRoutes,
+}
+
 // ProjectConfig holds all user choices for project generation.
 type ProjectConfig struct {
 	ProjectName string
@@ -74,6 +82,18 @@ type ProjectConfig struct {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				if !errors.Is(err, flag.ErrHelp) {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	}
+
 	flag.Parse()
 
 	if *flagVersion {
@@ -416,11 +436,7 @@ func generate(cfg ProjectConfig) error {
 		{"handlers/routes.go", tplRoutes, true},
 		{"database/database.go", tplDatabase, cfg.HasDatabase},
 		{"database/migrations/migrations.go", tplMigrations, cfg.HasDatabase},
-		{"database/migrations/001_initial.go", tplMigration001Accounts, cfg.HasDatabase && cfg.HasAuth},
-		{"database/migrations/001_initial.go", tplMigration001Example, cfg.HasDatabase && !cfg.HasAuth},
-		{"database/migrations/002_token_tables.go", tplMigration002, cfg.HasDatabase && cfg.HasAuth},
-		{"handlers/auth.go", tplAuthRoutes, cfg.HasAuth},
-		{"handlers/admin.go", tplAdminRoutes, cfg.HasAdmin},
+		{"database/migrations/001_initial.go", tplMigration001Example, cfg.HasDatabase},
 	}
 
 	for _, f := range files {
@@ -437,7 +453,7 @@ func generate(cfg ProjectConfig) error {
 	return nil
 }
 
-func renderAndWrite(path, tplStr string, data ProjectConfig) error {
+func renderAndWrite(path, tplStr string, data any) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return err
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -80,11 +81,7 @@ func allTemplates() []struct {
 		{"tplRoutes", tplRoutes},
 		{"tplDatabase", tplDatabase},
 		{"tplMigrations", tplMigrations},
-		{"tplMigration001Accounts", tplMigration001Accounts},
 		{"tplMigration001Example", tplMigration001Example},
-		{"tplMigration002", tplMigration002},
-		{"tplAuthRoutes", tplAuthRoutes},
-		{"tplAdminRoutes", tplAdminRoutes},
 	}
 }
 
@@ -155,16 +152,7 @@ func TestTemplateRendering(t *testing.T) {
 				for _, tt := range dbTemplates {
 					renderTemplate(t, tt.tpl, tc.cfg)
 				}
-				if tc.cfg.HasAuth {
-					renderTemplate(t, tplMigration001Accounts, tc.cfg)
-					renderTemplate(t, tplMigration002, tc.cfg)
-					renderTemplate(t, tplAuthRoutes, tc.cfg)
-				} else {
-					renderTemplate(t, tplMigration001Example, tc.cfg)
-				}
-				if tc.cfg.HasAdmin {
-					renderTemplate(t, tplAdminRoutes, tc.cfg)
-				}
+				renderTemplate(t, tplMigration001Example, tc.cfg)
 			}
 		})
 	}
@@ -177,7 +165,7 @@ func TestTemplateContentMain(t *testing.T) {
 		assert.Contains(t, out, `"github.com/test/testapp/database"`)
 		assert.Contains(t, out, `"github.com/test/testapp/database/migrations"`)
 		assert.Contains(t, out, `database.Connect()`)
-		assert.Contains(t, out, `migrations.Run(db)`)
+		assert.Contains(t, out, `migrations.Run(db, srv.Migrations()...)`)
 	})
 
 	t.Run("omits database when db=none", func(t *testing.T) {
@@ -201,19 +189,62 @@ func TestTemplateContentMain(t *testing.T) {
 		assert.NotContains(t, out, `cache/redis`)
 	})
 
-	t.Run("includes JWT when auth enabled", func(t *testing.T) {
+	t.Run("installs the auth module when auth enabled", func(t *testing.T) {
 		out := renderTemplate(t, tplMain, baseConfig("sqlite", "memory", true, false))
 
 		assert.Contains(t, out, `jwt.DefaultConfig`)
-		assert.Contains(t, out, `auth.NewHandlers`)
-		assert.Contains(t, out, `auth.NewMiddleware`)
-		assert.Contains(t, out, `handlers.RegisterAuthRoutes`)
+		assert.Contains(t, out, `authModule := auth.NewModule(db, jwtService)`)
+		assert.Contains(t, out, `srv.Install(authModule)`)
+		assert.NotContains(t, out, `admin.NewModule`)
+
+		// the module migrations are known once the modules are installed
+		assert.Less(t, strings.Index(out, "srv.Install("), strings.Index(out, "srv.Migrations()"))
 	})
 
-	t.Run("includes admin routes when admin enabled", func(t *testing.T) {
+	t.Run("installs the admin module when admin enabled", func(t *testing.T) {
 		out := renderTemplate(t, tplMain, baseConfig("sqlite", "memory", true, true))
 
-		assert.Contains(t, out, `handlers.RegisterAdminRoutes`)
+		assert.Contains(t, out, `srv.Install(authModule, admin.NewModule(db, authModule))`)
+		assert.Contains(t, out, `"github.com/jorgefuertes/martian-stack/pkg/admin"`)
+	})
+
+	t.Run("project migrations leave the module tables to the modules", func(t *testing.T) {
+		out := renderTemplate(t, tplMigrations, baseConfig("sqlite", "memory", true, false))
+
+		assert.Contains(t, out, `m.RegisterMultiple(modules)`)
+		assert.Contains(t, out, `return auth.Migrations()`)
+		assert.NotContains(t, out, `AddTokenTables`)
+
+		out = renderTemplate(t, tplMigrations, baseConfig("sqlite", "memory", false, false))
+		assert.Contains(t, out, `return nil`)
+		assert.NotContains(t, out, `pkg/auth`)
+	})
+
+	t.Run("prints routes without migrating", func(t *testing.T) {
+		out := renderTemplate(t, tplMain, baseConfig("sqlite", "memory", true, false))
+
+		assert.Contains(t, out, `printRoutes := len(os.Args) > 1 && os.Args[1] == "routes"`)
+		assert.Contains(t, out, `srv.PrintRoutes(os.Stdout)`)
+		assert.Contains(t, out, `srv.HealthRoutes()`)
+
+		// the migrations run when the app starts, after printing the routes
+		assert.Less(t, strings.Index(out, "srv.PrintRoutes"), strings.Index(out, "a.Run("))
+	})
+
+	t.Run("starts and stops the components with the app", func(t *testing.T) {
+		out := renderTemplate(t, tplMain, baseConfig("sqlite", "redis", true, false))
+
+		assert.Contains(t, out, `a := app.New(app.WithLogger(l.From("main", "app")))`)
+		assert.Contains(t, out, `a.Add("database", app.Database(db))`)
+		assert.Contains(t, out, `}, nil), "database")`)
+		assert.Contains(t, out, `a.Add("cache", app.Closer(cacheSvc))`)
+		assert.Contains(t, out, `a.Add("server", app.HTTPServer(srv), "migrations", "cache")`)
+		assert.Contains(t, out, `a.Run(context.Background())`)
+		assert.NotContains(t, out, `defer db.Close()`)
+		assert.NotContains(t, out, `ListenAndShutdown`)
+
+		out = renderTemplate(t, tplMain, baseConfig("none", "memory", false, false))
+		assert.Contains(t, out, `a.Add("server", app.HTTPServer(srv), "cache")`)
 	})
 
 	t.Run("includes timeout middleware and time import", func(t *testing.T) {
@@ -307,17 +338,17 @@ func TestGenerateCreatesFiles(t *testing.T) {
 			baseConfig("sqlite", "memory", true, true, "cors", "recovery"),
 			[]string{
 				"main.go", "go.mod", "Makefile", ".env.example", ".gitignore",
-				"handlers/routes.go", "handlers/auth.go", "handlers/admin.go",
+				"handlers/routes.go",
 				"database/database.go", "database/migrations/migrations.go",
-				"database/migrations/001_initial.go", "database/migrations/002_token_tables.go",
+				"database/migrations/001_initial.go",
 			},
-			nil,
+			[]string{"handlers/auth.go", "handlers/admin.go", "database/migrations/002_token_tables.go"},
 		},
 		{
 			"no database",
 			baseConfig("none", "memory", false, false, "cors"),
 			[]string{"main.go", "go.mod", "Makefile", ".env.example", ".gitignore", "handlers/routes.go"},
-			[]string{"database/database.go", "handlers/auth.go", "handlers/admin.go"},
+			[]string{"database/database.go", "database/migrations/migrations.go"},
 		},
 		{
 			"db without auth",
@@ -327,13 +358,7 @@ func TestGenerateCreatesFiles(t *testing.T) {
 				"database/database.go", "database/migrations/migrations.go",
 				"database/migrations/001_initial.go",
 			},
-			[]string{"handlers/auth.go", "handlers/admin.go", "database/migrations/002_token_tables.go"},
-		},
-		{
-			"auth without admin",
-			baseConfig("mysql", "memory", true, false, "cors"),
-			[]string{"handlers/auth.go", "database/migrations/002_token_tables.go"},
-			[]string{"handlers/admin.go"},
+			nil,
 		},
 	}
 
@@ -425,3 +450,232 @@ func TestGeneratedProjectsCompile(t *testing.T) {
 		})
 	}
 }
+
+func TestMigrationName(t *testing.T) {
+	assert.Equal(t, "add_posts", migrationName("add_posts"))
+	assert.Equal(t, "add_posts_table", migrationName("Add posts-table!"))
+	assert.Empty(t, migrationName("--"))
+}
+
+func TestNewMigration(t *testing.T) {
+	dir := t.TempDir()
+	cfg := baseConfig("sqlite", "memory", true, false)
+	cfg.OutputDir = dir
+	require.NoError(t, generate(cfg))
+
+	root, err := findProjectRoot(filepath.Join(dir, "database"))
+	require.NoError(t, err)
+	assert.Equal(t, dir, root)
+
+	path, err := newMigration(root, "Add posts", 20260102030405)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "database", "migrations", "20260102030405_add_posts.go"), path)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "var Migration20260102030405 = migration.Migration{")
+	assert.Contains(t, string(content), `Name:        "add_posts",`)
+
+	all, err := os.ReadFile(filepath.Join(dir, "database", "migrations", "migrations.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(all), "\t\tInitialSchema,\n\t\tMigration20260102030405,\n\t}")
+
+	_, err = newMigration(root, "Add posts", 20260102030405)
+	assert.Error(t, err, "an existing migration must not be overwritten")
+}
+
+func TestMigrateCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping migrate command tests in short mode")
+	}
+
+	frameworkRoot, err := filepath.Abs(filepath.Join("..", ".."))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	cfg := baseConfig("sqlite", "memory", true, false)
+	cfg.OutputDir = dir
+	require.NoError(t, generate(cfg))
+
+	for _, args := range [][]string{
+		{"mod", "edit", "-replace=github.com/jorgefuertes/martian-stack=" + frameworkRoot},
+		{"mod", "tidy"},
+	} {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "go %s failed: %s", args[0], out)
+	}
+
+	_, err = newMigration(dir, "add_posts", 20260302030405)
+	require.NoError(t, err)
+
+	env := []string{"DB_DSN=" + filepath.Join(dir, "test.db")}
+	migrate := func(args ...string) string {
+		var stdout, stderr strings.Builder
+		err := runProjectMigrations(dir, args, env, &stdout, &stderr)
+		require.NoError(t, err, "migrate %v failed: %s", args, stderr.String())
+		return stdout.String()
+	}
+
+	plan := migrate("plan")
+	assert.Regexp(t, `up\s+20260302030405\s+add_posts\s+0`, plan)
+	assert.Regexp(t, `up\s+20260213000001\s+add_token_tables`, plan, "the auth module migrations must be run too")
+	assert.Contains(t, migrate("to", "1"), "applied 1 initial_schema")
+	assert.Regexp(t, `20260302030405\s+add_posts\s+pending`, migrate("status"))
+	assert.Contains(t, migrate("up"), "applied 20260302030405 add_posts")
+	assert.Regexp(t, `20260302030405\s+add_posts\s+applied`, migrate("status"))
+	assert.Equal(t, "rolled back 20260302030405 add_posts\n", migrate("down"))
+
+	err = runProjectMigrations(dir, []string{"sideways"}, env, io.Discard, io.Discard)
+	assert.Error(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.False(t, strings.HasPrefix(e.Name(), ".martian-migrate-"), "temporary program left behind")
+	}
+}
+
+func TestRoutesCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping routes command tests in short mode")
+	}
+
+	frameworkRoot, err := filepath.Abs(filepath.Join("..", ".."))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	cfg := baseConfig("sqlite", "memory", true, true, "recovery", "logging")
+	cfg.OutputDir = dir
+	require.NoError(t, generate(cfg))
+
+	for _, args := range [][]string{
+		{"mod", "edit", "-replace=github.com/jorgefuertes/martian-stack=" + frameworkRoot},
+		{"mod", "tidy"},
+	} {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "go %s failed: %s", args[0], out)
+	}
+
+	t.Setenv("DB_DSN", filepath.Join(dir, "test.db"))
+
+	var stdout, stderr strings.Builder
+	require.NoError(t, printProjectRoutes(dir, &stdout, &stderr), stderr.String())
+
+	assert.Regexp(t, `(?m)^METHOD\s+PATTERN\s+NAME\s+HANDLER\s+MIDDLEWARE$`, stdout.String())
+	assert.Regexp(
+		t,
+		`(?m)^GET\s+/admin/users\s+-\s+admin\.\(\*Handlers\)\.List\s+.*auth\.\(\*Middleware\)\.RequireAuth`,
+		stdout.String(),
+	)
+	assert.NotContains(t, stdout.String(), "starting server")
+}
+
+func TestParseResource(t *testing.T) {
+	res, err := parseResource("github.com/test/testapp", "BlogPost", []string{
+		"title:string", "body:text", "published:bool", "author_id:string", "publishedAt:time",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "BlogPost", res.Name)
+	assert.Equal(t, "BlogPosts", res.Plural)
+	assert.Equal(t, "blogPost", res.Var)
+	assert.Equal(t, "blog post", res.Human)
+	assert.Equal(t, "blog_post", res.File)
+	assert.Equal(t, "blog_posts", res.Table)
+	assert.Equal(t, "/blog-posts", res.Path)
+
+	require.Len(t, res.Fields, 5)
+	assert.Equal(t, "AuthorID", res.Fields[3].Name)
+	assert.Equal(t, "published_at", res.Fields[4].Column)
+	assert.Equal(t, "time.Time", res.Fields[4].GoType)
+	assert.Equal(t, "`json:\"title\" validate:\"required,max=255\"`", res.Fields[0].Tag)
+	assert.Equal(t, "id, created_at, updated_at, title, body, published, author_id, published_at", res.Columns())
+
+	assert.Contains(t, res.CreateTable("sqlite"), "published INTEGER NOT NULL")
+	overrides := res.Overrides()
+	require.Len(t, overrides, 2)
+	assert.Equal(t, "Postgres", overrides[0].Const)
+	assert.Contains(t, overrides[0].Up, "published BOOLEAN NOT NULL")
+	assert.Contains(t, overrides[1].Up, "published_at DATETIME NOT NULL")
+
+	for name, table := range map[string]string{"category": "categories", "box": "boxes", "day": "days", "Tag": "tags"} {
+		res, err := parseResource("m", name, []string{"name:string"})
+		require.NoError(t, err)
+		assert.Equal(t, table, res.Table)
+	}
+
+	for _, fields := range [][]string{{"title"}, {"title:blob"}, {"id:string"}, {"a:int", "a:int"}, {"1a:int"}} {
+		_, err := parseResource("m", "Post", fields)
+		assert.Error(t, err, "fields: %v", fields)
+	}
+}
+
+func TestGenerateResource(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping resource generation tests in short mode")
+	}
+
+	frameworkRoot, err := filepath.Abs(filepath.Join("..", ".."))
+	require.NoError(t, err)
+
+	for _, cfg := range []ProjectConfig{
+		baseConfig("sqlite", "memory", true, true, "cors"),
+		baseConfig("mysql", "memory", false, false, "recovery"),
+	} {
+		t.Run(cfg.Database, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg.OutputDir = dir
+			require.NoError(t, generate(cfg))
+
+			res, err := parseResource(cfg.ModulePath, "Post", []string{
+				"title:string", "body:text", "published:bool", "views:int", "score:float", "published_at:time",
+			})
+			require.NoError(t, err)
+			res.Version = 20260102030405
+
+			files, err := generateResource(dir, res)
+			require.NoError(t, err)
+			assert.Len(t, files, 5)
+
+			routes, err := os.ReadFile(filepath.Join(dir, "handlers", "routes.go"))
+			require.NoError(t, err)
+			assert.Contains(t, string(routes), `RegisterPostRoutes(srv.Group("/posts"), db)`)
+
+			all, err := os.ReadFile(filepath.Join(dir, "database", "migrations", "migrations.go"))
+			require.NoError(t, err)
+			assert.Contains(t, string(all), "\t\tCreatePosts,\n\t}")
+
+			_, err = generateResource(dir, res)
+			assert.Error(t, err, "existing files must not be overwritten")
+
+			for _, args := range [][]string{
+				{"mod", "edit", "-replace=github.com/jorgefuertes/martian-stack=" + frameworkRoot},
+				{"mod", "tidy"},
+				{"vet", "./..."},
+				{"test", "./..."},
+			} {
+				cmd := exec.Command("go", args...)
+				cmd.Dir = dir
+				out, err := cmd.CombinedOutput()
+				require.NoError(t, err, "go %s failed: %s", args[0], out)
+			}
+		})
+	}
+
+	t.Run("without database", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg := baseConfig("none", "memory", false, false)
+		cfg.OutputDir = dir
+		require.NoError(t, generate(cfg))
+
+		res, err := parseResource(cfg.ModulePath, "Post", []string{"title:string"})
+		require.NoError(t, err)
+
+		_, err = generateResource(dir, res)
+		assert.ErrorContains(t, err, "no db parameter")
+	})
+}
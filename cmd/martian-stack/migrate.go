@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+)
+
+const migrateUsage = `Usage: martian-stack migrate [flags] <command>
+
+  new <name>    create database/migrations/<version>_<name>.go and register it in All()
+` + migration.CommandUsage + `
+
+The commands run the project migrations, connecting like database.Connect does:
+DB_DRIVER and DB_DSN from the environment, or the project defaults.
+
+Flags:`
+
+// migrationsDir is where generated projects keep their migrations
+var migrationsDir = filepath.Join("database", "migrations")
+
+// runMigrate handles "martian-stack migrate ..."
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := fs.String("dir", ".", "Project directory")
+	driver := fs.String("driver", "", "Database driver, overrides DB_DRIVER")
+	dsn := fs.String("dsn", "", "Database DSN, overrides DB_DSN")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing migrate command")
+	}
+
+	root, err := findProjectRoot(*dir)
+	if err != nil {
+		return err
+	}
+
+	if fs.Arg(0) == "new" {
+		if fs.NArg() != 2 {
+			return fmt.Errorf("usage: martian-stack migrate new <name>")
+		}
+
+		path, err := newMigration(root, fs.Arg(1), migration.GenerateVersion())
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		fmt.Printf("  created %s\n", rel)
+
+		return nil
+	}
+
+	var env []string
+	if *driver != "" {
+		env = append(env, "DB_DRIVER="+*driver)
+	}
+	if *dsn != "" {
+		env = append(env, "DB_DSN="+*dsn)
+	}
+
+	return runProjectMigrations(root, fs.Args(), env, os.Stdout, os.Stderr)
+}
+
+// findProjectRoot returns the closest directory holding a go.mod, starting at dir
+func findProjectRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d, nil
+		}
+		if filepath.Dir(d) == d {
+			return "", fmt.Errorf("no go.mod found in %s or its parents", dir)
+		}
+	}
+}
+
+// modulePath reads the module path from the go.mod in root
+func modulePath(root string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`), nil
+		}
+	}
+
+	return "", fmt.Errorf("no module directive in %s", filepath.Join(root, "go.mod"))
+}
+
+var nonIdentChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// migrationName turns a free form name into snake_case
+func migrationName(name string) string {
+	return strings.Trim(nonIdentChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// newMigration writes a migration skeleton to the project migrations package
+// and registers it in All(). It returns the path of the new file.
+func newMigration(root, name string, version int64) (string, error) {
+	name = migrationName(name)
+	if name == "" {
+		return "", fmt.Errorf("invalid migration name")
+	}
+
+	dir := filepath.Join(root, migrationsDir)
+	if _, err := os.Stat(filepath.Join(dir, "migrations.go")); err != nil {
+		return "", fmt.Errorf("%s/migrations.go not found, was the project generated with a database?", migrationsDir)
+	}
+
+	src, err := format.Source([]byte(migration.TemplateWithVersion(version, name)))
+	if err != nil {
+		return "", fmt.Errorf("formatting migration: %w", err)
+	}
+
+	path := filepath.Join(dir, strconv.FormatInt(version, 10)+"_"+name+".go")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(src); err != nil {
+		return "", err
+	}
+
+	if err := registerMigration(filepath.Join(dir, "migrations.go"), "Migration"+strconv.FormatInt(version, 10)); err != nil {
+		return path, err
+	}
+
+	return path, nil
+}
+
+// runProjectMigrations runs a migrate command against the project in root. It
+// builds a small program importing the project database and migrations packages,
+// so the project connection settings, drivers and Go migrations are all honored.
+func runProjectMigrations(root string, args, env []string, stdout, stderr io.Writer) error {
+	module, err := modulePath(root)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range []string{"database", migrationsDir} {
+		if _, err := os.Stat(filepath.Join(root, pkg)); err != nil {
+			return fmt.Errorf("%s package not found, was the project generated with a database?", pkg)
+		}
+	}
+
+	tmp, err := os.MkdirTemp(root, ".martian-migrate-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	err = renderAndWrite(filepath.Join(tmp, "main.go"), tplMigrateMain, ProjectConfig{ModulePath: module})
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("go", append([]string{"run", "./" + filepath.Base(tmp)}, args...)...)
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// the program already printed the reason
+		return fmt.Errorf("migrate %s failed", strings.Join(args, " "))
+	}
+
+	return err
+}
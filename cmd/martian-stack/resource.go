@@ -0,0 +1,542 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+)
+
+const generateUsage = `Usage: martian-stack generate resource [flags] <Name> <field:type>...
+
+Generates a model, a migration, a SQL repository, CRUD handlers and their tests
+for a new resource, registering them in migrations.All() and handlers.RegisterRoutes.
+
+Field types: string, text, int, int64, float, bool, time
+
+Example:
+  martian-stack generate resource Post title:string body:text published:bool
+
+Flags:`
+
+// Resource describes a generated CRUD resource
+type Resource struct {
+	ModulePath string
+	Version    int64
+
+	Name        string // Go type name: BlogPost
+	Plural      string // BlogPosts
+	Var         string // blogPost
+	Human       string // blog post
+	HumanPlural string // blog posts
+	File        string // blog_post
+	Table       string // blog_posts
+	Path        string // /blog-posts
+
+	Fields []Field
+}
+
+// Field is a column of a generated resource
+type Field struct {
+	Name    string // Go field name: PublishedAt
+	Column  string // published_at
+	Type    string // field type as given on the command line
+	GoType  string
+	Tag     string // Go struct tag, including the backquotes
+	Example any    // sample value used by the generated tests
+}
+
+// fieldTypes maps the accepted field types to their Go type, validation and sample value
+var fieldTypes = map[string]struct {
+	goType   string
+	validate string
+	example  any
+}{
+	"string": {"string", "required,max=255", "example"},
+	"text":   {"string", "required", "Example text."},
+	"int":    {"int", "", 42},
+	"int64":  {"int64", "", 4200000000},
+	"float":  {"float64", "", 1.5},
+	"bool":   {"bool", "", true},
+	"time":   {"time.Time", "required", "2026-01-02T15:04:05Z"},
+}
+
+// reservedColumns are added to every resource
+var reservedColumns = []string{"id", "created_at", "updated_at"}
+
+// runGenerate handles "martian-stack generate ..."
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	dir := fs.String("dir", ".", "Project directory")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), generateUsage)
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 || args[0] != "resource" {
+		fs.Usage()
+		return fmt.Errorf("unknown generate command, only resource is supported")
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("a resource needs a name and at least one field")
+	}
+
+	root, err := findProjectRoot(*dir)
+	if err != nil {
+		return err
+	}
+
+	module, err := modulePath(root)
+	if err != nil {
+		return err
+	}
+
+	res, err := parseResource(module, fs.Arg(0), fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	res.Version = migration.GenerateVersion()
+
+	files, err := generateResource(root, res)
+	for _, f := range files {
+		fmt.Printf("  created %s\n", f)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n  %s registered at %s, run go mod tidy to fetch any new dependency\n", res.Name, res.Path)
+
+	return nil
+}
+
+// parseResource builds a Resource from its name and field:type arguments
+func parseResource(module, name string, fieldArgs []string) (Resource, error) {
+	words := splitWords(name)
+	if len(words) == 0 || !unicode.IsLetter(rune(words[0][0])) {
+		return Resource{}, fmt.Errorf("invalid resource name %q", name)
+	}
+
+	plural := slices.Clone(words)
+	plural[len(plural)-1] = pluralize(plural[len(plural)-1])
+
+	res := Resource{
+		ModulePath:  module,
+		Name:        pascal(words),
+		Plural:      pascal(plural),
+		Var:         words[0] + pascal(words[1:]),
+		Human:       strings.Join(words, " "),
+		HumanPlural: strings.Join(plural, " "),
+		File:        strings.Join(words, "_"),
+		Table:       strings.Join(plural, "_"),
+		Path:        "/" + strings.Join(plural, "-"),
+	}
+
+	seen := slices.Clone(reservedColumns)
+	for _, arg := range fieldArgs {
+		fieldName, typ, ok := strings.Cut(arg, ":")
+		if !ok {
+			return Resource{}, fmt.Errorf("invalid field %q, use name:type", arg)
+		}
+
+		ft, ok := fieldTypes[typ]
+		if !ok {
+			return Resource{}, fmt.Errorf("unknown type %q for field %s", typ, fieldName)
+		}
+
+		words := splitWords(fieldName)
+		if len(words) == 0 || !unicode.IsLetter(rune(words[0][0])) {
+			return Resource{}, fmt.Errorf("invalid field name %q", fieldName)
+		}
+
+		column := strings.Join(words, "_")
+		if slices.Contains(seen, column) {
+			return Resource{}, fmt.Errorf("duplicate or reserved field %s", column)
+		}
+		seen = append(seen, column)
+
+		tag := `json:"` + column + `"`
+		if ft.validate != "" {
+			tag += ` validate:"` + ft.validate + `"`
+		}
+
+		res.Fields = append(res.Fields, Field{
+			Name:    pascal(words),
+			Column:  column,
+			Type:    typ,
+			GoType:  ft.goType,
+			Tag:     "`" + tag + "`",
+			Example: ft.example,
+		})
+	}
+
+	return res, nil
+}
+
+// Columns returns the comma separated column list, in struct order
+func (r Resource) Columns() string {
+	columns := slices.Clone(reservedColumns)
+	for _, f := range r.Fields {
+		columns = append(columns, f.Column)
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+// Placeholders returns one ? per column
+func (r Resource) Placeholders() string {
+	return strings.TrimSuffix(strings.Repeat("?, ", len(reservedColumns)+len(r.Fields)), ", ")
+}
+
+// Assignments returns the SET list of the update query
+func (r Resource) Assignments() string {
+	set := []string{"updated_at = ?"}
+	for _, f := range r.Fields {
+		set = append(set, f.Column+" = ?")
+	}
+
+	return strings.Join(set, ", ")
+}
+
+// SortFields returns the quoted columns the list endpoint can sort by
+func (r Resource) SortFields() string {
+	columns := slices.Clone(reservedColumns)
+	for _, f := range r.Fields {
+		columns = append(columns, f.Column)
+	}
+
+	return `"` + strings.Join(columns, `", "`) + `"`
+}
+
+// ExampleJSON returns a request body holding a sample value for every field
+func (r Resource) ExampleJSON() string {
+	body := make(map[string]any, len(r.Fields))
+	for _, f := range r.Fields {
+		body[f.Column] = f.Example
+	}
+
+	b, _ := json.Marshal(body)
+
+	return string(b)
+}
+
+// FirstString returns the first string field, compared by the generated tests
+func (r Resource) FirstString() *Field {
+	for i, f := range r.Fields {
+		if f.GoType == "string" {
+			return &r.Fields[i]
+		}
+	}
+
+	return nil
+}
+
+// CreateTable returns the script creating the resource table for a dialect
+func (r Resource) CreateTable(dialect string) string {
+	d := database.DialectFor(dialect)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", r.Table)
+	b.WriteString("\tid VARCHAR(36) PRIMARY KEY,\n")
+	fmt.Fprintf(&b, "\tcreated_at %s NOT NULL,\n", d.TimestampType())
+	fmt.Fprintf(&b, "\tupdated_at %s NOT NULL", d.TimestampType())
+	for _, f := range r.Fields {
+		fmt.Fprintf(&b, ",\n\t%s %s NOT NULL", f.Column, columnType(d, f.Type))
+	}
+	fmt.Fprintf(&b, "\n);\n\nCREATE INDEX idx_%s_created_at ON %s(created_at);\n", r.Table, r.Table)
+
+	return b.String()
+}
+
+// DialectScript is a dialect override of the create table script
+type DialectScript struct {
+	Const string // name of the database package constant
+	Up    string
+}
+
+// Overrides returns the create table scripts of the dialects that differ from the generic (SQLite) one
+func (r Resource) Overrides() []DialectScript {
+	generic := r.CreateTable(database.SQLite)
+
+	var overrides []DialectScript
+	for _, d := range []struct{ name, constant string }{
+		{database.Postgres, "Postgres"},
+		{database.MySQL, "MySQL"},
+	} {
+		if up := r.CreateTable(d.name); up != generic {
+			overrides = append(overrides, DialectScript{Const: d.constant, Up: up})
+		}
+	}
+
+	return overrides
+}
+
+// columnType returns the SQL type of a field type for a dialect
+func columnType(d database.Dialect, typ string) string {
+	switch typ {
+	case "text":
+		return "TEXT"
+	case "int":
+		return "INTEGER"
+	case "int64":
+		return "BIGINT"
+	case "float":
+		return "DOUBLE PRECISION"
+	case "bool":
+		return d.BoolType()
+	case "time":
+		return d.TimestampType()
+	default:
+		return "VARCHAR(255)"
+	}
+}
+
+// generateResource writes the resource files and registers the migration and routes.
+// It returns the paths of the created files, relative to root.
+func generateResource(root string, res Resource) ([]string, error) {
+	routes := filepath.Join(root, "handlers", "routes.go")
+	if err := checkRoutesTakeDB(routes); err != nil {
+		return nil, err
+	}
+
+	migrations := filepath.Join(root, migrationsDir, "migrations.go")
+	if _, err := os.Stat(migrations); err != nil {
+		return nil, fmt.Errorf("%s/migrations.go not found, was the project generated with a database?", migrationsDir)
+	}
+
+	files := []struct {
+		path string
+		tpl  string
+	}{
+		{filepath.Join("models", res.File+".go"), tplResourceModel},
+		{
+			filepath.Join(migrationsDir, strconv.FormatInt(res.Version, 10)+"_create_"+res.Table+".go"),
+			tplResourceMigration,
+		},
+		{filepath.Join("repository", res.File+".go"), tplResourceRepository},
+		{filepath.Join("handlers", res.File+".go"), tplResourceHandlers},
+		{filepath.Join("handlers", res.File+"_test.go"), tplResourceHandlersTest},
+	}
+
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(root, f.path)); err == nil {
+			return nil, fmt.Errorf("%s already exists", f.path)
+		}
+	}
+
+	var created []string
+	for _, f := range files {
+		if err := renderAndWrite(filepath.Join(root, f.path), f.tpl, res); err != nil {
+			return created, fmt.Errorf("generating %s: %w", f.path, err)
+		}
+		if err := formatFile(filepath.Join(root, f.path)); err != nil {
+			return created, fmt.Errorf("generating %s: %w", f.path, err)
+		}
+		created = append(created, f.path)
+	}
+
+	if err := registerMigration(migrations, "Create"+res.Plural); err != nil {
+		return created, err
+	}
+
+	route := fmt.Sprintf("Register%sRoutes(srv.Group(%q), db)", res.Name, res.Path)
+
+	return created, registerRoute(routes, route)
+}
+
+// formatFile runs gofmt on the Go file at path
+func formatFile(path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	out, err := format.Source(src)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, out, 0o644)
+}
+
+// checkRoutesTakeDB makes sure RegisterRoutes receives the database the resources need
+func checkRoutesTakeDB(path string) error {
+	_, file, err := parseGoFile(path)
+	if err != nil {
+		return err
+	}
+
+	fn := findFunc(file, "RegisterRoutes")
+	if fn == nil {
+		return fmt.Errorf("RegisterRoutes not found in %s", path)
+	}
+
+	for _, param := range fn.Type.Params.List {
+		for _, name := range param.Names {
+			if name.Name == "db" {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("RegisterRoutes in %s has no db parameter, "+
+		"resources need a project generated with a database", path)
+}
+
+// registerMigration appends ident to the slice returned by All() in the migrations.go file at path
+func registerMigration(path, ident string) error {
+	return insertGo(path, ident+",", func(file *ast.File) token.Pos {
+		fn := findFunc(file, "All")
+		if fn == nil {
+			return token.NoPos
+		}
+
+		for _, stmt := range fn.Body.List {
+			if ret, ok := stmt.(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+				if lit, ok := ret.Results[0].(*ast.CompositeLit); ok {
+					return lit.Rbrace
+				}
+			}
+		}
+
+		return token.NoPos
+	})
+}
+
+// registerRoute appends a statement to RegisterRoutes in the routes.go file at path
+func registerRoute(path, stmt string) error {
+	return insertGo(path, stmt, func(file *ast.File) token.Pos {
+		if fn := findFunc(file, "RegisterRoutes"); fn != nil {
+			return fn.Body.Rbrace
+		}
+
+		return token.NoPos
+	})
+}
+
+// insertGo inserts code on its own line before the position returned by at,
+// then formats the file
+func insertGo(path, code string, at func(*ast.File) token.Pos) error {
+	fset, file, err := parseGoFile(path)
+	if err != nil {
+		return err
+	}
+
+	pos := at(file)
+	if !pos.IsValid() {
+		return fmt.Errorf("insertion point not found in %s, add %s by hand", path, code)
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	offset := fset.Position(pos).Offset
+	before := strings.TrimRight(string(src[:offset]), " \t\n")
+	out, err := format.Source([]byte(before + "\n" + code + "\n" + string(src[offset:])))
+	if err != nil {
+		return fmt.Errorf("adding %s to %s: %w", code, path, err)
+	}
+
+	return os.WriteFile(path, out, 0o644)
+}
+
+func parseGoFile(path string) (*token.FileSet, *ast.File, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+
+	return fset, file, err
+}
+
+func findFunc(file *ast.File, name string) *ast.FuncDecl {
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == name {
+			return fn
+		}
+	}
+
+	return nil
+}
+
+// initialisms are kept upper case in Go names
+var initialisms = []string{"id", "url", "uri", "api", "http", "json", "html", "ip", "uuid", "sql"}
+
+// splitWords splits snake_case, kebab-case, spaced and CamelCase names into lower case words
+func splitWords(s string) []string {
+	var words []string
+	var word []rune
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = nil
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(word) > 0:
+			// a new word starts at an upper case letter after a lower case one,
+			// or at the last upper case letter of an acronym followed by lower case
+			prevLower := unicode.IsLower(word[len(word)-1]) || unicode.IsDigit(word[len(word)-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	return words
+}
+
+// pascal joins lower case words in PascalCase
+func pascal(words []string) string {
+	var b strings.Builder
+	for _, w := range words {
+		if slices.Contains(initialisms, w) {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+
+	return b.String()
+}
+
+// pluralize returns the English plural of a lower case word, covering the regular cases
+func pluralize(w string) string {
+	switch {
+	case strings.HasSuffix(w, "y") && len(w) > 1 && !strings.ContainsAny(w[len(w)-2:len(w)-1], "aeiou"):
+		return w[:len(w)-1] + "ies"
+	case strings.HasSuffix(w, "s"), strings.HasSuffix(w, "x"), strings.HasSuffix(w, "z"),
+		strings.HasSuffix(w, "ch"), strings.HasSuffix(w, "sh"):
+		return w + "es"
+	default:
+		return w + "s"
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+const routesUsage = `Usage: martian-stack routes [flags]
+
+Prints the route table of the project: method, pattern, name, handler and
+middleware of every route. It runs "go run . routes", so the project connects
+to its database like it does on start, without running the migrations.
+
+Flags:`
+
+// runRoutes handles "martian-stack routes"
+func runRoutes(args []string) error {
+	fs := flag.NewFlagSet("routes", flag.ContinueOnError)
+	dir := fs.String("dir", ".", "Project directory")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), routesUsage)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	root, err := findProjectRoot(*dir)
+	if err != nil {
+		return err
+	}
+
+	return printProjectRoutes(root, os.Stdout, os.Stderr)
+}
+
+// printProjectRoutes runs the project in root in routes mode
+func printProjectRoutes(root string, stdout, stderr io.Writer) error {
+	cmd := exec.Command("go", "run", ".", "routes")
+	cmd.Dir = root
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// the program already printed the reason
+		return fmt.Errorf("listing routes failed")
+	}
+
+	return err
+}
@@ -3,6 +3,7 @@ package main
 const tplMain = `package main
 
 import (
+	"context"
 	"os"
 	"strconv"
 {{- if or .MwTimeout}}
@@ -15,11 +16,14 @@ import (
 {{- end}}
 	"{{.ModulePath}}/handlers"
 
+	"github.com/jorgefuertes/martian-stack/pkg/app"
 	"github.com/jorgefuertes/martian-stack/pkg/server"
+{{- if .HasAdmin}}
+	"github.com/jorgefuertes/martian-stack/pkg/admin"
+{{- end}}
 {{- if .HasAuth}}
 	"github.com/jorgefuertes/martian-stack/pkg/auth"
 	"github.com/jorgefuertes/martian-stack/pkg/auth/jwt"
-	"github.com/jorgefuertes/martian-stack/pkg/database/repository"
 {{- end}}
 {{- if .HasRedis}}
 	"github.com/jorgefuertes/martian-stack/pkg/service/cache/redis"
@@ -47,8 +51,15 @@ func envOrInt(key string, fallback int) int {
 }
 
 func main() {
+	// "routes" prints the route table instead of starting the server
+	printRoutes := len(os.Args) > 1 && os.Args[1] == "routes"
+
 	// Logger
 	l := logger.New(os.Stdout, logger.TextFormat, logger.LevelDebug)
+
+	// the application starts the components in dependency order
+	// and stops them in reverse on SIGINT/SIGTERM
+	a := app.New(app.WithLogger(l.From("main", "app")))
 {{- if .HasDatabase}}
 
 	// Database
@@ -57,13 +68,7 @@ func main() {
 		l.From("main", "database").Error(err.Error())
 		os.Exit(1)
 	}
-	defer db.Close()
-
-	// Migrations
-	if err := migrations.Run(db); err != nil {
-		l.From("main", "migrations").Error(err.Error())
-		os.Exit(1)
-	}
+	a.Add("database", app.Database(db))
 {{- end}}
 
 	// Cache
@@ -76,12 +81,10 @@ func main() {
 		envOr("REDIS_PASS", ""),
 		envOrInt("REDIS_DB", 0),
 	)
-	defer cacheSvc.Close()
 {{- else}}
 	cacheSvc := memory.New()
-	defer cacheSvc.Close()
 {{- end}}
-	_ = cacheSvc
+	a.Add("cache", app.Closer(cacheSvc))
 {{- if .HasAuth}}
 
 	// JWT
@@ -91,15 +94,6 @@ func main() {
 		os.Exit(1)
 	}
 	jwtService := jwt.NewService(jwtCfg)
-
-	// Repositories
-	accountRepo := repository.NewSQLAccountRepository(db)
-	refreshTokenRepo := repository.NewSQLRefreshTokenRepository(db)
-	resetTokenRepo := repository.NewSQLPasswordResetTokenRepository(db)
-
-	// Auth
-	authHandlers := auth.NewHandlers(accountRepo, jwtService, refreshTokenRepo, resetTokenRepo)
-	authMiddleware := auth.NewMiddleware(jwtService)
 {{- end}}
 
 	// Server
@@ -132,13 +126,46 @@ func main() {
 	)
 
 	// Routes
+{{- if .HasDatabase}}
+	handlers.RegisterRoutes(srv, db)
+{{- else}}
 	handlers.RegisterRoutes(srv)
+{{- end}}
 {{- if .HasAuth}}
-	handlers.RegisterAuthRoutes(srv, authHandlers, authMiddleware)
+
+	// Modules: their routes, middleware, health checks and migrations
+	authModule := auth.NewModule(db, jwtService)
+	if err := srv.Install(authModule{{if .HasAdmin}}, admin.NewModule(db, authModule){{end}}); err != nil {
+		l.From("main", "modules").Error(err.Error())
+		os.Exit(1)
+	}
 {{- end}}
-{{- if .HasAdmin}}
-	handlers.RegisterAdminRoutes(srv, authMiddleware, accountRepo)
+
+	// Health reports: /livez and /readyz, with the checks the components add
+	srv.HealthRoutes()
+{{- if .HasDatabase}}
+
+	// Migrations, the project ones and the ones of the installed modules
+	a.Add("migrations", app.Func(func(context.Context) error {
+		return migrations.Run(db, srv.Migrations()...)
+	}, nil), "database")
+{{- end}}
+
+	if printRoutes {
+		err := srv.PrintRoutes(os.Stdout)
+{{- if .HasDatabase}}
+		db.Close()
 {{- end}}
+		cacheSvc.Close()
+		if err != nil {
+			l.From("main", "routes").Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Server, after the migrations and the cache
+	a.Add("server", app.HTTPServer(srv){{if .HasDatabase}}, "migrations"{{end}}, "cache")
 
 	// Start
 	l.From("main", "server").With(
@@ -146,16 +173,9 @@ func main() {
 		"port", envOr("PORT", "{{.DefaultPort}}"),
 	).Info("starting server")
 
-	if err := srv.ListenAndShutdown(func() {
-		l.From("main", "server").Info("shutting down")
-{{- if .HasDatabase}}
-		db.Close()
-{{- end}}
-{{- if .HasRedis}}
-		cacheSvc.Close()
-{{- end}}
-	}); err != nil {
-		l.From("main", "server").Error(err.Error())
+	// Run blocks until a signal, then stops the components in reverse order
+	if err := a.Run(context.Background()); err != nil {
+		l.From("main", "app").Error(err.Error())
 		os.Exit(1)
 	}
 }
@@ -173,7 +193,7 @@ require (
 const tplMakefile = `APP_NAME := {{.ProjectName}}
 MAIN := ./main.go
 
-.PHONY: build run clean tidy
+.PHONY: build run routes clean tidy
 
 build:
 	go build -o bin/$(APP_NAME) .
@@ -181,6 +201,9 @@ build:
 run: build
 	./bin/$(APP_NAME)
 
+routes:
+	go run . routes
+
 clean:
 	rm -rf bin/
 
@@ -265,13 +288,20 @@ Thumbs.db
 const tplRoutes = `package handlers
 
 import (
+{{- if .HasDatabase}}
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+{{- end}}
 	"github.com/jorgefuertes/martian-stack/pkg/server"
 	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
 	"github.com/jorgefuertes/martian-stack/pkg/server/web"
 )
 
 // RegisterRoutes registers the application routes
+{{- if .HasDatabase}}
+func RegisterRoutes(srv *server.Server, db database.Database) {
+{{- else}}
 func RegisterRoutes(srv *server.Server) {
+{{- end}}
 	srv.Route(web.MethodGet, "/", homeHandler())
 	srv.Route(web.MethodGet, "/health", healthHandler())
 }
@@ -342,70 +372,39 @@ const tplMigrations = `package migrations
 
 import (
 	"context"
-
+{{if .HasAuth}}
+	"github.com/jorgefuertes/martian-stack/pkg/auth"
+{{- end}}
 	"github.com/jorgefuertes/martian-stack/pkg/database"
 	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
 )
 
-// Run executes all pending database migrations
-func Run(db database.Database) error {
+// Run executes all pending database migrations, the project ones
+// and the ones of the installed modules (srv.Migrations())
+func Run(db database.Database, modules ...migration.Migration) error {
 	m := migration.New(db)
 	m.RegisterMultiple(All())
+	m.RegisterMultiple(modules)
 
-	ctx := context.Background()
-	if err := m.Init(ctx); err != nil {
-		return err
-	}
-
-	return m.Up(ctx)
+	// Up creates the migrations table under the migration lock
+	return m.Up(context.Background())
 }
 
-// All returns all available migrations
+// All returns the project migrations
 func All() []migration.Migration {
 	return []migration.Migration{
 		InitialSchema,
-{{- if .HasAuth}}
-		AddTokenTables,
-{{- end}}
 	}
 }
-`
-
-const tplMigration001Accounts = `package migrations
-
-import "github.com/jorgefuertes/martian-stack/pkg/database/migration"
-
-// InitialSchema creates the initial accounts table
-var InitialSchema = migration.Migration{
-	Version:     1,
-	Name:        "initial_schema",
-	Description: "Create accounts table with indexes",
-	Up: ` + "`" + `
-CREATE TABLE IF NOT EXISTS accounts (
-	id VARCHAR(36) PRIMARY KEY,
-	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-	last_login TIMESTAMP NULL,
-	username VARCHAR(50) NOT NULL UNIQUE,
-	name VARCHAR(120) NOT NULL,
-	email VARCHAR(255) NOT NULL UNIQUE,
-	enabled BOOLEAN NOT NULL DEFAULT true,
-	role VARCHAR(10) NOT NULL DEFAULT 'user',
-	crypted_password BLOB NOT NULL
-);
 
-CREATE INDEX IF NOT EXISTS idx_accounts_username ON accounts(username);
-CREATE INDEX IF NOT EXISTS idx_accounts_email ON accounts(email);
-CREATE INDEX IF NOT EXISTS idx_accounts_enabled ON accounts(enabled);
-CREATE INDEX IF NOT EXISTS idx_accounts_role ON accounts(role);
-` + "`" + `,
-	Down: ` + "`" + `
-DROP INDEX IF EXISTS idx_accounts_role;
-DROP INDEX IF EXISTS idx_accounts_enabled;
-DROP INDEX IF EXISTS idx_accounts_email;
-DROP INDEX IF EXISTS idx_accounts_username;
-DROP TABLE IF EXISTS accounts;
-` + "`" + `,
+// Modules returns the migrations of the modules main installs,
+// for the migrate command, which runs without the server
+func Modules() []migration.Migration {
+{{- if .HasAuth}}
+	return auth.Migrations()
+{{- else}}
+	return nil
+{{- end}}
 }
 `
 
@@ -433,142 +432,582 @@ DROP TABLE IF EXISTS items;
 }
 `
 
-const tplMigration002 = `package migrations
+// tplMigrateMain is the throwaway program "martian-stack migrate" runs inside a
+// project: it connects with database.Connect and runs the project migrations.
+const tplMigrateMain = `package main
 
-import "github.com/jorgefuertes/martian-stack/pkg/database/migration"
+import (
+	"context"
+	"fmt"
+	"os"
 
-// AddTokenTables creates tables for refresh tokens and password reset tokens
-var AddTokenTables = migration.Migration{
-	Version:     2,
-	Name:        "add_token_tables",
-	Description: "Create refresh_tokens and password_reset_tokens tables",
-	Up: ` + "`" + `
-CREATE TABLE IF NOT EXISTS refresh_tokens (
-	id VARCHAR(36) PRIMARY KEY,
-	user_id VARCHAR(36) NOT NULL,
-	token_hash VARCHAR(64) NOT NULL UNIQUE,
-	expires_at TIMESTAMP NOT NULL,
-	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-	revoked_at TIMESTAMP NULL,
-	FOREIGN KEY (user_id) REFERENCES accounts(id) ON DELETE CASCADE
-);
+	"{{.ModulePath}}/database"
+	"{{.ModulePath}}/database/migrations"
 
-CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
-CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
-CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+)
 
-CREATE TABLE IF NOT EXISTS password_reset_tokens (
-	id VARCHAR(36) PRIMARY KEY,
-	user_id VARCHAR(36) NOT NULL,
-	token_hash VARCHAR(64) NOT NULL UNIQUE,
-	expires_at TIMESTAMP NOT NULL,
-	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-	used_at TIMESTAMP NULL,
-	FOREIGN KEY (user_id) REFERENCES accounts(id) ON DELETE CASCADE
-);
+func main() {
+	db, err := database.Connect()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
-CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
-CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens(token_hash);
-CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at);
-` + "`" + `,
+	m := migration.New(db)
+	m.RegisterMultiple(migrations.All())
+	m.RegisterMultiple(migrations.Modules())
+
+	err = m.RunCommand(context.Background(), os.Stdout, os.Args[1:]...)
+	db.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+`
+
+// The tplResource* templates render a Resource for "martian-stack generate resource".
+
+const tplResourceModel = `package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
+)
+
+// Err{{.Name}}NotFound is returned when a {{.Human}} does not exist
+var Err{{.Name}}NotFound = errors.New("{{.Human}} not found")
+
+// {{.Name}} is the {{.Human}} resource
+type {{.Name}} struct {
+	ID        string    ` + "`" + `json:"id"` + "`" + `
+	CreatedAt time.Time ` + "`" + `json:"created_at"` + "`" + `
+	UpdatedAt time.Time ` + "`" + `json:"updated_at"` + "`" + `
+{{- range .Fields}}
+	{{.Name}} {{.GoType}} {{.Tag}}
+{{- end}}
+}
+
+// Validate checks the {{.Human}} fields against their validate tags
+func (m {{.Name}}) Validate() error {
+	return validator.New(validator.WithRequiredStructEnabled()).Struct(m)
+}
+
+// {{.Name}}Page is a page of {{.HumanPlural}}
+type {{.Name}}Page struct {
+	adapter.PageInfo
+	{{.Plural}} []{{.Name}} ` + "`" + `json:"{{.Table}}"` + "`" + `
+}
+`
+
+const tplResourceMigration = `package migrations
+
+import (
+{{- if .Overrides}}
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+{{- end}}
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+)
+
+// Create{{.Plural}} creates the {{.Table}} table
+var Create{{.Plural}} = migration.Migration{
+	Version:     {{.Version}},
+	Name:        "create_{{.Table}}",
+	Description: "Create {{.Table}} table",
+	Up: ` + "`" + `
+{{.CreateTable "sqlite"}}` + "`" + `,
 	Down: ` + "`" + `
-DROP INDEX IF EXISTS idx_password_reset_tokens_expires_at;
-DROP INDEX IF EXISTS idx_password_reset_tokens_token_hash;
-DROP INDEX IF EXISTS idx_password_reset_tokens_user_id;
-DROP TABLE IF EXISTS password_reset_tokens;
-
-DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
-DROP INDEX IF EXISTS idx_refresh_tokens_token_hash;
-DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
-DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS {{.Table}};
 ` + "`" + `,
+{{- if .Overrides}}
+	Dialects: map[string]migration.Script{
+{{- range .Overrides}}
+		database.{{.Const}}: {
+			Up: ` + "`" + `
+{{.Up}}` + "`" + `,
+		},
+{{- end}}
+	},
+{{- end}}
 }
 `
 
-const tplAuthRoutes = `package handlers
+const tplResourceRepository = `package repository
 
 import (
-	"github.com/jorgefuertes/martian-stack/pkg/auth"
-	"github.com/jorgefuertes/martian-stack/pkg/server"
-	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"{{.ModulePath}}/models"
+
+	"github.com/google/uuid"
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
 )
 
-// RegisterAuthRoutes registers authentication routes
-func RegisterAuthRoutes(srv *server.Server, h *auth.Handlers, mw *auth.Middleware) {
-	// Public auth routes
-	pub := srv.Group("/auth")
-	pub.Route(web.MethodPost, "/login", h.Login())
-	pub.Route(web.MethodPost, "/refresh", h.Refresh())
-	pub.Route(web.MethodPost, "/password-reset/request", h.RequestPasswordReset())
-	pub.Route(web.MethodPost, "/password-reset", h.ResetPassword())
+// {{.Var}}Columns are the {{.Table}} columns, in models.{{.Name}} field order
+const {{.Var}}Columns = "{{.Columns}}"
+
+// {{.Name}}SortFields are the fields {{.HumanPlural}} can be sorted by
+var {{.Name}}SortFields = []string{ {{- .SortFields -}} }
+
+// SQL{{.Name}}Repository stores {{.HumanPlural}} in a SQL database
+type SQL{{.Name}}Repository struct {
+	db database.Database
+}
+
+// NewSQL{{.Name}}Repository creates a new SQL-based {{.Human}} repository
+func NewSQL{{.Name}}Repository(db database.Database) *SQL{{.Name}}Repository {
+	return &SQL{{.Name}}Repository{
+		db: db,
+	}
+}
+
+// Get retrieves a {{.Human}} by ID
+func (r *SQL{{.Name}}Repository) Get(ctx context.Context, id string) (*models.{{.Name}}, error) {
+	query := ` + "`" + `SELECT ` + "`" + ` + {{.Var}}Columns + ` + "`" + ` FROM {{.Table}} WHERE id = ?` + "`" + `
+
+	m, err := scan{{.Name}}(r.db.QueryRow(ctx, r.db.Dialect().Rebind(query), id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.Err{{.Name}}NotFound
+		}
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Create stores a new {{.Human}}, setting its ID and timestamps
+func (r *SQL{{.Name}}Repository) Create(ctx context.Context, m *models.{{.Name}}) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	m.ID = uuid.NewString()
+	now := time.Now()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+
+	query := ` + "`" + `INSERT INTO {{.Table}} (` + "`" + ` + {{.Var}}Columns + ` + "`" + `) VALUES ({{.Placeholders}})` + "`" + `
+
+	_, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query),
+		m.ID,
+		m.CreatedAt,
+		m.UpdatedAt,
+{{- range .Fields}}
+		m.{{.Name}},
+{{- end}}
+	)
+
+	return err
+}
+
+// Update updates an existing {{.Human}}
+func (r *SQL{{.Name}}Repository) Update(ctx context.Context, m *models.{{.Name}}) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	m.UpdatedAt = time.Now()
+
+	query := ` + "`" + `UPDATE {{.Table}} SET {{.Assignments}} WHERE id = ?` + "`" + `
+
+	result, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query),
+		m.UpdatedAt,
+{{- range .Fields}}
+		m.{{.Name}},
+{{- end}}
+		m.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return models.Err{{.Name}}NotFound
+	}
+
+	return nil
+}
+
+// Delete deletes a {{.Human}} by ID
+func (r *SQL{{.Name}}Repository) Delete(ctx context.Context, id string) error {
+	query := ` + "`" + `DELETE FROM {{.Table}} WHERE id = ?` + "`" + `
+
+	result, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return models.Err{{.Name}}NotFound
+	}
+
+	return nil
+}
+
+// List returns a page of {{.HumanPlural}}, sorted by creation date unless the query sets a sort.
+// Only offset pagination is supported, a query with a cursor fails with adapter.ErrInvalidCursor.
+func (r *SQL{{.Name}}Repository) List(ctx context.Context, q adapter.ListQuery) (*models.{{.Name}}Page, error) {
+	if q.Cursor != "" {
+		return nil, adapter.ErrInvalidCursor
+	}
+
+	sort := q.Sort
+	if len(sort) == 0 {
+		sort = []adapter.SortField{ {Field: "created_at"} }
+	}
+
+	order := make([]string, 0, len(sort)+1)
+	for _, f := range sort {
+		if !slices.Contains({{.Name}}SortFields, f.Field) {
+			return nil, adapter.ErrInvalidSort
+		}
+
+		dir := " ASC"
+		if f.Desc {
+			dir = " DESC"
+		}
+		order = append(order, f.Field+dir)
+	}
+	order = append(order, "id ASC") // tie-breaker for a stable order
+
+	page := &models.{{.Name}}Page{
+		PageInfo: adapter.PageInfo{Page: q.PageNumber(), Limit: q.PageSize()},
+		{{.Plural}}: make([]models.{{.Name}}, 0, q.PageSize()),
+	}
+
+	if err := r.db.QueryRow(ctx, ` + "`" + `SELECT COUNT(*) FROM {{.Table}}` + "`" + `).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	query := ` + "`" + `SELECT ` + "`" + ` + {{.Var}}Columns + ` + "`" + ` FROM {{.Table}} ORDER BY ` + "`" + ` + strings.Join(order, ", ") + ` + "`" + ` LIMIT ? OFFSET ?` + "`" + `
+
+	rows, err := r.db.Query(ctx, r.db.Dialect().Rebind(query), q.PageSize(), q.Offset())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m, err := scan{{.Name}}(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.{{.Plural}} = append(page.{{.Plural}}, *m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	// Protected auth routes (require authentication)
-	priv := srv.Group("/auth", mw.RequireAuth())
-	priv.Route(web.MethodPost, "/logout", h.Logout())
-	priv.Route(web.MethodGet, "/me", h.Me())
+	return page, nil
+}
+
+// scan{{.Name}} scans a row selected with {{.Var}}Columns
+func scan{{.Name}}(row interface{ Scan(dest ...any) error }) (*models.{{.Name}}, error) {
+	var m models.{{.Name}}
+
+	err := row.Scan(
+		&m.ID,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+{{- range .Fields}}
+		&m.{{.Name}},
+{{- end}}
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
 }
 `
 
-const tplAdminRoutes = `package handlers
+const tplResourceHandlers = `package handlers
 
 import (
+	"errors"
 	"net/http"
 
-	"github.com/jorgefuertes/martian-stack/pkg/auth"
-	"github.com/jorgefuertes/martian-stack/pkg/database/repository"
+	"{{.ModulePath}}/models"
+	"{{.ModulePath}}/repository"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
 	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
 	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
 	"github.com/jorgefuertes/martian-stack/pkg/server/web"
 )
 
-// RegisterAdminRoutes registers admin panel routes
-func RegisterAdminRoutes(
-	srv *server.Server,
-	mw *auth.Middleware,
-	accountRepo *repository.SQLAccountRepository,
-) {
-	admin := srv.Group("/admin", mw.RequireAuth(), mw.RequireRole("admin"))
+// Register{{.Name}}Routes registers the {{.Human}} CRUD routes on g
+func Register{{.Name}}Routes(g *server.Group, db database.Database) {
+	repo := repository.NewSQL{{.Name}}Repository(db)
 
-	// User management CRUD
-	admin.Route(web.MethodGet, "/users", listUsers(accountRepo))
-	admin.Route(web.MethodPost, "/users", createUser(accountRepo))
-	admin.Route(web.MethodGet, "/users/{id}", getUser(accountRepo))
-	admin.Route(web.MethodPut, "/users/{id}", updateUser(accountRepo))
-	admin.Route(web.MethodDelete, "/users/{id}", deleteUser(accountRepo))
+	g.Route(web.MethodGet, "", list{{.Plural}}(repo))
+	g.Route(web.MethodPost, "", create{{.Name}}(repo))
+	g.Route(web.MethodGet, "/{id}", get{{.Name}}(repo))
+	g.Route(web.MethodPut, "/{id}", update{{.Name}}(repo))
+	g.Route(web.MethodDelete, "/{id}", delete{{.Name}}(repo))
 }
 
-func listUsers(_ *repository.SQLAccountRepository) ctx.Handler {
+func list{{.Plural}}(repo *repository.SQL{{.Name}}Repository) ctx.Handler {
 	return func(c ctx.Ctx) error {
-		// TODO: implement user listing
-		return c.SendJSON(map[string]string{"message": "list users - not yet implemented"})
+		q, err := c.ListQuery()
+		if err != nil {
+			return err
+		}
+
+		page, err := repo.List(c.Context(), q)
+		if err != nil {
+			if errors.Is(err, adapter.ErrInvalidSort) || errors.Is(err, adapter.ErrInvalidCursor) {
+				return c.Error(http.StatusBadRequest, err)
+			}
+			return err
+		}
+
+		c.SetPageHeaders(page.PageInfo)
+
+		return c.SendJSON(page)
 	}
 }
 
-func createUser(_ *repository.SQLAccountRepository) ctx.Handler {
+func create{{.Name}}(repo *repository.SQL{{.Name}}Repository) ctx.Handler {
 	return func(c ctx.Ctx) error {
-		// TODO: implement user creation
-		return c.Error(http.StatusNotImplemented, "create user - not yet implemented")
+		var m models.{{.Name}}
+		if err := c.UnmarshalAndValidate(&m); err != nil {
+			return c.Error(http.StatusBadRequest, err)
+		}
+
+		if err := repo.Create(c.Context(), &m); err != nil {
+			return err
+		}
+
+		return c.WithStatus(http.StatusCreated).SendJSON(m)
 	}
 }
 
-func getUser(_ *repository.SQLAccountRepository) ctx.Handler {
+func get{{.Name}}(repo *repository.SQL{{.Name}}Repository) ctx.Handler {
 	return func(c ctx.Ctx) error {
-		// TODO: implement get user by ID
-		return c.Error(http.StatusNotImplemented, "get user - not yet implemented")
+		m, err := repo.Get(c.Context(), c.Param("id"))
+		if err != nil {
+			if errors.Is(err, models.Err{{.Name}}NotFound) {
+				return c.Error(http.StatusNotFound, err)
+			}
+			return err
+		}
+
+		return c.SendJSON(m)
 	}
 }
 
-func updateUser(_ *repository.SQLAccountRepository) ctx.Handler {
+func update{{.Name}}(repo *repository.SQL{{.Name}}Repository) ctx.Handler {
 	return func(c ctx.Ctx) error {
-		// TODO: implement user update
-		return c.Error(http.StatusNotImplemented, "update user - not yet implemented")
+		current, err := repo.Get(c.Context(), c.Param("id"))
+		if err != nil {
+			if errors.Is(err, models.Err{{.Name}}NotFound) {
+				return c.Error(http.StatusNotFound, err)
+			}
+			return err
+		}
+
+		var m models.{{.Name}}
+		if err := c.UnmarshalAndValidate(&m); err != nil {
+			return c.Error(http.StatusBadRequest, err)
+		}
+		m.ID = current.ID
+		m.CreatedAt = current.CreatedAt
+
+		if err := repo.Update(c.Context(), &m); err != nil {
+			if errors.Is(err, models.Err{{.Name}}NotFound) {
+				return c.Error(http.StatusNotFound, err)
+			}
+			return err
+		}
+
+		return c.SendJSON(m)
 	}
 }
 
-func deleteUser(_ *repository.SQLAccountRepository) ctx.Handler {
+func delete{{.Name}}(repo *repository.SQL{{.Name}}Repository) ctx.Handler {
 	return func(c ctx.Ctx) error {
-		// TODO: implement user deletion
-		return c.Error(http.StatusNotImplemented, "delete user - not yet implemented")
+		if err := repo.Delete(c.Context(), c.Param("id")); err != nil {
+			if errors.Is(err, models.Err{{.Name}}NotFound) {
+				return c.Error(http.StatusNotFound, err)
+			}
+			return err
+		}
+
+		c.WithStatus(http.StatusNoContent)
+
+		return nil
+	}
+}
+`
+
+const tplResourceHandlersTest = `package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"{{.ModulePath}}/database/migrations"
+	"{{.ModulePath}}/models"
+	"{{.ModulePath}}/repository"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+	"github.com/jorgefuertes/martian-stack/pkg/database/sqlite"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/servererror"
+)
+
+const {{.Var}}Body = {{printf "%q" .ExampleJSON}}
+
+func setup{{.Name}}Repo(t *testing.T) *repository.SQL{{.Name}}Repository {
+	t.Helper()
+
+	db, err := sqlite.NewInMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m := migration.New(db)
+	m.Register(migrations.Create{{.Plural}})
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return repository.NewSQL{{.Name}}Repository(db)
+}
+
+// serve{{.Name}} runs h for a request and returns the response and the error status, if any
+func serve{{.Name}}(t *testing.T, h ctx.Handler, method, target, id, body string) (*httptest.ResponseRecorder, int) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if id != "" {
+		req.SetPathValue("id", id)
+	}
+
+	rec := httptest.NewRecorder()
+	err := ctx.New(rec, req, h).Next()
+	if err == nil {
+		return rec, rec.Code
+	}
+
+	var e servererror.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("%s %s: %v", method, target, err)
+	}
+
+	return rec, e.Code
+}
+
+func Test{{.Name}}CRUD(t *testing.T) {
+	repo := setup{{.Name}}Repo(t)
+
+	rec, code := serve{{.Name}}(t, create{{.Name}}(repo), http.MethodPost, "{{.Path}}", "", {{.Var}}Body)
+	if code != http.StatusCreated {
+		t.Fatalf("create: got status %d", code)
+	}
+
+	var created models.{{.Name}}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" {
+		t.Fatal("create: empty ID")
+	}
+
+	rec, code = serve{{.Name}}(t, get{{.Name}}(repo), http.MethodGet, "{{.Path}}", created.ID, "")
+	if code != http.StatusOK {
+		t.Fatalf("get: got status %d", code)
+	}
+
+	var got models.{{.Name}}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != created.ID {
+		t.Fatalf("get: got ID %q, want %q", got.ID, created.ID)
+	}
+{{- with .FirstString}}
+	if got.{{.Name}} != created.{{.Name}} {
+		t.Fatalf("get: got {{.Column}} %q, want %q", got.{{.Name}}, created.{{.Name}})
+	}
+{{- end}}
+
+	rec, code = serve{{.Name}}(t, list{{.Plural}}(repo), http.MethodGet, "{{.Path}}", "", "")
+	if code != http.StatusOK {
+		t.Fatalf("list: got status %d", code)
+	}
+
+	var page models.{{.Name}}Page
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || len(page.{{.Plural}}) != 1 {
+		t.Fatalf("list: got %d of %d {{.HumanPlural}}, want 1 of 1", len(page.{{.Plural}}), page.Total)
+	}
+
+	_, code = serve{{.Name}}(t, update{{.Name}}(repo), http.MethodPut, "{{.Path}}", created.ID, {{.Var}}Body)
+	if code != http.StatusOK {
+		t.Fatalf("update: got status %d", code)
+	}
+
+	_, code = serve{{.Name}}(t, delete{{.Name}}(repo), http.MethodDelete, "{{.Path}}", created.ID, "")
+	if code != http.StatusNoContent {
+		t.Fatalf("delete: got status %d", code)
+	}
+
+	_, code = serve{{.Name}}(t, get{{.Name}}(repo), http.MethodGet, "{{.Path}}", created.ID, "")
+	if code != http.StatusNotFound {
+		t.Fatalf("get after delete: got status %d", code)
+	}
+}
+
+func Test{{.Name}}Invalid(t *testing.T) {
+	repo := setup{{.Name}}Repo(t)
+
+	_, code := serve{{.Name}}(t, create{{.Name}}(repo), http.MethodPost, "{{.Path}}", "", "not json")
+	if code != http.StatusBadRequest {
+		t.Fatalf("create: got status %d", code)
+	}
+
+	_, code = serve{{.Name}}(t, update{{.Name}}(repo), http.MethodPut, "{{.Path}}", "missing", {{.Var}}Body)
+	if code != http.StatusNotFound {
+		t.Fatalf("update: got status %d", code)
+	}
+
+	_, code = serve{{.Name}}(t, list{{.Plural}}(repo), http.MethodGet, "{{.Path}}?sort=nope", "", "")
+	if code != http.StatusBadRequest {
+		t.Fatalf("list: got status %d", code)
 	}
 }
 `
@@ -0,0 +1,338 @@
+// Package admin provides the user management handlers of the admin panel:
+// listing, creating, updating and deleting accounts and forcing logouts.
+// Every handler answers JSON, or HTML pages rendered with goht when the client
+// asks for text/html, so the same routes serve an API and a browser UI.
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jorgefuertes/martian-stack/pkg/auth"
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+)
+
+const (
+	// DefaultPrefix is the path the admin routes are registered under
+	DefaultPrefix = "/admin"
+
+	// Role is the account role required by the admin routes
+	Role = "admin"
+
+	// DefaultUserRole is the role of created accounts that set none
+	DefaultUserRole = "user"
+)
+
+// AccountRepository defines the account operations the admin handlers need.
+// Both repository.SQLAccountRepository and adapter.InMemoryAccountRepository implement it.
+type AccountRepository interface {
+	Get(ctx context.Context, id string) (*adapter.Account, error)
+	Create(ctx context.Context, a *adapter.Account) error
+	Update(ctx context.Context, a *adapter.Account) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, q adapter.AccountQuery) (*adapter.AccountPage, error)
+}
+
+// CreateUserRequest is the payload to create an account.
+// Enabled defaults to true and Role to DefaultUserRole.
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	Enabled  *bool  `json:"enabled"`
+	Password string `json:"password"`
+}
+
+// UpdateUserRequest is the payload to update an account.
+// Only the fields present are changed; a password sets a new one.
+type UpdateUserRequest struct {
+	Username *string `json:"username"`
+	Name     *string `json:"name"`
+	Email    *string `json:"email"`
+	Role     *string `json:"role"`
+	Enabled  *bool   `json:"enabled"`
+	Password *string `json:"password"`
+}
+
+// MessageResponse is the JSON answer of the actions without a resource to return
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// Handlers provides the admin user management HTTP handlers
+type Handlers struct {
+	accounts      AccountRepository
+	refreshTokens adapter.RefreshTokenRepository
+	prefix        string
+	inTx          func(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// NewHandlers creates new admin handlers
+func NewHandlers(accounts AccountRepository, refreshTokens adapter.RefreshTokenRepository) *Handlers {
+	return &Handlers{
+		accounts:      accounts,
+		refreshTokens: refreshTokens,
+		prefix:        DefaultPrefix,
+		inTx: func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		},
+	}
+}
+
+// WithTransactions makes account deletion revoke the refresh tokens and delete the
+// account atomically. The SQL repositories built on the same db join the transaction.
+func (h *Handlers) WithTransactions(db database.Database) *Handlers {
+	h.inTx = func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return database.WithTx(ctx, db, fn)
+	}
+
+	return h
+}
+
+// WithPrefix changes the path the routes are registered under, DefaultPrefix by default
+func (h *Handlers) WithPrefix(prefix string) *Handlers {
+	h.prefix = strings.TrimSuffix(prefix, "/")
+
+	return h
+}
+
+// RegisterRoutes registers the user management routes under the prefix,
+// restricted to authenticated accounts with the admin role:
+//
+//	GET    /users              list, paginated and filtered like ctx.AccountQuery
+//	POST   /users              create
+//	GET    /users/{id}         get
+//	PUT    /users/{id}         update
+//	DELETE /users/{id}         delete
+//	POST   /users/{id}/logout  revoke every refresh token of the account
+//
+// HTML forms cannot send PUT or DELETE, so POST /users/{id} updates and
+// POST /users/{id}/delete deletes too.
+func (h *Handlers) RegisterRoutes(srv *server.Server, mw *auth.Middleware) {
+	g := srv.Group(h.prefix, mw.RequireAuth(), mw.RequireRole(Role))
+
+	g.Route(web.MethodGet, "/users", h.List())
+	g.Route(web.MethodPost, "/users", h.Create())
+	g.Route(web.MethodGet, "/users/{id}", h.Get())
+	g.Route(web.MethodPut, "/users/{id}", h.Update())
+	g.Route(web.MethodPost, "/users/{id}", h.Update())
+	g.Route(web.MethodDelete, "/users/{id}", h.Delete())
+	g.Route(web.MethodPost, "/users/{id}/delete", h.Delete())
+	g.Route(web.MethodPost, "/users/{id}/logout", h.Logout())
+}
+
+// List lists the accounts, see ctx.AccountQuery for the query parameters
+func (h *Handlers) List() ctx.Handler {
+	return func(c ctx.Ctx) error {
+		q, err := c.AccountQuery()
+		if err != nil {
+			return err
+		}
+
+		page, err := h.accounts.List(c.Context(), q)
+		if err != nil {
+			return h.fail(c, err)
+		}
+
+		c.SetPageHeaders(page.PageInfo)
+
+		if wantsHTML(c) {
+			prev, next := pageLinks(c, page.PageInfo)
+
+			return render(c, UsersPage(h.prefix, q, page, prev, next))
+		}
+
+		return c.SendJSON(page)
+	}
+}
+
+// Create creates an account from a CreateUserRequest
+func (h *Handlers) Create() ctx.Handler {
+	return func(c ctx.Ctx) error {
+		req, err := createRequest(c)
+		if err != nil {
+			return c.Error(http.StatusBadRequest, "Invalid request body")
+		}
+
+		account := adapter.Account{
+			Username: req.Username,
+			Name:     req.Name,
+			Email:    req.Email,
+			Role:     req.Role,
+			Enabled:  req.Enabled == nil || *req.Enabled,
+		}
+		if account.Role == "" {
+			account.Role = DefaultUserRole
+		}
+
+		if err := account.SetPassword(req.Password); err != nil {
+			return c.Error(http.StatusBadRequest, err)
+		}
+
+		if err := h.accounts.Create(c.Context(), &account); err != nil {
+			return h.fail(c, err)
+		}
+
+		if c.IsForm() {
+			return c.Redirect(http.StatusSeeOther, h.userURL(account.ID))
+		}
+
+		return c.WithStatus(http.StatusCreated).SendJSON(account)
+	}
+}
+
+// Get returns an account
+func (h *Handlers) Get() ctx.Handler {
+	return func(c ctx.Ctx) error {
+		account, err := h.accounts.Get(c.Context(), c.Param("id"))
+		if err != nil {
+			return h.fail(c, err)
+		}
+
+		if wantsHTML(c) {
+			return render(c, UserPage(h.prefix, account))
+		}
+
+		return c.SendJSON(account)
+	}
+}
+
+// Update changes the profile, role, enabled flag or password of an account.
+// Admins cannot disable their own account or drop their own admin role.
+func (h *Handlers) Update() ctx.Handler {
+	return func(c ctx.Ctx) error {
+		account, err := h.accounts.Get(c.Context(), c.Param("id"))
+		if err != nil {
+			return h.fail(c, err)
+		}
+
+		req, err := updateRequest(c)
+		if err != nil {
+			return c.Error(http.StatusBadRequest, "Invalid request body")
+		}
+
+		if isSelf(c, account.ID) &&
+			(req.Enabled != nil && !*req.Enabled || req.Role != nil && *req.Role != Role) {
+			return c.Error(http.StatusBadRequest, "You cannot remove your own admin access")
+		}
+
+		setIf(&account.Username, req.Username)
+		setIf(&account.Name, req.Name)
+		setIf(&account.Email, req.Email)
+		setIf(&account.Role, req.Role)
+		setIf(&account.Enabled, req.Enabled)
+
+		if req.Password != nil {
+			if err := account.SetPassword(*req.Password); err != nil {
+				return c.Error(http.StatusBadRequest, err)
+			}
+		}
+
+		if err := h.accounts.Update(c.Context(), account); err != nil {
+			return h.fail(c, err)
+		}
+
+		if c.IsForm() {
+			return c.Redirect(http.StatusSeeOther, h.userURL(account.ID))
+		}
+
+		return c.SendJSON(account)
+	}
+}
+
+// Delete revokes the refresh tokens of an account and deletes it.
+// Admins cannot delete their own account.
+func (h *Handlers) Delete() ctx.Handler {
+	return func(c ctx.Ctx) error {
+		id := c.Param("id")
+		if isSelf(c, id) {
+			return c.Error(http.StatusBadRequest, "You cannot delete your own account")
+		}
+
+		err := h.inTx(c.Context(), func(txCtx context.Context) error {
+			if err := h.refreshTokens.RevokeAll(txCtx, id); err != nil {
+				return err
+			}
+
+			return h.accounts.Delete(txCtx, id)
+		})
+		if err != nil {
+			return h.fail(c, err)
+		}
+
+		if c.IsForm() {
+			return c.Redirect(http.StatusSeeOther, h.prefix+"/users")
+		}
+
+		return c.SendJSON(MessageResponse{Message: "User deleted"})
+	}
+}
+
+// Logout forces an account to log in again by revoking all its refresh tokens.
+// Access tokens already issued stay valid until they expire.
+func (h *Handlers) Logout() ctx.Handler {
+	return func(c ctx.Ctx) error {
+		account, err := h.accounts.Get(c.Context(), c.Param("id"))
+		if err != nil {
+			return h.fail(c, err)
+		}
+
+		if err := h.refreshTokens.RevokeAll(c.Context(), account.ID); err != nil {
+			return err
+		}
+
+		if c.IsForm() {
+			return c.Redirect(http.StatusSeeOther, h.userURL(account.ID))
+		}
+
+		return c.SendJSON(MessageResponse{Message: "User sessions revoked"})
+	}
+}
+
+// fail maps the repository errors to HTTP errors
+func (h *Handlers) fail(c ctx.Ctx, err error) error {
+	var validationErrs validator.ValidationErrors
+
+	switch {
+	case errors.Is(err, adapter.ErrAccountNotFound):
+		return c.Error(http.StatusNotFound, "User not found")
+	case errors.Is(err, database.ErrDuplicateKey):
+		return c.Error(http.StatusConflict, "Username or email already in use")
+	case errors.As(err, &validationErrs),
+		errors.Is(err, adapter.ErrInvalidSort),
+		errors.Is(err, adapter.ErrInvalidCursor):
+		return c.Error(http.StatusBadRequest, err)
+	default:
+		return err
+	}
+}
+
+func (h *Handlers) userURL(id string) string {
+	return h.prefix + "/users/" + id
+}
+
+// isSelf reports whether id is the account making the request
+func isSelf(c ctx.Ctx, id string) bool {
+	userID, ok := auth.GetUserIDFromContext(c)
+
+	return ok && userID == id
+}
+
+// wantsHTML reports whether the client asked explicitly for an HTML page
+func wantsHTML(c ctx.Ctx) bool {
+	return strings.Contains(c.Accept(), web.MIMETextHTML)
+}
+
+func setIf[T any](dst, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
@@ -0,0 +1,312 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/admin"
+	"github.com/jorgefuertes/martian-stack/pkg/auth"
+	"github.com/jorgefuertes/martian-stack/pkg/auth/jwt"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration/migrations"
+	"github.com/jorgefuertes/martian-stack/pkg/database/repository"
+	"github.com/jorgefuertes/martian-stack/pkg/database/sqlite"
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/servererror"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	handlers *admin.Handlers
+	accounts *repository.SQLAccountRepository
+	tokens   *repository.SQLRefreshTokenRepository
+	admin    *adapter.Account
+}
+
+func setupTestEnv(t *testing.T) testEnv {
+	db, err := sqlite.NewInMemory()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	migrator := migration.New(db)
+	migrator.RegisterMultiple(migrations.All())
+	require.NoError(t, migrator.Up(context.Background()))
+
+	env := testEnv{
+		accounts: repository.NewSQLAccountRepository(db),
+		tokens:   repository.NewSQLRefreshTokenRepository(db),
+	}
+	env.handlers = admin.NewHandlers(env.accounts, env.tokens).WithTransactions(db)
+	env.admin = env.createAccount(t, "admin", admin.Role)
+
+	return env
+}
+
+func (env testEnv) createAccount(t *testing.T, username, role string) *adapter.Account {
+	acc := &adapter.Account{
+		Username: username,
+		Name:     "Test " + username,
+		Email:    username + "@example.com",
+		Enabled:  true,
+		Role:     role,
+	}
+	require.NoError(t, acc.SetPassword("password123"))
+	require.NoError(t, env.accounts.Create(context.Background(), acc))
+
+	return acc
+}
+
+func (env testEnv) createToken(t *testing.T, userID string) {
+	_, tokenHash, err := adapter.GenerateSecureToken()
+	require.NoError(t, err)
+	require.NoError(t, env.tokens.Create(context.Background(), adapter.NewRefreshToken(userID, tokenHash, time.Hour)))
+}
+
+// request runs h as the admin account, with the id path value set when not empty
+func (env testEnv) request(
+	h ctx.Handler,
+	method, target, id, contentType, body string,
+) (*httptest.ResponseRecorder, error) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(web.HeaderContentType, contentType)
+	}
+	if id != "" {
+		req.SetPathValue("id", id)
+	}
+
+	c := ctx.New(w, req, h)
+	_ = c.Store().Set("user_id", env.admin.ID)
+
+	return w, c.Next()
+}
+
+func assertStatus(t *testing.T, err error, code int) {
+	t.Helper()
+
+	var srvErr servererror.Error
+	require.ErrorAs(t, err, &srvErr)
+	assert.Equal(t, code, srvErr.Code)
+}
+
+func TestList(t *testing.T) {
+	env := setupTestEnv(t)
+	env.createAccount(t, "alice", "user")
+	env.createAccount(t, "bobby", "user")
+
+	t.Run("json", func(t *testing.T) {
+		w, err := env.request(env.handlers.List(), http.MethodGet, "/admin/users?q=ali&sort=username", "", "", "")
+		require.NoError(t, err)
+
+		var page adapter.AccountPage
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+		assert.Equal(t, 1, page.Total)
+		require.Len(t, page.Accounts, 1)
+		assert.Equal(t, "alice", page.Accounts[0].Username)
+		assert.Equal(t, "1", w.Header().Get(web.HeaderXTotalCount))
+	})
+
+	t.Run("html", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/users?limit=2&sort=username", nil)
+		req.Header.Set(web.HeaderAccept, "text/html,application/xhtml+xml")
+
+		require.NoError(t, ctx.New(w, req, env.handlers.List()).Next())
+		assert.Equal(t, web.MIMETextHTMLCharsetUTF8, w.Header().Get(web.HeaderContentType))
+		assert.Contains(t, w.Body.String(), `href="/admin/users/`+env.admin.ID+`"`)
+		assert.Contains(t, w.Body.String(), "alice")
+		assert.NotContains(t, w.Body.String(), "bobby")
+		assert.Contains(t, w.Body.String(), `rel="next"`)
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		_, err := env.request(env.handlers.List(), http.MethodGet, "/admin/users?sort=password", "", "", "")
+		assertStatus(t, err, http.StatusBadRequest)
+	})
+}
+
+func TestCreate(t *testing.T) {
+	env := setupTestEnv(t)
+
+	t.Run("json", func(t *testing.T) {
+		body := `{"username":"carol","name":"Carol","email":"carol@example.com","password":"secret123"}`
+		w, err := env.request(env.handlers.Create(), http.MethodPost, "/admin/users", "", web.MIMEApplicationJSON, body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var acc adapter.Account
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&acc))
+		assert.NotEmpty(t, acc.ID)
+		assert.Equal(t, admin.DefaultUserRole, acc.Role)
+		assert.True(t, acc.Enabled)
+
+		stored, err := env.accounts.Get(context.Background(), acc.ID)
+		require.NoError(t, err)
+		assert.NoError(t, stored.ValidatePassword("secret123"))
+	})
+
+	t.Run("form redirects", func(t *testing.T) {
+		form := url.Values{
+			"username": {"dave"},
+			"name":     {"Dave"},
+			"email":    {"dave@example.com"},
+			"password": {"secret123"},
+			"enabled":  {"false"},
+		}
+		w, err := env.request(
+			env.handlers.Create(), http.MethodPost, "/admin/users", "", web.MIMEApplicationForm, form.Encode(),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get(web.HeaderLocation), "/admin/users/"))
+
+		id := strings.TrimPrefix(w.Header().Get(web.HeaderLocation), "/admin/users/")
+		stored, err := env.accounts.Get(context.Background(), id)
+		require.NoError(t, err)
+		assert.False(t, stored.Enabled)
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		body := `{"username":"admin","name":"Other","email":"other@example.com","password":"secret123"}`
+		_, err := env.request(env.handlers.Create(), http.MethodPost, "/admin/users", "", web.MIMEApplicationJSON, body)
+		assertStatus(t, err, http.StatusConflict)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		body := `{"username":"evelyn","name":"Eve","email":"not-an-email","password":"secret123"}`
+		_, err := env.request(env.handlers.Create(), http.MethodPost, "/admin/users", "", web.MIMEApplicationJSON, body)
+		assertStatus(t, err, http.StatusBadRequest)
+
+		body = `{"username":"evelyn","name":"Eve","email":"eve@example.com","password":"short"}`
+		_, err = env.request(env.handlers.Create(), http.MethodPost, "/admin/users", "", web.MIMEApplicationJSON, body)
+		assertStatus(t, err, http.StatusBadRequest)
+	})
+}
+
+func TestGet(t *testing.T) {
+	env := setupTestEnv(t)
+
+	w, err := env.request(env.handlers.Get(), http.MethodGet, "/admin/users/x", env.admin.ID, "", "")
+	require.NoError(t, err)
+	assert.Contains(t, w.Body.String(), `"username":"admin"`)
+	assert.NotContains(t, w.Body.String(), "password")
+
+	_, err = env.request(env.handlers.Get(), http.MethodGet, "/admin/users/x", "missing", "", "")
+	assertStatus(t, err, http.StatusNotFound)
+}
+
+func TestUpdate(t *testing.T) {
+	env := setupTestEnv(t)
+	user := env.createAccount(t, "frank", "user")
+
+	t.Run("json partial update", func(t *testing.T) {
+		body := `{"name":"Frank Renamed","role":"editor","enabled":false,"password":"newpass123"}`
+		_, err := env.request(env.handlers.Update(), http.MethodPut, "/", user.ID, web.MIMEApplicationJSON, body)
+		require.NoError(t, err)
+
+		stored, err := env.accounts.Get(context.Background(), user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "frank", stored.Username)
+		assert.Equal(t, "Frank Renamed", stored.Name)
+		assert.Equal(t, "editor", stored.Role)
+		assert.False(t, stored.Enabled)
+		assert.NoError(t, stored.ValidatePassword("newpass123"))
+	})
+
+	t.Run("form checkbox", func(t *testing.T) {
+		form := url.Values{"name": {"Frank"}, "enabled": {"true", "false"}}
+		w, err := env.request(
+			env.handlers.Update(),
+			http.MethodPost,
+			"/",
+			user.ID,
+			web.MIMEApplicationForm,
+			form.Encode(),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+
+		stored, err := env.accounts.Get(context.Background(), user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Frank", stored.Name)
+		assert.True(t, stored.Enabled)
+		assert.NoError(t, stored.ValidatePassword("newpass123"))
+	})
+
+	t.Run("cannot remove own admin access", func(t *testing.T) {
+		for _, body := range []string{`{"enabled":false}`, `{"role":"user"}`} {
+			_, err := env.request(
+				env.handlers.Update(),
+				http.MethodPut,
+				"/",
+				env.admin.ID,
+				web.MIMEApplicationJSON,
+				body,
+			)
+			assertStatus(t, err, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := env.request(env.handlers.Update(), http.MethodPut, "/", "missing", web.MIMEApplicationJSON, `{}`)
+		assertStatus(t, err, http.StatusNotFound)
+	})
+}
+
+func TestDeleteAndLogout(t *testing.T) {
+	env := setupTestEnv(t)
+	user := env.createAccount(t, "grace", "user")
+	env.createToken(t, user.ID)
+
+	_, err := env.request(env.handlers.Logout(), http.MethodPost, "/", user.ID, "", "")
+	require.NoError(t, err)
+
+	tokens, err := env.tokens.GetByUserID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.True(t, tokens[0].IsRevoked())
+
+	_, err = env.request(env.handlers.Delete(), http.MethodDelete, "/", env.admin.ID, "", "")
+	assertStatus(t, err, http.StatusBadRequest)
+
+	_, err = env.request(env.handlers.Delete(), http.MethodDelete, "/", user.ID, "", "")
+	require.NoError(t, err)
+
+	_, err = env.accounts.Get(context.Background(), user.ID)
+	assert.ErrorIs(t, err, adapter.ErrAccountNotFound)
+
+	_, err = env.request(env.handlers.Delete(), http.MethodDelete, "/", user.ID, "", "")
+	assertStatus(t, err, http.StatusNotFound)
+}
+
+func TestRequireAdminRole(t *testing.T) {
+	env := setupTestEnv(t)
+	cfg, err := jwt.DefaultConfig(strings.Repeat("s", 32))
+	require.NoError(t, err)
+	jwtService := jwt.NewService(cfg)
+	mw := auth.NewMiddleware(jwtService)
+
+	get := func(role string) error {
+		token, err := jwtService.GenerateAccessToken("id", "name", "name@example.com", role)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return ctx.New(w, req, mw.RequireAuth(), mw.RequireRole(admin.Role), env.handlers.List()).Next()
+	}
+
+	assert.NoError(t, get(admin.Role))
+	assertStatus(t, get("user"), http.StatusForbidden)
+}
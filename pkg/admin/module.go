@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"github.com/jorgefuertes/martian-stack/pkg/auth"
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/jorgefuertes/martian-stack/pkg/database/repository"
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+)
+
+// ModuleName is the name of the admin module
+const ModuleName = "admin"
+
+// Module is the admin user management as a server.Module. It depends on the auth
+// module, which creates the tables and authenticates the admins.
+//
+//	authModule := auth.NewModule(db, jwtService)
+//	srv.Install(authModule, admin.NewModule(db, authModule))
+type Module struct {
+	server.BaseModule
+
+	handlers *Handlers
+	auth     *auth.Module
+}
+
+// NewModule creates the admin module on the database, using the auth middleware of authModule
+func NewModule(db database.Database, authModule *auth.Module) *Module {
+	return &Module{
+		handlers: NewHandlers(
+			repository.NewSQLAccountRepository(db),
+			repository.NewSQLRefreshTokenRepository(db),
+		).WithTransactions(db),
+		auth: authModule,
+	}
+}
+
+// WithPrefix changes the path the routes are registered under, DefaultPrefix by default
+func (m *Module) WithPrefix(prefix string) *Module {
+	m.handlers.WithPrefix(prefix)
+
+	return m
+}
+
+func (m *Module) Name() string {
+	return ModuleName
+}
+
+func (m *Module) Dependencies() []string {
+	return []string{auth.ModuleName}
+}
+
+// Register registers the user management routes, see Handlers.RegisterRoutes
+func (m *Module) Register(srv *server.Server) error {
+	m.handlers.RegisterRoutes(srv, m.auth.Auth())
+
+	return nil
+}
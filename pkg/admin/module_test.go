@@ -0,0 +1,81 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jorgefuertes/martian-stack/pkg/admin"
+	"github.com/jorgefuertes/martian-stack/pkg/auth"
+	"github.com/jorgefuertes/martian-stack/pkg/auth/jwt"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+	"github.com/jorgefuertes/martian-stack/pkg/database/repository"
+	"github.com/jorgefuertes/martian-stack/pkg/database/sqlite"
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
+	"github.com/jorgefuertes/martian-stack/pkg/server/health"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModules(t *testing.T) {
+	db, err := sqlite.NewInMemory()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	cfg, err := jwt.DefaultConfig(strings.Repeat("s", 32))
+	require.NoError(t, err)
+
+	srv, err := server.NewWithOptions()
+	require.NoError(t, err)
+
+	authModule := auth.NewModule(db, jwt.NewService(cfg))
+	require.NoError(t, srv.Install(admin.NewModule(db, authModule), authModule))
+	assert.Equal(t, []string{auth.ModuleName, admin.ModuleName}, srv.Modules())
+
+	migrator := migration.New(db)
+	migrator.RegisterMultiple(srv.Migrations())
+	require.NoError(t, migrator.Up(context.Background()))
+
+	acc := &adapter.Account{
+		Username: "admin",
+		Name:     "Test admin",
+		Email:    "admin@example.com",
+		Enabled:  true,
+		Role:     admin.Role,
+	}
+	require.NoError(t, acc.SetPassword("password123"))
+	require.NoError(t, repository.NewSQLAccountRepository(db).Create(context.Background(), acc))
+
+	request := func(method, target, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(web.HeaderContentType, web.MIMEApplicationJSON)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := request(http.MethodPost, "/auth/login", "", `{"username":"admin","password":"password123"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var login auth.LoginResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&login))
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/auth/me", login.AccessToken, "").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/admin/users", login.AccessToken, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/admin/users", "", "").Code)
+
+	report := srv.Health().Readiness(context.Background())
+	assert.Equal(t, health.StatusUp, report.Status)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, "auth.database", report.Checks[0].Name)
+}
@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+)
+
+// createRequest reads a CreateUserRequest from a JSON or form body
+func createRequest(c ctx.Ctx) (CreateUserRequest, error) {
+	var req CreateUserRequest
+	if !c.IsForm() {
+		return req, c.UnmarshalBody(&req)
+	}
+
+	enabled, err := formBool(c, "enabled")
+	if err != nil {
+		return req, err
+	}
+
+	return CreateUserRequest{
+		Username: c.FormValue("username"),
+		Name:     c.FormValue("name"),
+		Email:    c.FormValue("email"),
+		Role:     c.FormValue("role"),
+		Enabled:  enabled,
+		Password: c.FormValue("password"),
+	}, nil
+}
+
+// updateRequest reads an UpdateUserRequest from a JSON or form body.
+// Empty form fields are left unchanged.
+func updateRequest(c ctx.Ctx) (UpdateUserRequest, error) {
+	var req UpdateUserRequest
+	if !c.IsForm() {
+		return req, c.UnmarshalBody(&req)
+	}
+
+	enabled, err := formBool(c, "enabled")
+	if err != nil {
+		return req, err
+	}
+
+	return UpdateUserRequest{
+		Username: formString(c, "username"),
+		Name:     formString(c, "name"),
+		Email:    formString(c, "email"),
+		Role:     formString(c, "role"),
+		Enabled:  enabled,
+		Password: formString(c, "password"),
+	}, nil
+}
+
+func formString(c ctx.Ctx, key string) *string {
+	if v := c.FormValue(key); v != "" {
+		return &v
+	}
+
+	return nil
+}
+
+// formBool parses a boolean form field, nil when missing. The pages pair each
+// checkbox with a following hidden "false" input, so the first value wins.
+func formBool(c ctx.Ctx, key string) (*bool, error) {
+	v := c.FormValue(key)
+	if v == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// pageLinks returns the URLs of the previous and next pages, empty when there is none
+func pageLinks(c ctx.Ctx, p adapter.PageInfo) (prev, next string) {
+	u := *c.URL()
+	q := u.Query()
+
+	link := func(key, value string) string {
+		q.Del("page")
+		q.Del("cursor")
+		q.Set(key, value)
+		u.RawQuery = q.Encode()
+
+		return u.String()
+	}
+
+	if p.Page == 0 {
+		if p.NextCursor != "" {
+			next = link("cursor", p.NextCursor)
+		}
+
+		return prev, next
+	}
+
+	if p.Page > 1 {
+		prev = link("page", strconv.Itoa(p.Page-1))
+	}
+	if p.Page*p.Limit < p.Total {
+		next = link("page", strconv.Itoa(p.Page+1))
+	}
+
+	return prev, next
+}
+
+// render writes a goht page as HTML
+func render(c ctx.Ctx, page ctx.Component) error {
+	c.SetContentType(web.MIMETextHTMLCharsetUTF8)
+
+	return c.Render(page)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
+
+// formatTime formats the account dates shown by the pages
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,299 @@
+// Code generated by GoHT - DO NOT EDIT.
+// https://github.com/stackus/goht
+
+package admin
+
+import "context"
+import "io"
+import "github.com/stackus/goht"
+import (
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
+	"github.com/jorgefuertes/martian-stack/pkg/server/view"
+)
+
+func UsersPage(prefix string, q adapter.AccountQuery, page *adapter.AccountPage, prevURL, nextURL string) goht.Template {
+	return goht.TemplateFunc(func(ctx context.Context, __w io.Writer) (__err error) {
+		__buf, __isBuf := __w.(goht.Buffer)
+		if !__isBuf {
+			__buf = goht.GetBuffer()
+			defer goht.ReleaseBuffer(__buf)
+		}
+		var __children goht.Template
+		ctx, __children = goht.PopChildren(ctx)
+		_ = __children
+		__var1 := goht.TemplateFunc(func(ctx context.Context, __w io.Writer) (__err error) {
+			__buf, __isBuf := __w.(goht.Buffer)
+			if !__isBuf {
+				__buf = goht.GetBuffer()
+				defer goht.ReleaseBuffer(__buf)
+			}
+			if _, __err = __buf.WriteString("<form method=\"get\" action=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(prefix+"/users") + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(">\n<input type=\"search\" name=\"q\" value=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(q.Search) + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(" placeholder=\"Search\"><input type=\"text\" name=\"role\" value=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(q.Role) + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(" placeholder=\"Role\"><button type=\"submit\">Search</button>\n</form>\n<p>"); __err != nil {
+				return
+			}
+			var __var2 string
+			if __var2, __err = goht.CaptureErrors(goht.EscapeString(goht.FormatString("%d", page.Total))); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(__var2); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(" users</p>\n<table>\n<thead>\n<tr>\n<th>Username</th>\n<th>Name</th>\n<th>Email</th>\n<th>Role</th>\n<th>Enabled</th>\n<th>Last login</th>\n</tr>\n</thead>\n<tbody>\n"); __err != nil {
+				return
+			}
+			for _, a := range page.Accounts {
+				if _, __err = __buf.WriteString("<tr>\n<td>\n<a href=\""); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(goht.EscapeString(prefix+"/users/"+a.ID) + "\""); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(">"); __err != nil {
+					return
+				}
+				var __var3 string
+				if __var3, __err = goht.CaptureErrors(goht.EscapeString(a.Username)); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(__var3); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString("</a>\n</td>\n<td>"); __err != nil {
+					return
+				}
+				var __var4 string
+				if __var4, __err = goht.CaptureErrors(goht.EscapeString(a.Name)); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(__var4); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString("</td>\n<td>"); __err != nil {
+					return
+				}
+				var __var5 string
+				if __var5, __err = goht.CaptureErrors(goht.EscapeString(a.Email)); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(__var5); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString("</td>\n<td>"); __err != nil {
+					return
+				}
+				var __var6 string
+				if __var6, __err = goht.CaptureErrors(goht.EscapeString(a.Role)); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(__var6); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString("</td>\n<td>"); __err != nil {
+					return
+				}
+				var __var7 string
+				if __var7, __err = goht.CaptureErrors(goht.EscapeString(yesNo(a.Enabled))); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(__var7); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString("</td>\n<td>"); __err != nil {
+					return
+				}
+				var __var8 string
+				if __var8, __err = goht.CaptureErrors(goht.EscapeString(formatTime(a.LastLogin))); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(__var8); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString("</td>\n</tr>\n"); __err != nil {
+					return
+				}
+			}
+			if _, __err = __buf.WriteString("</tbody>\n</table>\n<nav>\n"); __err != nil {
+				return
+			}
+			if prevURL != "" {
+				if _, __err = __buf.WriteString("<a href=\""); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(goht.EscapeString(prevURL) + "\""); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(" rel=\"prev\">Previous</a>\n"); __err != nil {
+					return
+				}
+			}
+			if nextURL != "" {
+				if _, __err = __buf.WriteString("<a href=\""); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(goht.EscapeString(nextURL) + "\""); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(" rel=\"next\">Next</a>\n"); __err != nil {
+					return
+				}
+			}
+			if _, __err = __buf.WriteString("</nav>\n<h2>New user</h2>\n<form method=\"post\" action=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(prefix+"/users") + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(">\n<input type=\"text\" name=\"username\" placeholder=\"Username\" required=\"required\"><input type=\"text\" name=\"name\" placeholder=\"Name\" required=\"required\"><input type=\"email\" name=\"email\" placeholder=\"Email\" required=\"required\"><input type=\"text\" name=\"role\" placeholder=\"Role\" value=\"user\"><input type=\"password\" name=\"password\" placeholder=\"Password\" required=\"required\"><label>\n<input type=\"checkbox\" name=\"enabled\" value=\"true\" checked=\"checked\">Enabled\n</label>\n<input type=\"hidden\" name=\"enabled\" value=\"false\"><button type=\"submit\">Create</button>\n</form>\n"); __err != nil {
+				return
+			}
+			if !__isBuf {
+				_, __err = io.Copy(__w, __buf)
+			}
+			return
+		})
+		if __err = view.Layout("Users").Render(goht.PushChildren(ctx, __var1), __buf); __err != nil {
+			return
+		}
+		if !__isBuf {
+			_, __err = __w.Write(__buf.Bytes())
+		}
+		return
+	})
+}
+
+func UserPage(prefix string, a *adapter.Account) goht.Template {
+	return goht.TemplateFunc(func(ctx context.Context, __w io.Writer) (__err error) {
+		__buf, __isBuf := __w.(goht.Buffer)
+		if !__isBuf {
+			__buf = goht.GetBuffer()
+			defer goht.ReleaseBuffer(__buf)
+		}
+		var __children goht.Template
+		ctx, __children = goht.PopChildren(ctx)
+		_ = __children
+		__var1 := goht.TemplateFunc(func(ctx context.Context, __w io.Writer) (__err error) {
+			__buf, __isBuf := __w.(goht.Buffer)
+			if !__isBuf {
+				__buf = goht.GetBuffer()
+				defer goht.ReleaseBuffer(__buf)
+			}
+			if _, __err = __buf.WriteString("<p>\n<a href=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(prefix+"/users") + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(">All users</a>\n</p>\n<dl>\n<dt>Created</dt>\n<dd>"); __err != nil {
+				return
+			}
+			var __var2 string
+			if __var2, __err = goht.CaptureErrors(goht.EscapeString(formatTime(a.CreatedAt))); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(__var2); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString("</dd>\n<dt>Updated</dt>\n<dd>"); __err != nil {
+				return
+			}
+			var __var3 string
+			if __var3, __err = goht.CaptureErrors(goht.EscapeString(formatTime(a.UpdatedAt))); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(__var3); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString("</dd>\n<dt>Last login</dt>\n<dd>"); __err != nil {
+				return
+			}
+			var __var4 string
+			if __var4, __err = goht.CaptureErrors(goht.EscapeString(formatTime(a.LastLogin))); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(__var4); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString("</dd>\n</dl>\n<form method=\"post\" action=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(prefix+"/users/"+a.ID) + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(">\n<label>\nUsername\n<input type=\"text\" name=\"username\" value=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(a.Username) + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(" required=\"required\"></label>\n<label>\nName\n<input type=\"text\" name=\"name\" value=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(a.Name) + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(" required=\"required\"></label>\n<label>\nEmail\n<input type=\"email\" name=\"email\" value=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(a.Email) + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(" required=\"required\"></label>\n<label>\nRole\n<input type=\"text\" name=\"role\" value=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(a.Role) + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(" required=\"required\"></label>\n<label>\nNew password\n<input type=\"password\" name=\"password\"></label>\n<label>\n<input type=\"checkbox\" name=\"enabled\" value=\"true\""); __err != nil {
+				return
+			}
+			if a.Enabled {
+				if _, __err = __buf.WriteString(" checked"); __err != nil {
+					return
+				}
+			}
+			if _, __err = __buf.WriteString(">Enabled\n</label>\n<input type=\"hidden\" name=\"enabled\" value=\"false\"><button type=\"submit\">Save</button>\n</form>\n<form method=\"post\" action=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(prefix+"/users/"+a.ID+"/logout") + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(">\n<button type=\"submit\">Log out everywhere</button>\n</form>\n<form method=\"post\" action=\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(goht.EscapeString(prefix+"/users/"+a.ID+"/delete") + "\""); __err != nil {
+				return
+			}
+			if _, __err = __buf.WriteString(">\n<button type=\"submit\">Delete</button>\n</form>\n"); __err != nil {
+				return
+			}
+			if !__isBuf {
+				_, __err = io.Copy(__w, __buf)
+			}
+			return
+		})
+		if __err = view.Layout(a.Username).Render(goht.PushChildren(ctx, __var1), __buf); __err != nil {
+			return
+		}
+		if !__isBuf {
+			_, __err = __w.Write(__buf.Bytes())
+		}
+		return
+	})
+}
@@ -0,0 +1,314 @@
+// Package app starts and stops the components of an application in dependency
+// order: database, cache, migrations, workers and HTTP servers.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrDuplicateComponent = errors.New("duplicate component")
+	ErrUnknownDependency  = errors.New("unknown dependency")
+	ErrDependencyCycle    = errors.New("dependency cycle")
+	ErrAlreadyStarted     = errors.New("app already started")
+)
+
+// Default timeouts of Run
+const (
+	DefaultStartTimeout = 30 * time.Second
+	DefaultStopTimeout  = 30 * time.Second
+)
+
+// Component is a part of the application. Start must not block: long running
+// components start their work in the background and report their failures with
+// Failer. Stop releases the component, within the context deadline.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Failer is implemented by the components that can fail after starting, like
+// servers and workers. Run stops the app when one of them fails.
+type Failer interface {
+	Failed() <-chan error
+}
+
+// App starts the components in dependency order and stops them in reverse.
+//
+//	a := app.New(app.WithLogger(l.From("main", "app")))
+//	a.Add("database", app.Database(db))
+//	a.Add("migrations", app.Func(migrate, nil), "database")
+//	a.Add("server", app.HTTPServer(srv), "migrations")
+//	if err := a.Run(context.Background()); err != nil {
+//		os.Exit(1)
+//	}
+type App struct {
+	mu           sync.Mutex
+	components   []*entry
+	started      []*entry
+	running      bool
+	logger       *slog.Logger
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+	signals      []os.Signal
+}
+
+// entry is a registered component with its dependencies
+type entry struct {
+	name      string
+	component Component
+	dependsOn []string
+}
+
+// Option configures an App.
+type Option func(a *App)
+
+// WithLogger logs the start and stop of every component.
+func WithLogger(l *slog.Logger) Option {
+	return func(a *App) {
+		a.logger = l
+	}
+}
+
+// WithStartTimeout sets how long Run waits for the components to start.
+func WithStartTimeout(d time.Duration) Option {
+	return func(a *App) {
+		a.startTimeout = d
+	}
+}
+
+// WithStopTimeout sets how long Run waits for the components to stop.
+func WithStopTimeout(d time.Duration) Option {
+	return func(a *App) {
+		a.stopTimeout = d
+	}
+}
+
+// WithSignals sets the signals stopping Run, SIGINT and SIGTERM by default.
+func WithSignals(signals ...os.Signal) Option {
+	return func(a *App) {
+		a.signals = signals
+	}
+}
+
+func New(opts ...Option) *App {
+	a := &App{
+		logger:       slog.New(slog.DiscardHandler),
+		startTimeout: DefaultStartTimeout,
+		stopTimeout:  DefaultStopTimeout,
+		signals:      []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Add registers a component that starts after the named components it depends on,
+// which can be added later. Without dependencies, components start in the order they
+// are added. It panics if the name is already registered.
+func (a *App) Add(name string, c Component, dependsOn ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.find(name) != nil {
+		panic(fmt.Errorf("%w: %s", ErrDuplicateComponent, name))
+	}
+
+	a.components = append(a.components, &entry{name: name, component: c, dependsOn: dependsOn})
+}
+
+// Start starts the components in dependency order. When one fails, the already
+// started ones are stopped in reverse order, within the stop timeout, and the
+// error names the component.
+func (a *App) Start(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.running {
+		return ErrAlreadyStarted
+	}
+
+	order, err := a.order()
+	if err != nil {
+		return err
+	}
+
+	a.running = true
+	for _, e := range order {
+		a.logger.Debug("starting", "component", e.name)
+
+		if err := e.component.Start(ctx); err != nil {
+			err = fmt.Errorf("start %s: %w", e.name, err)
+			a.logger.Error(err.Error(), "component", e.name)
+
+			stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.stopTimeout)
+			defer cancel()
+
+			return errors.Join(err, a.stop(stopCtx))
+		}
+
+		a.started = append(a.started, e)
+	}
+
+	a.logger.Info("started", "components", len(a.started))
+
+	return nil
+}
+
+// Stop stops the started components in reverse order, all of them even when some
+// fail, joining their errors.
+func (a *App) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.stop(ctx)
+}
+
+func (a *App) stop(ctx context.Context) error {
+	var errs []error
+
+	for _, e := range slices.Backward(a.started) {
+		a.logger.Debug("stopping", "component", e.name)
+
+		if err := e.component.Stop(ctx); err != nil {
+			err = fmt.Errorf("stop %s: %w", e.name, err)
+			a.logger.Error(err.Error(), "component", e.name)
+			errs = append(errs, err)
+		}
+	}
+
+	a.started = nil
+	a.running = false
+
+	return errors.Join(errs...)
+}
+
+// Run starts the app and blocks until a signal, the end of ctx or the failure of
+// a Failer component, then stops it. The start and stop are bounded by their
+// timeouts. It returns the start error, or the failure joined with the stop errors.
+func (a *App) Run(ctx context.Context) error {
+	startCtx, cancel := context.WithTimeout(ctx, a.startTimeout)
+	err := a.Start(startCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, a.signals...)
+	defer signal.Stop(quit)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	var failure error
+	select {
+	case sig := <-quit:
+		a.logger.Info("stopping", "signal", sig.String())
+	case <-ctx.Done():
+		a.logger.Info("stopping", "reason", ctx.Err().Error())
+	case failure = <-a.failures(done):
+		a.logger.Error("stopping", "error", failure.Error())
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.stopTimeout)
+	defer cancel()
+
+	return errors.Join(failure, a.Stop(stopCtx))
+}
+
+// failures merges the failures of the started Failer components until done
+func (a *App) failures(done <-chan struct{}) <-chan error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	merged := make(chan error, len(a.started))
+	for _, e := range a.started {
+		f, ok := e.component.(Failer)
+		if !ok {
+			continue
+		}
+
+		go func() {
+			select {
+			case err := <-f.Failed():
+				if err != nil {
+					merged <- fmt.Errorf("%s: %w", e.name, err)
+				}
+			case <-done:
+			}
+		}()
+	}
+
+	return merged
+}
+
+// order sorts the components so each starts after its dependencies,
+// keeping the registration order otherwise
+func (a *App) order() ([]*entry, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+
+	state := make(map[string]int, len(a.components))
+	order := make([]*entry, 0, len(a.components))
+
+	var visit func(e *entry, path []string) error
+	visit = func(e *entry, path []string) error {
+		switch state[e.name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(append(path, e.name), " -> "))
+		}
+
+		state[e.name] = visiting
+		for _, name := range e.dependsOn {
+			dep := a.find(name)
+			if dep == nil {
+				return fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, e.name, name)
+			}
+
+			if err := visit(dep, append(path, e.name)); err != nil {
+				return err
+			}
+		}
+		state[e.name] = done
+		order = append(order, e)
+
+		return nil
+	}
+
+	for _, e := range a.components {
+		if err := visit(e, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func (a *App) find(name string) *entry {
+	for _, e := range a.components {
+		if e.name == name {
+			return e
+		}
+	}
+
+	return nil
+}
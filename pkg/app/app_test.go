@@ -0,0 +1,207 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/app"
+	"github.com/jorgefuertes/martian-stack/pkg/database/sqlite"
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the start and stop of its components
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) component(name string, startErr error) app.Component {
+	return app.Func(func(context.Context) error {
+		r.add("start " + name)
+		return startErr
+	}, func(context.Context) error {
+		r.add("stop " + name)
+		return nil
+	})
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string{}, r.events...)
+}
+
+func TestAppOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("dependency order", func(t *testing.T) {
+		r := &recorder{}
+		a := app.New()
+		a.Add("server", r.component("server", nil), "migrations", "cache")
+		a.Add("migrations", r.component("migrations", nil), "database")
+		a.Add("cache", r.component("cache", nil))
+		a.Add("database", r.component("database", nil))
+
+		require.NoError(t, a.Start(ctx))
+		require.ErrorIs(t, a.Start(ctx), app.ErrAlreadyStarted)
+		require.NoError(t, a.Stop(ctx))
+
+		assert.Equal(t, []string{
+			"start database", "start migrations", "start cache", "start server",
+			"stop server", "stop cache", "stop migrations", "stop database",
+		}, r.list())
+	})
+
+	t.Run("start failure stops the started components", func(t *testing.T) {
+		r := &recorder{}
+		a := app.New()
+		a.Add("database", r.component("database", nil))
+		a.Add("migrations", r.component("migrations", errors.New("bad migration")), "database")
+		a.Add("server", r.component("server", nil), "migrations")
+
+		err := a.Start(ctx)
+		require.EqualError(t, err, "start migrations: bad migration")
+		assert.Equal(t, []string{"start database", "start migrations", "stop database"}, r.list())
+	})
+
+	t.Run("invalid dependencies", func(t *testing.T) {
+		a := app.New()
+		a.Add("server", app.Func(nil, nil), "database")
+		require.ErrorIs(t, a.Start(ctx), app.ErrUnknownDependency)
+
+		a = app.New()
+		a.Add("a", app.Func(nil, nil), "b")
+		a.Add("b", app.Func(nil, nil), "a")
+		err := a.Start(ctx)
+		require.ErrorIs(t, err, app.ErrDependencyCycle)
+		assert.Contains(t, err.Error(), "a -> b -> a")
+
+		assert.Panics(t, func() { a.Add("a", app.Func(nil, nil)) })
+	})
+}
+
+func TestAppRun(t *testing.T) {
+	t.Run("stops when the context ends", func(t *testing.T) {
+		r := &recorder{}
+		a := app.New()
+		a.Add("database", r.component("database", nil))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		require.NoError(t, a.Run(ctx))
+		assert.Equal(t, []string{"start database", "stop database"}, r.list())
+	})
+
+	t.Run("stops when a worker fails", func(t *testing.T) {
+		r := &recorder{}
+		stopped := make(chan struct{})
+
+		a := app.New()
+		a.Add("database", r.component("database", nil))
+		a.Add("healthy", app.Worker(func(ctx context.Context) error {
+			<-ctx.Done()
+			close(stopped)
+			return ctx.Err()
+		}))
+		a.Add("failing", app.Worker(func(context.Context) error {
+			return errors.New("queue closed")
+		}), "database")
+
+		err := a.Run(context.Background())
+		require.EqualError(t, err, "failing: queue closed")
+		assert.Equal(t, []string{"start database", "stop database"}, r.list())
+		assert.NotPanics(t, func() { <-stopped })
+	})
+
+	t.Run("bounds the stop after a start failure", func(t *testing.T) {
+		a := app.New(app.WithStopTimeout(50 * time.Millisecond))
+		a.Add("hanging", app.Func(nil, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}))
+		a.Add("failing", app.Func(func(context.Context) error {
+			return errors.New("boom")
+		}, nil), "hanging")
+
+		start := time.Now()
+		err := a.Run(context.Background())
+		require.ErrorContains(t, err, "start failing: boom")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestComponents(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("database", func(t *testing.T) {
+		db, err := sqlite.NewInMemory()
+		require.NoError(t, err)
+
+		c := app.Database(db)
+		require.NoError(t, c.Start(ctx))
+		require.NoError(t, c.Stop(ctx))
+		require.Error(t, db.Ping(ctx), "closed on stop")
+	})
+
+	t.Run("http server", func(t *testing.T) {
+		srv, err := server.NewWithOptions(server.WithAddr("localhost:0"))
+		require.NoError(t, err)
+
+		c := app.HTTPServer(srv)
+		require.NoError(t, c.Start(ctx))
+		require.Eventually(t, srv.IsReady, 5*time.Second, 10*time.Millisecond)
+
+		res, err := http.Get("http://" + srv.Addr().String() + "/server/ready")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		_ = res.Body.Close()
+
+		require.NoError(t, c.Stop(ctx))
+		assert.False(t, srv.IsReady())
+	})
+
+	t.Run("http server address in use", func(t *testing.T) {
+		l, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = l.Close() })
+
+		srv, err := server.NewWithOptions(server.WithAddr(l.Addr().String()))
+		require.NoError(t, err)
+
+		a := app.New()
+		a.Add("server", app.HTTPServer(srv))
+		err = a.Run(ctx)
+		require.ErrorContains(t, err, "start server: listen tcp")
+	})
+
+	t.Run("http server named listener address in use", func(t *testing.T) {
+		l, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = l.Close() })
+
+		srv, err := server.NewWithOptions(server.WithAddr("localhost:0"))
+		require.NoError(t, err)
+		srv.Listener("internal", l.Addr().String())
+
+		err = app.HTTPServer(srv).Start(ctx)
+		require.ErrorContains(t, err, "listener internal: listen tcp")
+		assert.Nil(t, srv.Addr())
+	})
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+)
+
+// funcComponent runs functions on start and stop
+type funcComponent struct {
+	start func(ctx context.Context) error
+	stop  func(ctx context.Context) error
+}
+
+// Func returns a component running start and stop, any of them can be nil.
+//
+//	a.Add("migrations", app.Func(func(ctx context.Context) error {
+//		return migrations.Run(db)
+//	}, nil), "database")
+func Func(start, stop func(ctx context.Context) error) Component {
+	return funcComponent{start: start, stop: stop}
+}
+
+func (c funcComponent) Start(ctx context.Context) error {
+	if c.start == nil {
+		return nil
+	}
+
+	return c.start(ctx)
+}
+
+func (c funcComponent) Stop(ctx context.Context) error {
+	if c.stop == nil {
+		return nil
+	}
+
+	return c.stop(ctx)
+}
+
+// Database returns a component pinging the database on start and closing it on stop.
+func Database(db database.Database) Component {
+	return Func(db.Ping, func(context.Context) error {
+		return db.Close()
+	})
+}
+
+// Closer returns a component closing c on stop, like a cache service.
+func Closer(c io.Closer) Component {
+	return Func(nil, func(context.Context) error {
+		return c.Close()
+	})
+}
+
+// worker runs a function in the background until stopped
+type worker struct {
+	fn     func(ctx context.Context) error
+	cancel context.CancelFunc
+	done   chan struct{}
+	failed chan error
+}
+
+// Worker returns a component running fn in the background, with a context canceled
+// on stop, which waits for fn to return. An error other than the context one fails
+// the app.
+//
+//	a.Add("mailer", app.Worker(mailer.Loop), "database")
+func Worker(fn func(ctx context.Context) error) Component {
+	return &worker{fn: fn}
+}
+
+func (w *worker) Start(ctx context.Context) error {
+	// the worker outlives the start context
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	w.failed = make(chan error, 1)
+
+	go func() {
+		defer close(w.done)
+
+		if err := w.fn(runCtx); err != nil && !errors.Is(err, context.Canceled) {
+			w.failed <- err
+		}
+	}()
+
+	return nil
+}
+
+func (w *worker) Stop(ctx context.Context) error {
+	w.cancel()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *worker) Failed() <-chan error {
+	return w.failed
+}
+
+// httpServer serves a server.Server in the background
+type httpServer struct {
+	srv    *server.Server
+	failed chan error
+}
+
+// HTTPServer returns a component serving srv. It listens on start, so errors like
+// an address in use fail the start, and stops with the server graceful Shutdown.
+func HTTPServer(srv *server.Server) Component {
+	return &httpServer{srv: srv}
+}
+
+func (h *httpServer) Start(context.Context) error {
+	l, err := h.srv.Listen()
+	if err != nil {
+		return err
+	}
+
+	h.failed = make(chan error, 1)
+	go func() {
+		if err := h.srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			h.failed <- err
+		}
+	}()
+
+	return nil
+}
+
+func (h *httpServer) Stop(ctx context.Context) error {
+	return h.srv.Shutdown(ctx)
+}
+
+func (h *httpServer) Failed() <-chan error {
+	return h.failed
+}
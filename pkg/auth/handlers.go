@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/jorgefuertes/martian-stack/pkg/auth/jwt"
+	"github.com/jorgefuertes/martian-stack/pkg/database"
 	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
 	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
 )
@@ -47,10 +50,10 @@ type RefreshResponse struct {
 
 // AccountRepository defines the interface for account operations
 type AccountRepository interface {
-	GetByEmail(email string) (*adapter.Account, error)
-	GetByUsername(username string) (*adapter.Account, error)
-	Get(id string) (*adapter.Account, error)
-	Update(a *adapter.Account) error
+	GetByEmail(ctx context.Context, email string) (*adapter.Account, error)
+	GetByUsername(ctx context.Context, username string) (*adapter.Account, error)
+	Get(ctx context.Context, id string) (*adapter.Account, error)
+	Update(ctx context.Context, a *adapter.Account) error
 }
 
 // Handlers provides authentication HTTP handlers
@@ -59,6 +62,7 @@ type Handlers struct {
 	jwtService       *jwt.Service
 	refreshTokenRepo adapter.RefreshTokenRepository
 	resetTokenRepo   adapter.PasswordResetTokenRepository
+	inTx             func(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 // NewHandlers creates new authentication handlers
@@ -73,6 +77,69 @@ func NewHandlers(
 		jwtService:       jwtService,
 		refreshTokenRepo: refreshTokenRepo,
 		resetTokenRepo:   resetTokenRepo,
+		inTx: func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		},
+	}
+}
+
+// WithTransactions makes the multi-step flows (token refresh, password reset request
+// and password reset) run atomically in a database transaction. The SQL repositories
+// built on the same db join it automatically.
+func (h *Handlers) WithTransactions(db database.Database) *Handlers {
+	h.inTx = func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return database.WithTx(ctx, db, fn)
+	}
+
+	return h
+}
+
+// The outcomes of the transactional flows that are not failures. The flows
+// return them, or the repository errors as they are, so the transaction can
+// retry on the retryable ones, and map them to HTTP errors once it is over.
+var (
+	errTokenInvalid    = errors.New("invalid token")
+	errTokenExpired    = errors.New("token expired")
+	errTokenRevoked    = errors.New("token revoked")
+	errTokenUsed       = errors.New("token already used")
+	errAccountDisabled = errors.New("account disabled")
+)
+
+// refreshError maps the errors of the token refresh transaction to HTTP errors
+func refreshError(c ctx.Ctx, err error) error {
+	switch {
+	case errors.Is(err, adapter.ErrTokenNotFound), errors.Is(err, errTokenInvalid):
+		return c.Error(http.StatusUnauthorized, "Invalid refresh token")
+	case errors.Is(err, errTokenExpired):
+		return c.Error(http.StatusUnauthorized, "Refresh token expired")
+	case errors.Is(err, errTokenRevoked):
+		return c.Error(http.StatusUnauthorized, "Refresh token revoked")
+	case errors.Is(err, adapter.ErrAccountNotFound):
+		return c.Error(http.StatusUnauthorized, "Account not found")
+	case errors.Is(err, errAccountDisabled):
+		return c.Error(http.StatusForbidden, "Account is disabled")
+	default:
+		return c.Error(http.StatusInternalServerError, "Failed to refresh token")
+	}
+}
+
+// resetError maps the errors of the password reset transaction to HTTP errors
+func resetError(c ctx.Ctx, err error) error {
+	switch {
+	case errors.Is(err, adapter.ErrTokenNotFound):
+		return c.Error(http.StatusUnauthorized, "Invalid or expired reset token")
+	case errors.Is(err, errTokenInvalid):
+		return c.Error(http.StatusUnauthorized, "Invalid reset token")
+	case errors.Is(err, errTokenExpired):
+		return c.Error(http.StatusUnauthorized, "Reset token has expired")
+	case errors.Is(err, errTokenUsed):
+		return c.Error(http.StatusUnauthorized, "Reset token has already been used")
+	case errors.Is(err, adapter.ErrAccountNotFound):
+		return c.Error(http.StatusNotFound, "Account not found")
+	case errors.Is(err, adapter.ErrPasswordTooShort), errors.Is(err, adapter.ErrPasswordTooLong):
+		return c.Error(http.StatusBadRequest, err.Error())
+	default:
+		return c.Error(http.StatusInternalServerError, "Failed to reset password")
 	}
 }
 
@@ -98,9 +165,9 @@ func (h *Handlers) Login() ctx.Handler {
 		var err error
 
 		if req.Email != "" {
-			account, err = h.repo.GetByEmail(req.Email)
+			account, err = h.repo.GetByEmail(c.Context(), req.Email)
 		} else {
-			account, err = h.repo.GetByUsername(req.Username)
+			account, err = h.repo.GetByUsername(c.Context(), req.Username)
 		}
 
 		if err != nil {
@@ -119,7 +186,7 @@ func (h *Handlers) Login() ctx.Handler {
 
 		// Update last login
 		account.LastLogin = time.Now()
-		if err := h.repo.Update(account); err != nil {
+		if err := h.repo.Update(c.Context(), account); err != nil {
 			// Log error but don't fail login
 		}
 
@@ -143,7 +210,7 @@ func (h *Handlers) Login() ctx.Handler {
 		// Store refresh token in database
 		refreshTokenExpiry := 7 * 24 * time.Hour // 7 days
 		refreshTokenRecord := adapter.NewRefreshToken(account.ID, tokenHash, refreshTokenExpiry)
-		if err := h.refreshTokenRepo.Create(refreshTokenRecord); err != nil {
+		if err := h.refreshTokenRepo.Create(c.Context(), refreshTokenRecord); err != nil {
 			return c.Error(http.StatusInternalServerError, "Failed to store refresh token")
 		}
 
@@ -184,69 +251,83 @@ func (h *Handlers) Refresh() ctx.Handler {
 			return c.Error(http.StatusBadRequest, "Invalid refresh token format")
 		}
 
-		// Get refresh token from database
-		storedToken, err := h.refreshTokenRepo.GetByTokenHash(tokenHash)
-		if err != nil {
-			return c.Error(http.StatusUnauthorized, "Invalid refresh token")
-		}
+		var response RefreshResponse
 
-		// Validate token
-		if !storedToken.IsValid() {
-			if storedToken.IsExpired() {
-				return c.Error(http.StatusUnauthorized, "Refresh token expired")
+		// Revoke and reissue atomically so a token can only be rotated once
+		err = h.inTx(c.Context(), func(txCtx context.Context) error {
+			// Get refresh token from database
+			storedToken, err := h.refreshTokenRepo.GetByTokenHash(txCtx, tokenHash)
+			if err != nil {
+				return err
 			}
-			if storedToken.IsRevoked() {
-				return c.Error(http.StatusUnauthorized, "Refresh token revoked")
+
+			// Validate token
+			if !storedToken.IsValid() {
+				if storedToken.IsExpired() {
+					return errTokenExpired
+				}
+				if storedToken.IsRevoked() {
+					return errTokenRevoked
+				}
+				return errTokenInvalid
 			}
-			return c.Error(http.StatusUnauthorized, "Invalid refresh token")
-		}
 
-		// Get account to generate new tokens with current data
-		account, err := h.repo.Get(storedToken.UserID)
-		if err != nil {
-			return c.Error(http.StatusUnauthorized, "Account not found")
-		}
+			// Get account to generate new tokens with current data
+			account, err := h.repo.Get(txCtx, storedToken.UserID)
+			if err != nil {
+				return err
+			}
 
-		// Check if account is enabled
-		if !account.Enabled {
-			return c.Error(http.StatusForbidden, "Account is disabled")
-		}
+			// Check if account is enabled
+			if !account.Enabled {
+				return errAccountDisabled
+			}
 
-		// Revoke the old refresh token (token rotation)
-		if err := h.refreshTokenRepo.Revoke(tokenHash); err != nil {
-			// Log error but continue - we don't want to fail the refresh
-		}
+			// Revoke the old refresh token (token rotation)
+			if err := h.refreshTokenRepo.Revoke(txCtx, tokenHash); err != nil {
+				if errors.Is(err, adapter.ErrTokenNotFound) {
+					// revoked by a concurrent refresh
+					return errTokenRevoked
+				}
+				return err
+			}
 
-		// Generate new access token
-		accessToken, err := h.jwtService.GenerateAccessToken(
-			account.ID,
-			account.Username,
-			account.Email,
-			account.Role,
-		)
-		if err != nil {
-			return c.Error(http.StatusInternalServerError, "Failed to generate token")
-		}
+			// Generate new access token
+			accessToken, err := h.jwtService.GenerateAccessToken(
+				account.ID,
+				account.Username,
+				account.Email,
+				account.Role,
+			)
+			if err != nil {
+				return err
+			}
 
-		// Generate new refresh token
-		rawRefreshToken, newTokenHash, err := adapter.GenerateSecureToken()
-		if err != nil {
-			return c.Error(http.StatusInternalServerError, "Failed to generate refresh token")
-		}
+			// Generate new refresh token
+			rawRefreshToken, newTokenHash, err := adapter.GenerateSecureToken()
+			if err != nil {
+				return err
+			}
 
-		// Store new refresh token in database
-		refreshTokenExpiry := 7 * 24 * time.Hour // 7 days
-		newRefreshTokenRecord := adapter.NewRefreshToken(account.ID, newTokenHash, refreshTokenExpiry)
-		if err := h.refreshTokenRepo.Create(newRefreshTokenRecord); err != nil {
-			return c.Error(http.StatusInternalServerError, "Failed to store refresh token")
-		}
+			// Store new refresh token in database
+			refreshTokenExpiry := 7 * 24 * time.Hour // 7 days
+			newRefreshTokenRecord := adapter.NewRefreshToken(account.ID, newTokenHash, refreshTokenExpiry)
+			if err := h.refreshTokenRepo.Create(txCtx, newRefreshTokenRecord); err != nil {
+				return err
+			}
 
-		expiresAt, _ := h.jwtService.GetExpiryTime(accessToken)
+			expiresAt, _ := h.jwtService.GetExpiryTime(accessToken)
 
-		response := RefreshResponse{
-			AccessToken:  accessToken,
-			RefreshToken: rawRefreshToken,
-			ExpiresAt:    expiresAt,
+			response = RefreshResponse{
+				AccessToken:  accessToken,
+				RefreshToken: rawRefreshToken,
+				ExpiresAt:    expiresAt,
+			}
+
+			return nil
+		})
+		if err != nil {
+			return refreshError(c, err)
 		}
 
 		return c.SendJSON(response)
@@ -266,7 +347,7 @@ func (h *Handlers) Logout() ctx.Handler {
 		}
 
 		// Revoke all refresh tokens for this user
-		if err := h.refreshTokenRepo.RevokeAll(claims.UserID); err != nil {
+		if err := h.refreshTokenRepo.RevokeAll(c.Context(), claims.UserID); err != nil {
 			// Log error but don't fail logout
 		}
 
@@ -287,7 +368,7 @@ func (h *Handlers) Me() ctx.Handler {
 		}
 
 		// Get fresh user data by ID (immutable identifier)
-		account, err := h.repo.Get(claims.UserID)
+		account, err := h.repo.Get(c.Context(), claims.UserID)
 		if err != nil {
 			return c.Error(http.StatusNotFound, "User not found")
 		}
@@ -327,7 +408,7 @@ func (h *Handlers) RequestPasswordReset() ctx.Handler {
 		}
 
 		// Get account by email
-		account, err := h.repo.GetByEmail(req.Email)
+		account, err := h.repo.GetByEmail(c.Context(), req.Email)
 		if err != nil {
 			// For security, always return success even if email doesn't exist
 			// This prevents email enumeration attacks
@@ -344,21 +425,25 @@ func (h *Handlers) RequestPasswordReset() ctx.Handler {
 			})
 		}
 
-		// Delete any existing password reset tokens for this user
-		if err := h.resetTokenRepo.DeleteByUserID(account.ID); err != nil {
-			// Log error but continue
-		}
-
 		// Generate password reset token
 		rawToken, tokenHash, err := adapter.GenerateSecureToken()
 		if err != nil {
 			return c.Error(http.StatusInternalServerError, "Failed to generate reset token")
 		}
 
-		// Store password reset token (valid for 1 hour)
-		resetTokenExpiry := 1 * time.Hour
-		resetToken := adapter.NewPasswordResetToken(account.ID, tokenHash, resetTokenExpiry)
-		if err := h.resetTokenRepo.Create(resetToken); err != nil {
+		// Replace any existing password reset tokens for this user atomically
+		err = h.inTx(c.Context(), func(txCtx context.Context) error {
+			if err := h.resetTokenRepo.DeleteByUserID(txCtx, account.ID); err != nil {
+				return err
+			}
+
+			// Store password reset token (valid for 1 hour)
+			resetTokenExpiry := 1 * time.Hour
+			resetToken := adapter.NewPasswordResetToken(account.ID, tokenHash, resetTokenExpiry)
+
+			return h.resetTokenRepo.Create(txCtx, resetToken)
+		})
+		if err != nil {
 			return c.Error(http.StatusInternalServerError, "Failed to store reset token")
 		}
 
@@ -405,47 +490,55 @@ func (h *Handlers) ResetPassword() ctx.Handler {
 			return c.Error(http.StatusBadRequest, "Invalid token format")
 		}
 
-		// Get password reset token from database
-		storedToken, err := h.resetTokenRepo.GetByTokenHash(tokenHash)
-		if err != nil {
-			return c.Error(http.StatusUnauthorized, "Invalid or expired reset token")
-		}
-
-		// Validate token
-		if !storedToken.IsValid() {
-			if storedToken.IsExpired() {
-				return c.Error(http.StatusUnauthorized, "Reset token has expired")
+		// Update the password, consume the token and revoke sessions atomically
+		err = h.inTx(c.Context(), func(txCtx context.Context) error {
+			// Get password reset token from database
+			storedToken, err := h.resetTokenRepo.GetByTokenHash(txCtx, tokenHash)
+			if err != nil {
+				return err
 			}
-			if storedToken.IsUsed() {
-				return c.Error(http.StatusUnauthorized, "Reset token has already been used")
+
+			// Validate token
+			if !storedToken.IsValid() {
+				if storedToken.IsExpired() {
+					return errTokenExpired
+				}
+				if storedToken.IsUsed() {
+					return errTokenUsed
+				}
+				return errTokenInvalid
 			}
-			return c.Error(http.StatusUnauthorized, "Invalid reset token")
-		}
 
-		// Get account
-		account, err := h.repo.Get(storedToken.UserID)
-		if err != nil {
-			return c.Error(http.StatusNotFound, "Account not found")
-		}
+			// Get account
+			account, err := h.repo.Get(txCtx, storedToken.UserID)
+			if err != nil {
+				return err
+			}
 
-		// Set new password
-		if err := account.SetPassword(req.NewPassword); err != nil {
-			return c.Error(http.StatusBadRequest, err.Error())
-		}
+			// Set new password
+			if err := account.SetPassword(req.NewPassword); err != nil {
+				return err
+			}
 
-		// Update account
-		if err := h.repo.Update(account); err != nil {
-			return c.Error(http.StatusInternalServerError, "Failed to update password")
-		}
+			// Update account
+			if err := h.repo.Update(txCtx, account); err != nil {
+				return err
+			}
 
-		// Mark token as used
-		if err := h.resetTokenRepo.MarkAsUsed(tokenHash); err != nil {
-			// Log error but don't fail the reset
-		}
+			// Mark token as used
+			if err := h.resetTokenRepo.MarkAsUsed(txCtx, tokenHash); err != nil {
+				if errors.Is(err, adapter.ErrTokenNotFound) {
+					// consumed by a concurrent reset
+					return errTokenUsed
+				}
+				return err
+			}
 
-		// Revoke all refresh tokens to force re-login
-		if err := h.refreshTokenRepo.RevokeAll(account.ID); err != nil {
-			// Log error but don't fail the reset
+			// Revoke all refresh tokens to force re-login
+			return h.refreshTokenRepo.RevokeAll(txCtx, account.ID)
+		})
+		if err != nil {
+			return resetError(c, err)
 		}
 
 		return c.SendJSON(PasswordResetResponse{
@@ -0,0 +1,160 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/auth"
+	"github.com/jorgefuertes/martian-stack/pkg/auth/jwt"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration/migrations"
+	"github.com/jorgefuertes/martian-stack/pkg/database/repository"
+	"github.com/jorgefuertes/martian-stack/pkg/database/sqlite"
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/servererror"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// busyRefreshTokens fails the first lookups with a busy database error,
+// as a concurrent writer would
+type busyRefreshTokens struct {
+	*repository.SQLRefreshTokenRepository
+	failures int
+	calls    int
+}
+
+func (r *busyRefreshTokens) GetByTokenHash(ctx context.Context, tokenHash string) (*adapter.RefreshToken, error) {
+	r.calls++
+	if r.calls <= r.failures {
+		return nil, errors.New("database is locked (5) (SQLITE_BUSY)")
+	}
+
+	return r.SQLRefreshTokenRepository.GetByTokenHash(ctx, tokenHash)
+}
+
+type testEnv struct {
+	handlers *auth.Handlers
+	tokens   *busyRefreshTokens
+	account  *adapter.Account
+}
+
+func setupTestEnv(t *testing.T) testEnv {
+	db, err := sqlite.NewInMemory()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	migrator := migration.New(db)
+	migrator.RegisterMultiple(migrations.All())
+	require.NoError(t, migrator.Up(context.Background()))
+
+	jwtCfg, err := jwt.DefaultConfig("test-secret-that-is-at-least-32-bytes-long")
+	require.NoError(t, err)
+
+	accounts := repository.NewSQLAccountRepository(db)
+	env := testEnv{
+		tokens: &busyRefreshTokens{SQLRefreshTokenRepository: repository.NewSQLRefreshTokenRepository(db)},
+		account: &adapter.Account{
+			Username: "alice",
+			Name:     "Alice",
+			Email:    "alice@example.com",
+			Enabled:  true,
+			Role:     "user",
+		},
+	}
+	require.NoError(t, env.account.SetPassword("password123"))
+	require.NoError(t, accounts.Create(context.Background(), env.account))
+
+	env.handlers = auth.NewHandlers(
+		accounts,
+		jwt.NewService(jwtCfg),
+		env.tokens,
+		repository.NewSQLPasswordResetTokenRepository(db),
+	).WithTransactions(db)
+
+	return env
+}
+
+// createToken stores a refresh token for the account and returns the raw token
+func (env testEnv) createToken(t *testing.T) string {
+	raw, tokenHash, err := adapter.GenerateSecureToken()
+	require.NoError(t, err)
+	require.NoError(t, env.tokens.Create(context.Background(),
+		adapter.NewRefreshToken(env.account.ID, tokenHash, time.Hour)))
+
+	return raw
+}
+
+func (env testEnv) refresh(token string) (*httptest.ResponseRecorder, error) {
+	body, _ := json.Marshal(auth.RefreshRequest{RefreshToken: token})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(string(body)))
+	req.Header.Set(web.HeaderContentType, web.MIMEApplicationJSON)
+
+	return w, ctx.New(w, req, env.handlers.Refresh()).Next()
+}
+
+func assertError(t *testing.T, err error, code int, msg string) {
+	t.Helper()
+
+	var srvErr servererror.Error
+	require.ErrorAs(t, err, &srvErr)
+	assert.Equal(t, code, srvErr.Code)
+	assert.Equal(t, msg, srvErr.Msg)
+}
+
+func TestRefresh(t *testing.T) {
+	t.Run("retries a busy database", func(t *testing.T) {
+		env := setupTestEnv(t)
+		env.tokens.failures = 2
+		token := env.createToken(t)
+
+		w, err := env.refresh(token)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 3, env.tokens.calls)
+
+		var res auth.RefreshResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.NotEmpty(t, res.AccessToken)
+		assert.NotEqual(t, token, res.RefreshToken)
+	})
+
+	t.Run("busy for too long", func(t *testing.T) {
+		env := setupTestEnv(t)
+		env.tokens.failures = 100
+
+		_, err := env.refresh(env.createToken(t))
+		assertError(t, err, http.StatusInternalServerError, "Failed to refresh token")
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		env := setupTestEnv(t)
+		unknown, _, err := adapter.GenerateSecureToken()
+		require.NoError(t, err)
+
+		_, err = env.refresh(unknown)
+		assertError(t, err, http.StatusUnauthorized, "Invalid refresh token")
+		assert.Equal(t, 1, env.tokens.calls, "a missing token is not retried")
+	})
+
+	t.Run("rotated token", func(t *testing.T) {
+		env := setupTestEnv(t)
+		token := env.createToken(t)
+
+		_, err := env.refresh(token)
+		require.NoError(t, err)
+
+		_, err = env.refresh(token)
+		assertError(t, err, http.StatusUnauthorized, "Refresh token revoked")
+	})
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/jorgefuertes/martian-stack/pkg/auth/jwt"
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration/migrations"
+	"github.com/jorgefuertes/martian-stack/pkg/database/repository"
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/health"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+)
+
+const (
+	// ModuleName is the name of the auth module, for the modules depending on it
+	ModuleName = "auth"
+
+	// DefaultPrefix is the path the auth routes are registered under
+	DefaultPrefix = "/auth"
+)
+
+// Module is the authentication feature as a server.Module: the SQL repositories
+// on the database, the accounts and token tables, the auth routes and the
+// OptionalAuth middleware, so every handler can tell who is calling.
+//
+//	srv.Install(auth.NewModule(db, jwtService))
+type Module struct {
+	server.BaseModule
+
+	db         database.Database
+	prefix     string
+	handlers   *Handlers
+	middleware *Middleware
+}
+
+// Migrations returns the migrations of the module, for the migrate commands
+// running without a server to install it in
+func Migrations() []migration.Migration {
+	return []migration.Migration{migrations.InitialSchema, migrations.AddTokenTables}
+}
+
+// NewModule creates the auth module on the database and the JWT service
+func NewModule(db database.Database, jwtService *jwt.Service) *Module {
+	return &Module{
+		db:     db,
+		prefix: DefaultPrefix,
+		handlers: NewHandlers(
+			repository.NewSQLAccountRepository(db),
+			jwtService,
+			repository.NewSQLRefreshTokenRepository(db),
+			repository.NewSQLPasswordResetTokenRepository(db),
+		).WithTransactions(db),
+		middleware: NewMiddleware(jwtService),
+	}
+}
+
+// WithPrefix changes the path the routes are registered under, DefaultPrefix by default
+func (m *Module) WithPrefix(prefix string) *Module {
+	m.prefix = strings.TrimSuffix(prefix, "/")
+
+	return m
+}
+
+// Handlers returns the auth handlers, to register more routes with them
+func (m *Module) Handlers() *Handlers {
+	return m.handlers
+}
+
+// Auth returns the auth middleware, to protect the routes of the project
+// and of the modules depending on this one
+//
+//	srv.Route(web.MethodGet, "/profile", profile, authModule.Auth().RequireAuth())
+func (m *Module) Auth() *Middleware {
+	return m.middleware
+}
+
+func (m *Module) Name() string {
+	return ModuleName
+}
+
+// Register registers the auth routes under the prefix:
+//
+//	POST /login                   public
+//	POST /refresh                 public
+//	POST /password-reset/request  public
+//	POST /password-reset          public
+//	POST /logout                  authenticated
+//	GET  /me                      authenticated
+func (m *Module) Register(srv *server.Server) error {
+	pub := srv.Group(m.prefix)
+	pub.Route(web.MethodPost, "/login", m.handlers.Login())
+	pub.Route(web.MethodPost, "/refresh", m.handlers.Refresh())
+	pub.Route(web.MethodPost, "/password-reset/request", m.handlers.RequestPasswordReset())
+	pub.Route(web.MethodPost, "/password-reset", m.handlers.ResetPassword())
+
+	priv := srv.Group(m.prefix, m.middleware.RequireAuth())
+	priv.Route(web.MethodPost, "/logout", m.handlers.Logout())
+	priv.Route(web.MethodGet, "/me", m.handlers.Me())
+
+	return nil
+}
+
+// Migrations creates the accounts, refresh tokens and password reset tokens tables
+func (m *Module) Migrations() []migration.Migration {
+	return Migrations()
+}
+
+// HealthChecks pings the database the accounts are in
+func (m *Module) HealthChecks() []health.Check {
+	return []health.Check{{Name: "auth.database", Func: health.Ping(m.db)}}
+}
+
+// Middleware extracts the claims of a valid token on every request, see OptionalAuth
+func (m *Module) Middleware() []ctx.Handler {
+	return []ctx.Handler{m.middleware.OptionalAuth()}
+}
@@ -9,8 +9,9 @@ import (
 
 // Connection implements the Database interface wrapping *sql.DB
 type Connection struct {
-	db     *sql.DB
-	driver string
+	db      *sql.DB
+	driver  string
+	dialect Dialect
 }
 
 // New creates a new database connection from config
@@ -44,8 +45,9 @@ func New(cfg *Config) (Database, error) {
 	}
 
 	return &Connection{
-		db:     db,
-		driver: cfg.Driver,
+		db:      db,
+		driver:  cfg.Driver,
+		dialect: DialectFor(cfg.Driver),
 	}, nil
 }
 
@@ -69,22 +71,30 @@ func (c *Connection) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return c.db.BeginTx(ctx, nil)
 }
 
-// Exec executes a query without returning rows
+// Exec executes a query without returning rows.
+// It joins the transaction carried by ctx, if any.
 func (c *Connection) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return c.db.ExecContext(ctx, query, args...)
+	return ExecutorFor(ctx, c.db).ExecContext(ctx, query, args...)
 }
 
-// Query executes a query that returns rows
+// Query executes a query that returns rows.
+// It joins the transaction carried by ctx, if any.
 func (c *Connection) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	return c.db.QueryContext(ctx, query, args...)
+	return ExecutorFor(ctx, c.db).QueryContext(ctx, query, args...)
 }
 
-// QueryRow executes a query that returns at most one row
+// QueryRow executes a query that returns at most one row.
+// It joins the transaction carried by ctx, if any.
 func (c *Connection) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	return c.db.QueryRowContext(ctx, query, args...)
+	return ExecutorFor(ctx, c.db).QueryRowContext(ctx, query, args...)
 }
 
 // Stats returns database statistics
 func (c *Connection) Stats() sql.DBStats {
 	return c.db.Stats()
 }
+
+// Dialect returns the SQL dialect for the connection driver
+func (c *Connection) Dialect() Dialect {
+	return c.dialect
+}
@@ -20,17 +20,20 @@ type Database interface {
 	// BeginTx starts a new transaction
 	BeginTx(ctx context.Context) (*sql.Tx, error)
 
-	// Exec executes a query without returning rows
+	// Exec executes a query without returning rows, joining the transaction in ctx if any
 	Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 
-	// Query executes a query that returns rows
+	// Query executes a query that returns rows, joining the transaction in ctx if any
 	Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 
-	// QueryRow executes a query that returns at most one row
+	// QueryRow executes a query that returns at most one row, joining the transaction in ctx if any
 	QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row
 
 	// Stats returns database statistics
 	Stats() sql.DBStats
+
+	// Dialect returns the SQL dialect for the connection driver
+	Dialect() Dialect
 }
 
 // Config represents common database configuration
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database/internal/sqltext"
+)
+
+// Dialect names returned by Dialect.Name
+const (
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+	MySQL    = "mysql"
+)
+
+// Dialect abstracts the SQL differences between the supported databases.
+// Queries are written with ? placeholders and rebound to the driver syntax.
+type Dialect interface {
+	// Name returns the dialect name: sqlite, postgres or mysql
+	Name() string
+
+	// Placeholder returns the bind parameter for the n-th argument (1-based)
+	Placeholder(n int) string
+
+	// Rebind converts ? placeholders to the dialect bind syntax,
+	// leaving quoted strings, quoted identifiers and comments untouched
+	Rebind(query string) string
+
+	// Quote quotes an identifier (table or column name)
+	Quote(identifier string) string
+
+	// BoolType returns the column type used for booleans
+	BoolType() string
+
+	// BlobType returns the column type used for binary data
+	BlobType() string
+
+	// TimestampType returns the column type used for date and time values
+	TimestampType() string
+
+	// SupportsReturning reports whether INSERT/UPDATE/DELETE ... RETURNING is available
+	SupportsReturning() bool
+
+	// Returning builds a RETURNING clause, or an empty string when unsupported
+	Returning(columns ...string) string
+
+	// Upsert builds an INSERT statement for columns that updates the update
+	// columns when a row with the same conflict columns already exists.
+	// With no update columns the conflicting insert is ignored.
+	Upsert(table string, columns, conflict, update []string) string
+}
+
+// DialectFor returns the dialect for the given database/sql driver name.
+// Unknown drivers get the SQLite dialect, which uses ANSI quoting and ? placeholders.
+func DialectFor(driver string) Dialect {
+	switch strings.ToLower(driver) {
+	case "pgx", "pgx/v5", "postgres", "postgresql":
+		return postgresDialect{}
+	case "mysql", "mariadb":
+		return mysqlDialect{}
+	default:
+		return sqliteDialect{}
+	}
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string                         { return SQLite }
+func (sqliteDialect) Placeholder(int) string               { return "?" }
+func (sqliteDialect) Rebind(query string) string           { return query }
+func (sqliteDialect) Quote(identifier string) string       { return quoteWith(identifier, '"') }
+func (sqliteDialect) BoolType() string                     { return "INTEGER" }
+func (sqliteDialect) BlobType() string                     { return "BLOB" }
+func (sqliteDialect) TimestampType() string                { return "DATETIME" }
+func (sqliteDialect) SupportsReturning() bool              { return true }
+func (d sqliteDialect) Returning(columns ...string) string { return returning(d, columns) }
+
+func (d sqliteDialect) Upsert(table string, columns, conflict, update []string) string {
+	return onConflictUpsert(d, table, columns, conflict, update)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string                         { return Postgres }
+func (postgresDialect) Placeholder(n int) string             { return "$" + strconv.Itoa(n) }
+func (d postgresDialect) Rebind(query string) string         { return rebind(query, d.Placeholder) }
+func (postgresDialect) Quote(identifier string) string       { return quoteWith(identifier, '"') }
+func (postgresDialect) BoolType() string                     { return "BOOLEAN" }
+func (postgresDialect) BlobType() string                     { return "BYTEA" }
+func (postgresDialect) TimestampType() string                { return "TIMESTAMP" }
+func (postgresDialect) SupportsReturning() bool              { return true }
+func (d postgresDialect) Returning(columns ...string) string { return returning(d, columns) }
+
+func (d postgresDialect) Upsert(table string, columns, conflict, update []string) string {
+	return onConflictUpsert(d, table, columns, conflict, update)
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string                   { return MySQL }
+func (mysqlDialect) Placeholder(int) string         { return "?" }
+func (mysqlDialect) Rebind(query string) string     { return query }
+func (mysqlDialect) Quote(identifier string) string { return quoteWith(identifier, '`') }
+func (mysqlDialect) BoolType() string               { return "BOOLEAN" }
+func (mysqlDialect) BlobType() string               { return "LONGBLOB" }
+func (mysqlDialect) TimestampType() string          { return "DATETIME" }
+func (mysqlDialect) SupportsReturning() bool        { return false }
+func (mysqlDialect) Returning(...string) string     { return "" }
+
+func (d mysqlDialect) Upsert(table string, columns, conflict, update []string) string {
+	var b strings.Builder
+
+	b.WriteString(insertInto(d, table, columns))
+	b.WriteString(" ON DUPLICATE KEY UPDATE ")
+
+	if len(update) == 0 {
+		// no-op update so the duplicate row is ignored without masking other errors
+		col := d.Quote(conflict[0])
+		b.WriteString(col + " = " + col)
+
+		return b.String()
+	}
+
+	for i, col := range update {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(d.Quote(col) + " = VALUES(" + d.Quote(col) + ")")
+	}
+
+	return b.String()
+}
+
+// onConflictUpsert builds the INSERT ... ON CONFLICT form shared by SQLite and PostgreSQL
+func onConflictUpsert(d Dialect, table string, columns, conflict, update []string) string {
+	var b strings.Builder
+
+	b.WriteString(insertInto(d, table, columns))
+	b.WriteString(" ON CONFLICT (")
+	b.WriteString(quoteList(d, conflict))
+	b.WriteString(")")
+
+	if len(update) == 0 {
+		b.WriteString(" DO NOTHING")
+
+		return b.String()
+	}
+
+	b.WriteString(" DO UPDATE SET ")
+	for i, col := range update {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(d.Quote(col) + " = excluded." + d.Quote(col))
+	}
+
+	return b.String()
+}
+
+// insertInto builds "INSERT INTO table (columns) VALUES (placeholders)"
+func insertInto(d Dialect, table string, columns []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = d.Placeholder(i + 1)
+	}
+
+	return "INSERT INTO " + d.Quote(table) +
+		" (" + quoteList(d, columns) + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+}
+
+func returning(d Dialect, columns []string) string {
+	if len(columns) == 0 {
+		return ""
+	}
+
+	return " RETURNING " + quoteList(d, columns)
+}
+
+func quoteList(d Dialect, identifiers []string) string {
+	quoted := make([]string, len(identifiers))
+	for i, id := range identifiers {
+		quoted[i] = d.Quote(id)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// quoteWith wraps the identifier in q, doubling any q inside it
+func quoteWith(identifier string, q byte) string {
+	s := string(q)
+	return s + strings.ReplaceAll(identifier, s, s+s) + s
+}
+
+// rebind replaces every ? outside quotes, comments and dollar-quoted bodies with placeholder(n)
+func rebind(query string, placeholder func(n int) string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := sqltext.SkipQuoted(query, i, ch)
+			b.WriteString(query[i:end])
+			i = end - 1
+		case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case ch == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end == -1 {
+				end = len(query)
+			} else {
+				end = i + 2 + end + 2
+			}
+			b.WriteString(query[i:end])
+			i = end - 1
+		case ch == '$':
+			end, ok := sqltext.SkipDollarQuoted(query, i)
+			if !ok {
+				end = i + 1
+			}
+			b.WriteString(query[i:end])
+			i = end - 1
+		case ch == '?':
+			n++
+			b.WriteString(placeholder(n))
+		default:
+			b.WriteByte(ch)
+		}
+	}
+
+	return b.String()
+}
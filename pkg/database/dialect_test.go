@@ -0,0 +1,148 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectFor(t *testing.T) {
+	tests := map[string]string{
+		"sqlite":     SQLite,
+		"sqlite3":    SQLite,
+		"pgx":        Postgres,
+		"pgx/v5":     Postgres,
+		"postgres":   Postgres,
+		"postgresql": Postgres,
+		"mysql":      MySQL,
+		"mariadb":    MySQL,
+		"unknown":    SQLite,
+	}
+
+	for driver, name := range tests {
+		t.Run(driver, func(t *testing.T) {
+			assert.Equal(t, name, DialectFor(driver).Name())
+		})
+	}
+}
+
+func TestDialect_Rebind(t *testing.T) {
+	pg := DialectFor("pgx")
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"no placeholders", "SELECT 1", "SELECT 1"},
+		{
+			"simple",
+			"SELECT * FROM accounts WHERE id = ? AND role = ?",
+			"SELECT * FROM accounts WHERE id = $1 AND role = $2",
+		},
+		{
+			"single quoted string",
+			"SELECT '?' FROM t WHERE a = ?",
+			"SELECT '?' FROM t WHERE a = $1",
+		},
+		{
+			"escaped quote",
+			"SELECT 'it''s ?' FROM t WHERE a = ?",
+			"SELECT 'it''s ?' FROM t WHERE a = $1",
+		},
+		{
+			"quoted identifier",
+			`SELECT "col?" FROM t WHERE a = ?`,
+			`SELECT "col?" FROM t WHERE a = $1`,
+		},
+		{
+			"line comment",
+			"SELECT a -- why?\nFROM t WHERE a = ?",
+			"SELECT a -- why?\nFROM t WHERE a = $1",
+		},
+		{
+			"block comment",
+			"SELECT a /* why? */ FROM t WHERE a = ?",
+			"SELECT a /* why? */ FROM t WHERE a = $1",
+		},
+		{
+			"dollar-quoted body",
+			"SELECT $$ why? $$, a FROM t WHERE a = ?",
+			"SELECT $$ why? $$, a FROM t WHERE a = $1",
+		},
+		{
+			"tagged dollar-quoted body",
+			"CREATE FUNCTION f() RETURNS text AS $fn$ SELECT 'a?' || $$?$$ $fn$ LANGUAGE sql; SELECT ?",
+			"CREATE FUNCTION f() RETURNS text AS $fn$ SELECT 'a?' || $$?$$ $fn$ LANGUAGE sql; SELECT $1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, pg.Rebind(tt.query))
+		})
+	}
+
+	// ? based dialects leave the query untouched
+	q := "SELECT * FROM t WHERE a = ? AND b = ?"
+	assert.Equal(t, q, DialectFor("sqlite").Rebind(q))
+	assert.Equal(t, q, DialectFor("mysql").Rebind(q))
+}
+
+func TestDialect_Quote(t *testing.T) {
+	assert.Equal(t, `"accounts"`, DialectFor("sqlite").Quote("accounts"))
+	assert.Equal(t, `"we""ird"`, DialectFor("pgx").Quote(`we"ird`))
+	assert.Equal(t, "`accounts`", DialectFor("mysql").Quote("accounts"))
+}
+
+func TestDialect_Types(t *testing.T) {
+	pg := DialectFor("pgx")
+	assert.Equal(t, "BOOLEAN", pg.BoolType())
+	assert.Equal(t, "BYTEA", pg.BlobType())
+	assert.Equal(t, "TIMESTAMP", pg.TimestampType())
+
+	lite := DialectFor("sqlite")
+	assert.Equal(t, "INTEGER", lite.BoolType())
+	assert.Equal(t, "BLOB", lite.BlobType())
+	assert.Equal(t, "DATETIME", lite.TimestampType())
+
+	my := DialectFor("mysql")
+	assert.Equal(t, "BOOLEAN", my.BoolType())
+	assert.Equal(t, "LONGBLOB", my.BlobType())
+	assert.Equal(t, "DATETIME", my.TimestampType())
+}
+
+func TestDialect_Returning(t *testing.T) {
+	assert.True(t, DialectFor("pgx").SupportsReturning())
+	assert.Equal(t, ` RETURNING "id", "created_at"`, DialectFor("pgx").Returning("id", "created_at"))
+
+	assert.False(t, DialectFor("mysql").SupportsReturning())
+	assert.Empty(t, DialectFor("mysql").Returning("id"))
+}
+
+func TestDialect_Upsert(t *testing.T) {
+	cols := []string{"key", "value"}
+	conflict := []string{"key"}
+	update := []string{"value"}
+
+	assert.Equal(t,
+		`INSERT INTO "settings" ("key", "value") VALUES ($1, $2) `+
+			`ON CONFLICT ("key") DO UPDATE SET "value" = excluded."value"`,
+		DialectFor("pgx").Upsert("settings", cols, conflict, update),
+	)
+
+	assert.Equal(t,
+		`INSERT INTO "settings" ("key", "value") VALUES (?, ?) ON CONFLICT ("key") DO NOTHING`,
+		DialectFor("sqlite").Upsert("settings", cols, conflict, nil),
+	)
+
+	assert.Equal(t,
+		"INSERT INTO `settings` (`key`, `value`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `value` = VALUES(`value`)",
+		DialectFor("mysql").Upsert("settings", cols, conflict, update),
+	)
+
+	assert.Equal(t,
+		"INSERT INTO `settings` (`key`, `value`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `key` = `key`",
+		DialectFor("mysql").Upsert("settings", cols, conflict, nil),
+	)
+}
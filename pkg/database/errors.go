@@ -20,4 +20,16 @@ var (
 
 	// ErrMigrationFailed is returned when migration fails
 	ErrMigrationFailed = errors.New("migration failed")
+
+	// ErrMigrationModified is returned when an applied migration no longer matches its checksum
+	ErrMigrationModified = errors.New("applied migration was modified")
+
+	// ErrMigrationOutOfOrder is returned when a pending migration is older than the latest applied one
+	ErrMigrationOutOfOrder = errors.New("migration out of order")
+
+	// ErrMigrationLocked is returned when the migration lock cannot be acquired in time
+	ErrMigrationLocked = errors.New("migration lock is held by another process")
+
+	// ErrInvalidMigrationCommand is returned when a migrate command is unknown or malformed
+	ErrInvalidMigrationCommand = errors.New("invalid migrate command")
 )
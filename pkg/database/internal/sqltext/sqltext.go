@@ -0,0 +1,58 @@
+// Package sqltext scans SQL text, shared by the query rebinding of the database
+// package and the statement splitting of the migrations.
+package sqltext
+
+import "strings"
+
+// SkipQuoted returns the index right after the quoted section starting at start.
+// A doubled quote character inside the section is treated as an escaped quote.
+func SkipQuoted(s string, start int, q byte) int {
+	for i := start + 1; i < len(s); i++ {
+		if s[i] != q {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == q {
+			i++
+			continue
+		}
+
+		return i + 1
+	}
+
+	return len(s)
+}
+
+// SkipDollarQuoted returns the index right after the PostgreSQL dollar-quoted body
+// ($$ ... $$ or $tag$ ... $tag$) starting at start, and false when none starts there.
+func SkipDollarQuoted(s string, start int) (int, bool) {
+	tag, ok := dollarTag(s, start)
+	if !ok {
+		return start, false
+	}
+
+	end := strings.Index(s[start+len(tag):], tag)
+	if end == -1 {
+		return len(s), true
+	}
+
+	return start + len(tag) + end + len(tag), true
+}
+
+// dollarTag returns the dollar quote tag ($$ or $name$) starting at i, if any.
+// Positional parameters such as $1 are not tags.
+func dollarTag(s string, i int) (string, bool) {
+	for j := i + 1; j < len(s); j++ {
+		c := s[j]
+
+		switch {
+		case c == '$':
+			return s[i : j+1], true
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && j > i+1:
+		default:
+			return "", false
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,186 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+)
+
+// CommandUsage describes the commands accepted by RunCommand
+const CommandUsage = `Commands:
+  up            apply all pending migrations
+  down          roll back the latest applied migration
+  to <version>  apply or roll back migrations until version is the latest applied one
+  redo          roll back and apply again the latest applied migration
+  status        print the status of every registered migration
+  plan [<v>]    print what up (or to <v>) would run, without running it`
+
+// RunCommand runs a migrate command and prints its result to w.
+// See CommandUsage for the accepted commands.
+func (m *Migrator) RunCommand(ctx context.Context, w io.Writer, args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing command\n%s", database.ErrInvalidMigrationCommand, CommandUsage)
+	}
+
+	command, args := args[0], args[1:]
+
+	version, err := commandVersion(command, args)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		steps, err := m.run(ctx, m.upPlanner(math.MaxInt64))
+		if err != nil {
+			return err
+		}
+		printDone(w, steps)
+	case "down":
+		steps, err := m.run(ctx, m.downPlanner(math.MinInt64, 1))
+		if err != nil {
+			return err
+		}
+		printDone(w, steps)
+	case "to":
+		steps, err := m.run(ctx, m.toPlanner(version))
+		if err != nil {
+			return err
+		}
+		printDone(w, steps)
+	case "redo":
+		steps, err := m.run(ctx, m.redoPlanner())
+		if err != nil {
+			return err
+		}
+		for _, step := range steps {
+			if step.Direction == DirectionDown {
+				fmt.Fprintf(w, "redone %d %s\n", step.Migration.Version, step.Migration.Name)
+			}
+		}
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		PrintStatus(w, status)
+	case "plan":
+		var steps []Step
+		if len(args) == 0 {
+			steps, err = m.Plan(ctx)
+		} else {
+			steps, err = m.dryRun(ctx, m.toPlanner(version))
+		}
+		if err != nil {
+			return err
+		}
+		PrintPlan(w, steps)
+	}
+
+	return nil
+}
+
+// commandVersion validates the arguments of a command and parses its version, if any
+func commandVersion(command string, args []string) (int64, error) {
+	switch command {
+	case "up", "down", "redo", "status":
+		if len(args) == 0 {
+			return 0, nil
+		}
+	case "to", "plan":
+		if len(args) == 0 && command == "plan" {
+			return 0, nil
+		}
+		if len(args) == 1 {
+			version, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("%w: invalid version %q", database.ErrInvalidMigrationCommand, args[0])
+			}
+
+			return version, nil
+		}
+	default:
+		return 0, fmt.Errorf("%w: %s\n%s", database.ErrInvalidMigrationCommand, command, CommandUsage)
+	}
+
+	return 0, fmt.Errorf(
+		"%w: unexpected arguments for %s: %s",
+		database.ErrInvalidMigrationCommand,
+		command,
+		strings.Join(args, " "),
+	)
+}
+
+// printDone prints one line per executed step
+func printDone(w io.Writer, steps []Step) {
+	if len(steps) == 0 {
+		fmt.Fprintln(w, "nothing to do")
+		return
+	}
+
+	for _, step := range steps {
+		verb := "applied"
+		if step.Direction == DirectionDown {
+			verb = "rolled back"
+		}
+		fmt.Fprintf(w, "%s %d %s\n", verb, step.Migration.Version, step.Migration.Name)
+	}
+}
+
+// PrintStatus prints the output of Migrator.Status as a table
+func PrintStatus(w io.Writer, status []Migration) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT\tDESCRIPTION")
+
+	for _, migration := range status {
+		state, appliedAt := "pending", "-"
+		if migration.AppliedAt != nil {
+			state, appliedAt = "applied", migration.AppliedAt.Local().Format(time.DateTime)
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+			migration.Version, migration.Name, state, appliedAt, migration.Description)
+	}
+
+	_ = tw.Flush()
+}
+
+// PrintPlan prints the steps returned by the Plan methods as a table
+func PrintPlan(w io.Writer, steps []Step) {
+	if len(steps) == 0 {
+		fmt.Fprintln(w, "nothing to do")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DIRECTION\tVERSION\tNAME\tSTATEMENTS\tNOTES")
+
+	for _, step := range steps {
+		var notes []string
+		if step.HasFunc {
+			notes = append(notes, "go func")
+		}
+		if step.OutOfOrder {
+			notes = append(notes, "out of order")
+		}
+
+		fmt.Fprintf(
+			tw,
+			"%s\t%d\t%s\t%d\t%s\n",
+			step.Direction,
+			step.Migration.Version,
+			step.Migration.Name,
+			len(step.Statements),
+			strings.Join(notes, ", "),
+		)
+	}
+
+	_ = tw.Flush()
+}
@@ -0,0 +1,112 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCommandMigrator(t *testing.T) *Migrator {
+	migrator := setupTestMigrator(t)
+	migrator.RegisterMultiple([]Migration{
+		{
+			Version:     1,
+			Name:        "create_posts",
+			Description: "Posts table",
+			Up:          "CREATE TABLE posts (id INTEGER PRIMARY KEY)",
+			Down:        "DROP TABLE posts",
+		},
+		{
+			Version: 2,
+			Name:    "create_tags",
+			Up:      "CREATE TABLE tags (id INTEGER PRIMARY KEY); CREATE INDEX idx_tags_id ON tags (id)",
+			Down:    "DROP TABLE tags",
+		},
+	})
+
+	return migrator
+}
+
+func TestMigrator_RunCommand(t *testing.T) {
+	migrator := setupCommandMigrator(t)
+	ctx := context.Background()
+	var out bytes.Buffer
+
+	require.NoError(t, migrator.RunCommand(ctx, &out, "plan"))
+	assert.Contains(t, out.String(), "DIRECTION")
+	assert.Regexp(t, `up\s+2\s+create_tags\s+2`, out.String())
+
+	out.Reset()
+	require.NoError(t, migrator.RunCommand(ctx, &out, "up"))
+	assert.Equal(t, "applied 1 create_posts\napplied 2 create_tags\n", out.String())
+
+	out.Reset()
+	require.NoError(t, migrator.RunCommand(ctx, &out, "up"))
+	assert.Equal(t, "nothing to do\n", out.String())
+
+	out.Reset()
+	require.NoError(t, migrator.RunCommand(ctx, &out, "to", "1"))
+	assert.Equal(t, "rolled back 2 create_tags\n", out.String())
+
+	out.Reset()
+	require.NoError(t, migrator.RunCommand(ctx, &out, "status"))
+	assert.Regexp(t, `VERSION\s+NAME\s+STATUS\s+APPLIED AT\s+DESCRIPTION`, out.String())
+	assert.Regexp(t, `1\s+create_posts\s+applied\s+\d{4}-\d{2}-\d{2} [\d:]+\s+Posts table`, out.String())
+	assert.Regexp(t, `2\s+create_tags\s+pending\s+-`, out.String())
+
+	out.Reset()
+	require.NoError(t, migrator.RunCommand(ctx, &out, "redo"))
+	assert.Equal(t, "redone 1 create_posts\n", out.String())
+
+	out.Reset()
+	require.NoError(t, migrator.RunCommand(ctx, &out, "down"))
+	assert.Equal(t, "rolled back 1 create_posts\n", out.String())
+}
+
+func TestMigrator_RunCommandTo(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+	var out bytes.Buffer
+
+	migrator.RegisterMultiple([]Migration{
+		{Version: 1, Name: "create_posts", Up: "CREATE TABLE posts (id INTEGER)", Down: "DROP TABLE posts"},
+		{Version: 3, Name: "create_users", Up: "CREATE TABLE users (id INTEGER)", Down: "DROP TABLE users"},
+	})
+	require.NoError(t, migrator.Up(ctx))
+
+	// a migration merged from another branch, older than the latest applied one
+	migrator.Register(Migration{
+		Version: 2,
+		Name:    "create_tags",
+		Up:      "CREATE TABLE tags (id INTEGER)",
+		Down:    "DROP TABLE tags",
+	})
+
+	// the rollback and the apply run in the same locked run
+	require.NoError(t, migrator.RunCommand(ctx, &out, "to", "2"))
+	assert.Equal(t, "rolled back 3 create_users\napplied 2 create_tags\n", out.String())
+}
+
+func TestMigrator_RunCommandInvalid(t *testing.T) {
+	migrator := setupCommandMigrator(t)
+	ctx := context.Background()
+	var out bytes.Buffer
+
+	for _, args := range [][]string{
+		{},
+		{"sideways"},
+		{"to"},
+		{"to", "latest"},
+		{"up", "2"},
+		{"plan", "1", "2"},
+	} {
+		err := migrator.RunCommand(ctx, &out, args...)
+		assert.ErrorIs(t, err, database.ErrInvalidMigrationCommand, "args: %v", args)
+	}
+
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,120 @@
+package migration
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+)
+
+// fileNameRe matches NNNN_name.up.sql, NNNN_name.down.sql and the dialect
+// specific variants NNNN_name.postgres.up.sql, NNNN_name.mysql.down.sql, ...
+var fileNameRe = regexp.MustCompile(
+	`^(\d+)_([A-Za-z0-9_-]+?)(?:\.(` + database.SQLite + `|` + database.Postgres + `|` + database.MySQL + `))?\.(up|down)\.sql$`,
+)
+
+// LoadFS loads the SQL migrations found in dir (use "." for the root) of fsys.
+// Files are named NNNN_name.up.sql and NNNN_name.down.sql, where NNNN is the version.
+// A dialect name before the direction (NNNN_name.postgres.up.sql) overrides the
+// generic file for that dialect. Other files are ignored.
+func LoadFS(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", database.ErrMigrationFailed, err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := fileNameRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf(
+				"%w: invalid migration file name %q, expected NNNN_name.up.sql or NNNN_name.down.sql",
+				database.ErrMigrationFailed,
+				entry.Name(),
+			)
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid version in %q: %v", database.ErrMigrationFailed, entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", database.ErrMigrationFailed, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d is used by %q and %q",
+				database.ErrMigrationFailed, version, m.Name, match[2])
+		}
+
+		setScript(m, match[3], match[4], string(body))
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%w: migration %d (%s) has no generic up file",
+				database.ErrMigrationFailed, m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// RegisterFS loads the SQL migrations in dir of fsys and registers them
+func (m *Migrator) RegisterFS(fsys fs.FS, dir string) error {
+	migrations, err := LoadFS(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	m.RegisterMultiple(migrations)
+
+	return nil
+}
+
+// setScript stores a file body as the generic or dialect script of m
+func setScript(m *Migration, dialect, direction, body string) {
+	if dialect == "" {
+		if direction == "up" {
+			m.Up = body
+		} else {
+			m.Down = body
+		}
+
+		return
+	}
+
+	if m.Dialects == nil {
+		m.Dialects = make(map[string]Script)
+	}
+
+	s := m.Dialects[dialect]
+	if direction == "up" {
+		s.Up = body
+	} else {
+		s.Down = body
+	}
+	m.Dialects[dialect] = s
+}
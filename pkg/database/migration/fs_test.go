@@ -0,0 +1,161 @@
+package migration
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_create_posts.up.sql": {
+			Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY, body BLOB);"),
+		},
+		"sql/0002_create_posts.postgres.up.sql": {
+			Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY, body BYTEA);"),
+		},
+		"sql/0002_create_posts.down.sql": {Data: []byte("DROP TABLE posts;")},
+		"sql/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")},
+		"sql/README.md":                  {Data: []byte("ignored")},
+	}
+
+	migrations, err := LoadFS(fsys, "sql")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Empty(t, migrations[0].Down)
+
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Equal(t, "create_posts", migrations[1].Name)
+	assert.Contains(t, migrations[1].UpFor(database.SQLite), "BLOB")
+	assert.Contains(t, migrations[1].UpFor(database.Postgres), "BYTEA")
+	assert.Equal(t, "DROP TABLE posts;", migrations[1].DownFor(database.Postgres))
+}
+
+func TestLoadFS_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"bad name":       {"create_users.up.sql": {Data: []byte("SELECT 1")}},
+		"missing up":     {"0001_create_users.down.sql": {Data: []byte("SELECT 1")}},
+		"version reused": {"0001_a.up.sql": {Data: []byte("SELECT 1")}, "0001_b.up.sql": {Data: []byte("SELECT 1")}},
+	}
+
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadFS(fsys, ".")
+			assert.ErrorIs(t, err, database.ErrMigrationFailed)
+		})
+	}
+}
+
+func TestMigrator_RegisterFS(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+
+	fsys := fstest.MapFS{
+		"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")},
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	}
+	require.NoError(t, migrator.RegisterFS(fsys, "."))
+	require.NoError(t, migrator.Up(ctx))
+
+	_, err := migrator.db.Exec(ctx, "INSERT INTO users (id) VALUES (1)")
+	assert.NoError(t, err)
+}
+
+func TestMigrator_ChecksumMismatch(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+
+	migration := Migration{
+		Version: 1,
+		Name:    "create_users",
+		Up:      "CREATE TABLE users (id INTEGER PRIMARY KEY)",
+		Down:    "DROP TABLE users",
+	}
+	migrator.Register(migration)
+	require.NoError(t, migrator.Up(ctx))
+
+	// the same database with an edited migration
+	edited := New(migrator.db)
+	migration.Up = "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"
+	edited.Register(migration)
+
+	err := edited.Up(ctx)
+	assert.ErrorIs(t, err, database.ErrMigrationModified)
+	assert.ErrorContains(t, err, "create_users")
+
+	err = edited.Down(ctx)
+	assert.ErrorIs(t, err, database.ErrMigrationModified)
+}
+
+func TestMigrator_LegacyTableWithoutChecksum(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+
+	_, err := migrator.db.Exec(ctx, `
+		CREATE TABLE schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	require.NoError(t, err)
+	_, err = migrator.db.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES (1, 'select_one')`)
+	require.NoError(t, err)
+
+	migration := Migration{Version: 1, Name: "select_one", Up: "SELECT 1", Down: "SELECT 1"}
+	migrator.Register(migration)
+	require.NoError(t, migrator.Up(ctx))
+
+	// the checksum is backfilled for rows applied before checksums existed
+	var checksum string
+	require.NoError(
+		t,
+		migrator.db.QueryRow(ctx, `SELECT checksum FROM schema_migrations WHERE version = 1`).Scan(&checksum),
+	)
+	assert.Equal(t, migration.Checksum(database.SQLite), checksum)
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	migrator := setupTestMigrator(t).WithLockTimeout(200 * time.Millisecond)
+	ctx := context.Background()
+	migrator.Register(Migration{Version: 1, Name: "select_one", Up: "SELECT 1"})
+
+	// another process holds the lock
+	unlock, err := migrator.lock(ctx)
+	require.NoError(t, err)
+
+	err = migrator.Up(ctx)
+	assert.ErrorIs(t, err, database.ErrMigrationLocked)
+
+	unlock()
+	assert.NoError(t, migrator.Up(ctx))
+}
+
+func TestMigrator_StaleLock(t *testing.T) {
+	migrator := setupTestMigrator(t).WithLockTimeout(200 * time.Millisecond).WithLockStaleAfter(time.Minute)
+	ctx := context.Background()
+	migrator.Register(Migration{Version: 1, Name: "select_one", Up: "SELECT 1"})
+
+	unlock, err := migrator.lock(ctx)
+	require.NoError(t, err)
+	unlock()
+
+	// a process died holding the lock an hour ago
+	_, err = migrator.db.Exec(ctx,
+		`INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)`, time.Now().UTC().Add(-time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(ctx))
+
+	// a recent lock is still respected
+	_, err = migrator.db.Exec(ctx,
+		`INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)`, time.Now().UTC().Add(-time.Second))
+	require.NoError(t, err)
+	assert.ErrorIs(t, migrator.Down(ctx), database.ErrMigrationLocked)
+}
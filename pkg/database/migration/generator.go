@@ -37,7 +37,11 @@ func NewWithVersion(version int64, name, description string) Migration {
 
 // Template generates a migration file template
 func Template(name string) string {
-	version := GenerateVersion()
+	return TemplateWithVersion(GenerateVersion(), name)
+}
+
+// TemplateWithVersion generates a migration file template with a specific version
+func TemplateWithVersion(version int64, name string) string {
 	return fmt.Sprintf(`package migrations
 
 import "github.com/jorgefuertes/martian-stack/pkg/database/migration"
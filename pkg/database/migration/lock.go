@@ -0,0 +1,175 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+)
+
+const (
+	// DefaultLockTimeout is how long the migrator waits for another process to release the lock
+	DefaultLockTimeout = time.Minute
+
+	// DefaultLockStaleAfter is the age of the SQLite lock row after which it is
+	// taken as abandoned by a dead process and taken over
+	DefaultLockStaleAfter = 10 * time.Minute
+
+	// lockName identifies the migration lock on MySQL (GET_LOCK)
+	lockName = "martian_stack_schema_migrations"
+
+	// lockKey identifies the migration lock on PostgreSQL (pg_advisory_lock)
+	lockKey int64 = 7_236_828_530_981_167_470
+
+	// lockPollInterval is the delay between lock attempts
+	lockPollInterval = 100 * time.Millisecond
+)
+
+// WithLockTimeout sets how long Up, Down and DownTo wait for the migration lock
+func (m *Migrator) WithLockTimeout(d time.Duration) *Migrator {
+	m.lockTimeout = d
+	return m
+}
+
+// WithLockStaleAfter sets the age of the SQLite lock row after which it is taken
+// as abandoned and taken over. It must be longer than the slowest migration run.
+// PostgreSQL and MySQL release their locks when the holder dies.
+func (m *Migrator) WithLockStaleAfter(d time.Duration) *Migrator {
+	m.lockStaleAfter = d
+	return m
+}
+
+// lock takes the database wide migration lock, so several processes starting
+// at once cannot apply the same migration twice. It uses an advisory lock on
+// PostgreSQL, GET_LOCK on MySQL and a lock row on SQLite.
+// The returned function releases the lock.
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	switch m.db.Dialect().Name() {
+	case database.Postgres:
+		return m.sessionLock(ctx,
+			`SELECT pg_try_advisory_lock($1)`, `SELECT pg_advisory_unlock($1)`, lockKey)
+	case database.MySQL:
+		return m.sessionLock(ctx,
+			`SELECT COALESCE(GET_LOCK(?, 0), 0) = 1`, `SELECT RELEASE_LOCK(?)`, lockName)
+	default:
+		return m.rowLock(ctx)
+	}
+}
+
+// sessionLock takes a lock bound to a database session, keeping a dedicated
+// connection open until the lock is released
+func (m *Migrator) sessionLock(ctx context.Context, tryQuery, unlockQuery string, key any) (func(), error) {
+	conn, err := m.db.DB().Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", database.ErrMigrationFailed, err)
+	}
+
+	err = m.waitLock(ctx, func() (bool, error) {
+		var acquired bool
+		err := conn.QueryRowContext(ctx, tryQuery, key).Scan(&acquired)
+		return acquired, err
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), unlockQuery, key)
+		_ = conn.Close()
+	}, nil
+}
+
+// rowLock takes the lock by inserting the single row of schema_migrations_lock.
+// A row older than the stale threshold was left by a dead process and is deleted.
+func (m *Migrator) rowLock(ctx context.Context) (func(), error) {
+	create := `CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+		id INTEGER PRIMARY KEY,
+		locked_at ` + m.db.Dialect().TimestampType() + ` NOT NULL
+	)`
+	if _, err := m.db.Exec(ctx, create); err != nil {
+		return nil, fmt.Errorf("%w: failed to create migrations lock table: %v", database.ErrMigrationFailed, err)
+	}
+
+	insert := m.db.Dialect().Rebind(`INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)`)
+	takeOver := m.db.Dialect().Rebind(`DELETE FROM schema_migrations_lock WHERE id = 1 AND locked_at < ?`)
+	tryInsert := func() (bool, error) {
+		if _, err := m.db.Exec(ctx, insert, time.Now().UTC()); err != nil {
+			if isConstraintError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	err := m.waitLock(ctx, func() (bool, error) {
+		acquired, err := tryInsert()
+		if acquired || err != nil {
+			return acquired, err
+		}
+
+		res, err := m.db.Exec(ctx, takeOver, time.Now().UTC().Add(-m.lockStaleAfter))
+		if err != nil {
+			return false, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return false, err
+		}
+
+		// the stale lock is gone, unless another process takes it first
+		return tryInsert()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_, _ = m.db.Exec(context.WithoutCancel(ctx), `DELETE FROM schema_migrations_lock WHERE id = 1`)
+	}, nil
+}
+
+// waitLock calls try until it acquires the lock, the lock timeout expires or ctx is done
+func (m *Migrator) waitLock(ctx context.Context, try func() (bool, error)) error {
+	deadline := time.Now().Add(m.lockTimeout)
+
+	for {
+		acquired, err := try()
+		if err != nil {
+			return fmt.Errorf("%w: failed to acquire migration lock: %v", database.ErrMigrationFailed, err)
+		}
+		if acquired {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: gave up after %s", database.ErrMigrationLocked, m.lockTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(database.ErrMigrationLocked, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// isConstraintError checks if the error is a primary key or unique constraint violation
+func isConstraintError(err error) bool {
+	errMsg := err.Error()
+	for _, s := range []string{
+		"constraint failed",   // SQLite
+		"duplicate key value", // PostgreSQL
+		"Duplicate entry",     // MySQL/MariaDB
+	} {
+		if strings.Contains(errMsg, s) {
+			return true
+		}
+	}
+
+	return false
+}
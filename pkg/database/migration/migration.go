@@ -2,34 +2,93 @@ package migration
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"sort"
 	"time"
 
 	"github.com/jorgefuertes/martian-stack/pkg/database"
 )
 
-// Migration represents a database migration
+// Migration represents a database migration.
+// Up and Down are the generic scripts, Dialects overrides them for a given
+// dialect (database.SQLite, database.Postgres or database.MySQL).
+// Scripts may contain several statements separated by semicolons.
+// UpFunc and DownFunc run after the scripts, in the same transaction.
 type Migration struct {
 	Version     int64
 	Name        string
 	Up          string
 	Down        string
+	Dialects    map[string]Script
+	UpFunc      Func
+	DownFunc    Func
 	AppliedAt   *time.Time
 	Description string
 }
 
+// Func is a Go migration step for backfills and data fixes.
+// It runs inside the migration transaction: every query made through db
+// with ctx joins it, so use ctx for all of them.
+type Func func(ctx context.Context, db database.Database) error
+
+// Script holds dialect specific migration scripts.
+// An empty Up or Down falls back to the generic script of the migration.
+type Script struct {
+	Up   string
+	Down string
+}
+
+// UpFor returns the up script for the given dialect name
+func (m Migration) UpFor(dialect string) string {
+	if s, ok := m.Dialects[dialect]; ok && s.Up != "" {
+		return s.Up
+	}
+
+	return m.Up
+}
+
+// DownFor returns the down script for the given dialect name
+func (m Migration) DownFor(dialect string) string {
+	if s, ok := m.Dialects[dialect]; ok && s.Down != "" {
+		return s.Down
+	}
+
+	return m.Down
+}
+
+// Checksum returns the SHA-256 of the up and down scripts used for the given dialect.
+// It is stored when the migration is applied to detect later edits.
+// Go functions are not part of the checksum.
+func (m Migration) Checksum(dialect string) string {
+	sum := sha256.Sum256([]byte(m.UpFor(dialect) + "\x00" + m.DownFor(dialect)))
+	return hex.EncodeToString(sum[:])
+}
+
 // Migrator manages database migrations
 type Migrator struct {
-	db         database.Database
-	migrations []Migration
+	db              database.Database
+	migrations      []Migration
+	lockTimeout     time.Duration
+	lockStaleAfter  time.Duration
+	allowOutOfOrder bool
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	appliedAt time.Time
+	checksum  string
 }
 
 // New creates a new Migrator
 func New(db database.Database) *Migrator {
 	return &Migrator{
-		db:         db,
-		migrations: make([]Migration, 0),
+		db:             db,
+		migrations:     make([]Migration, 0),
+		lockTimeout:    DefaultLockTimeout,
+		lockStaleAfter: DefaultLockStaleAfter,
 	}
 }
 
@@ -43,13 +102,15 @@ func (m *Migrator) RegisterMultiple(migrations []Migration) {
 	m.migrations = append(m.migrations, migrations...)
 }
 
-// Init creates the migrations table if it doesn't exist
+// Init creates the migrations table if it doesn't exist. Up, Down and the
+// other runs call it under the migration lock, so calling it first is not needed.
 func (m *Migrator) Init(ctx context.Context) error {
 	query := `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version BIGINT PRIMARY KEY,
 			name VARCHAR(255) NOT NULL,
-			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			applied_at ` + m.db.Dialect().TimestampType() + ` NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			checksum VARCHAR(64) NOT NULL DEFAULT ''
 		)
 	`
 
@@ -58,125 +119,102 @@ func (m *Migrator) Init(ctx context.Context) error {
 		return fmt.Errorf("%w: failed to create migrations table: %v", database.ErrMigrationFailed, err)
 	}
 
-	return nil
-}
-
-// Up runs all pending migrations
-func (m *Migrator) Up(ctx context.Context) error {
-	// Ensure migrations table exists
-	if err := m.Init(ctx); err != nil {
-		return err
+	// add the checksum column to tables created before checksums were stored
+	if m.canQuery(ctx, `SELECT checksum FROM schema_migrations WHERE 1 = 0`) {
+		return nil
 	}
 
-	// Get applied migrations
-	applied, err := m.getAppliedMigrations(ctx)
-	if err != nil {
-		return err
+	alter := `ALTER TABLE schema_migrations ADD COLUMN checksum VARCHAR(64) NOT NULL DEFAULT ''`
+	if _, err := m.db.Exec(ctx, alter); err != nil {
+		return fmt.Errorf("%w: failed to add checksum column: %v", database.ErrMigrationFailed, err)
 	}
 
-	// Sort migrations by version
-	sort.Slice(m.migrations, func(i, j int) bool {
-		return m.migrations[i].Version < m.migrations[j].Version
-	})
-
-	// Run pending migrations
-	for _, migration := range m.migrations {
-		if _, exists := applied[migration.Version]; exists {
-			continue // Already applied
-		}
+	return nil
+}
 
-		if err := m.applyMigration(ctx, migration); err != nil {
-			return fmt.Errorf("%w: failed to apply migration %d (%s): %v",
-				database.ErrMigrationFailed, migration.Version, migration.Name, err)
-		}
-	}
+// Up runs all pending migrations
+func (m *Migrator) Up(ctx context.Context) error {
+	_, err := m.run(ctx, m.upPlanner(math.MaxInt64))
+	return err
+}
 
-	return nil
+// UpTo runs the pending migrations up to and including version
+func (m *Migrator) UpTo(ctx context.Context, version int64) error {
+	_, err := m.run(ctx, m.upPlanner(version))
+	return err
 }
 
 // Down rolls back the last migration
 func (m *Migrator) Down(ctx context.Context) error {
-	// Get applied migrations
-	applied, err := m.getAppliedMigrations(ctx)
-	if err != nil {
-		return err
-	}
+	_, err := m.run(ctx, m.downPlanner(math.MinInt64, 1))
+	return err
+}
 
-	if len(applied) == 0 {
-		return nil // No migrations to rollback
-	}
+// DownTo rolls back migrations to a specific version
+func (m *Migrator) DownTo(ctx context.Context, targetVersion int64) error {
+	_, err := m.run(ctx, m.downPlanner(targetVersion, -1))
+	return err
+}
 
-	// Find the last applied migration
-	var lastVersion int64
-	for version := range applied {
-		if version > lastVersion {
-			lastVersion = version
-		}
-	}
+// Redo rolls back the last migration and applies it again
+func (m *Migrator) Redo(ctx context.Context) error {
+	_, err := m.run(ctx, m.redoPlanner())
+	return err
+}
 
-	// Find the migration definition
-	var targetMigration *Migration
-	for i := range m.migrations {
-		if m.migrations[i].Version == lastVersion {
-			targetMigration = &m.migrations[i]
-			break
-		}
+// run takes the migration lock, plans the steps against the applied migrations
+// and executes them in order, each one in its own transaction. It returns the
+// executed steps.
+func (m *Migrator) run(ctx context.Context, plan planner) ([]Step, error) {
+	// the locks do not use schema_migrations, so it is created or altered
+	// by one process at a time
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	if targetMigration == nil {
-		return fmt.Errorf(
-			"%w: migration %d not found in registered migrations",
-			database.ErrMigrationFailed,
-			lastVersion,
-		)
+	// Ensure migrations table exists
+	if err := m.Init(ctx); err != nil {
+		return nil, err
 	}
 
-	if targetMigration.Down == "" {
-		return fmt.Errorf("%w: migration %d has no down script", database.ErrMigrationFailed, lastVersion)
+	// Get applied migrations, read after taking the lock so another
+	// process cannot apply them in between
+	applied, err := m.load(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return m.rollbackMigration(ctx, *targetMigration)
-}
+	if err := m.backfillChecksums(ctx, applied); err != nil {
+		return nil, err
+	}
 
-// DownTo rolls back migrations to a specific version
-func (m *Migrator) DownTo(ctx context.Context, targetVersion int64) error {
-	// Get applied migrations
-	applied, err := m.getAppliedMigrations(ctx)
+	steps, err := plan(applied)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Sort migrations by version (descending)
-	var versions []int64
-	for version := range applied {
-		if version > targetVersion {
-			versions = append(versions, version)
-		}
+	if err := m.checkOrder(steps); err != nil {
+		return nil, err
 	}
-	sort.Slice(versions, func(i, j int) bool {
-		return versions[i] > versions[j]
-	})
 
-	// Rollback migrations
-	for _, version := range versions {
-		var targetMigration *Migration
-		for i := range m.migrations {
-			if m.migrations[i].Version == version {
-				targetMigration = &m.migrations[i]
-				break
+	for i, step := range steps {
+		if step.Direction == DirectionDown {
+			if err := m.rollbackMigration(ctx, step.Migration); err != nil {
+				return steps[:i], fmt.Errorf("%w: failed to roll back migration %d (%s): %v",
+					database.ErrMigrationFailed, step.Migration.Version, step.Migration.Name, err)
 			}
+			continue
 		}
 
-		if targetMigration == nil {
-			return fmt.Errorf("%w: migration %d not found", database.ErrMigrationFailed, version)
-		}
-
-		if err := m.rollbackMigration(ctx, *targetMigration); err != nil {
-			return err
+		if err := m.applyMigration(ctx, step.Migration); err != nil {
+			return steps[:i], fmt.Errorf("%w: failed to apply migration %d (%s): %v",
+				database.ErrMigrationFailed, step.Migration.Version, step.Migration.Name, err)
 		}
 	}
 
-	return nil
+	return steps, nil
 }
 
 // Status returns the current migration status
@@ -200,8 +238,8 @@ func (m *Migrator) Status(ctx context.Context) ([]Migration, error) {
 	status := make([]Migration, len(m.migrations))
 	for i, migration := range m.migrations {
 		status[i] = migration
-		if appliedAt, exists := applied[migration.Version]; exists {
-			status[i].AppliedAt = &appliedAt
+		if a, exists := applied[migration.Version]; exists {
+			status[i].AppliedAt = &a.appliedAt
 		}
 	}
 
@@ -209,8 +247,14 @@ func (m *Migrator) Status(ctx context.Context) ([]Migration, error) {
 }
 
 // getAppliedMigrations retrieves all applied migrations from the database
-func (m *Migrator) getAppliedMigrations(ctx context.Context) (map[int64]time.Time, error) {
-	query := `SELECT version, applied_at FROM schema_migrations ORDER BY version`
+func (m *Migrator) getAppliedMigrations(ctx context.Context) (map[int64]appliedMigration, error) {
+	return m.queryApplied(ctx, "checksum")
+}
+
+// queryApplied reads schema_migrations, taking the checksums from checksumColumn,
+// which is an empty string literal for the tables created before checksums
+func (m *Migrator) queryApplied(ctx context.Context, checksumColumn string) (map[int64]appliedMigration, error) {
+	query := `SELECT version, applied_at, ` + checksumColumn + ` FROM schema_migrations ORDER BY version`
 
 	rows, err := m.db.Query(ctx, query)
 	if err != nil {
@@ -218,61 +262,121 @@ func (m *Migrator) getAppliedMigrations(ctx context.Context) (map[int64]time.Tim
 	}
 	defer rows.Close()
 
-	applied := make(map[int64]time.Time)
+	applied := make(map[int64]appliedMigration)
 	for rows.Next() {
 		var version int64
-		var appliedAt time.Time
-		if err := rows.Scan(&version, &appliedAt); err != nil {
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.appliedAt, &a.checksum); err != nil {
 			return nil, err
 		}
-		applied[version] = appliedAt
+		applied[version] = a
 	}
 
 	return applied, rows.Err()
 }
 
-// applyMigration applies a single migration
-func (m *Migrator) applyMigration(ctx context.Context, migration Migration) error {
-	// Start transaction
-	tx, err := m.db.BeginTx(ctx)
+// load reads the applied migrations and verifies them
+func (m *Migrator) load(ctx context.Context) (map[int64]appliedMigration, error) {
+	applied, err := m.getAppliedMigrations(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer tx.Rollback()
 
-	// Execute migration
-	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
-		return err
+	return applied, m.verify(applied)
+}
+
+// verify sorts the registered migrations by version and refuses to continue
+// when a registered migration was edited after being applied
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+
+	dialect := m.db.Dialect().Name()
+	for _, migration := range m.migrations {
+		a, exists := applied[migration.Version]
+		if !exists || a.checksum == "" {
+			continue
+		}
+
+		if checksum := migration.Checksum(dialect); a.checksum != checksum {
+			return fmt.Errorf("%w: migration %d (%s) was edited after being applied "+
+				"(checksum %s, now %s); revert the change and add a new migration instead",
+				database.ErrMigrationModified, migration.Version, migration.Name, a.checksum, checksum)
+		}
 	}
 
-	// Record migration
-	insertQuery := `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`
-	if _, err := tx.ExecContext(ctx, insertQuery, migration.Version, migration.Name, time.Now()); err != nil {
-		return err
+	return nil
+}
+
+// backfillChecksums stores the current checksum of the migrations applied
+// before checksums were stored
+func (m *Migrator) backfillChecksums(ctx context.Context, applied map[int64]appliedMigration) error {
+	query := m.db.Dialect().Rebind(`UPDATE schema_migrations SET checksum = ? WHERE version = ?`)
+
+	for _, migration := range m.migrations {
+		a, exists := applied[migration.Version]
+		if !exists || a.checksum != "" {
+			continue
+		}
+
+		if _, err := m.db.Exec(ctx, query, migration.Checksum(m.db.Dialect().Name()), migration.Version); err != nil {
+			return fmt.Errorf("%w: failed to store checksum of migration %d: %v",
+				database.ErrMigrationFailed, migration.Version, err)
+		}
 	}
 
-	return tx.Commit()
+	return nil
+}
+
+// applyMigration applies a single migration
+func (m *Migrator) applyMigration(ctx context.Context, migration Migration) error {
+	return database.WithTxConfig(ctx, m.db, database.TxConfig{}, func(txCtx context.Context) error {
+		// Execute migration, one statement at a time since not every driver
+		// accepts several statements in a single call
+		for _, stmt := range SplitStatements(migration.UpFor(m.db.Dialect().Name())) {
+			if _, err := m.db.Exec(txCtx, stmt); err != nil {
+				return err
+			}
+		}
+
+		if migration.UpFunc != nil {
+			if err := migration.UpFunc(txCtx, m.db); err != nil {
+				return err
+			}
+		}
+
+		// Record migration
+		insertQuery := m.db.Dialect().Rebind(
+			`INSERT INTO schema_migrations (version, name, applied_at, checksum) VALUES (?, ?, ?, ?)`,
+		)
+		checksum := migration.Checksum(m.db.Dialect().Name())
+		_, err := m.db.Exec(txCtx, insertQuery, migration.Version, migration.Name, time.Now(), checksum)
+
+		return err
+	})
 }
 
 // rollbackMigration rolls back a single migration
 func (m *Migrator) rollbackMigration(ctx context.Context, migration Migration) error {
-	// Start transaction
-	tx, err := m.db.BeginTx(ctx)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
+	return database.WithTxConfig(ctx, m.db, database.TxConfig{}, func(txCtx context.Context) error {
+		// Execute rollback
+		for _, stmt := range SplitStatements(migration.DownFor(m.db.Dialect().Name())) {
+			if _, err := m.db.Exec(txCtx, stmt); err != nil {
+				return err
+			}
+		}
 
-	// Execute rollback
-	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
-		return err
-	}
+		if migration.DownFunc != nil {
+			if err := migration.DownFunc(txCtx, m.db); err != nil {
+				return err
+			}
+		}
 
-	// Remove migration record
-	deleteQuery := `DELETE FROM schema_migrations WHERE version = ?`
-	if _, err := tx.ExecContext(ctx, deleteQuery, migration.Version); err != nil {
-		return err
-	}
+		// Remove migration record
+		deleteQuery := m.db.Dialect().Rebind(`DELETE FROM schema_migrations WHERE version = ?`)
+		_, err := m.db.Exec(txCtx, deleteQuery, migration.Version)
 
-	return tx.Commit()
+		return err
+	})
 }
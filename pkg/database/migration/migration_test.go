@@ -2,9 +2,16 @@ package migration
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/jorgefuertes/martian-stack/pkg/database"
 	"github.com/jorgefuertes/martian-stack/pkg/database/sqlite"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -132,6 +139,69 @@ func TestMigrator_UpIdempotent(t *testing.T) {
 	assert.Len(t, applied, 1)
 }
 
+// slowAlterDB delays the schema changes, so the other replicas reach
+// the same point meanwhile unless the migration lock keeps them out
+type slowAlterDB struct {
+	database.Database
+}
+
+func (db slowAlterDB) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if strings.HasPrefix(query, "ALTER TABLE") {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return db.Database.Exec(ctx, query, args...)
+}
+
+func TestMigrator_ConcurrentStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	ctx := context.Background()
+
+	// a migrations table from before the checksums, so Init has to alter it
+	db, err := sqlite.New(sqlite.DefaultConfig(path))
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, `CREATE TABLE schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// several replicas starting at once, each with its own connection
+	const replicas = 8
+	errs := make(chan error, replicas)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range replicas {
+		db, err := sqlite.New(sqlite.DefaultConfig(path))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		migrator := New(slowAlterDB{db}).WithLockTimeout(10 * time.Second)
+		migrator.Register(Migration{
+			Version: 1,
+			Name:    "create_users",
+			Up:      "CREATE TABLE users (id INTEGER PRIMARY KEY)",
+			Down:    "DROP TABLE users",
+		})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- migrator.Up(ctx)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
 func TestMigrator_Down(t *testing.T) {
 	migrator := setupTestMigrator(t)
 	ctx := context.Background()
@@ -278,8 +348,8 @@ func TestMigrator_MigrationOrdering(t *testing.T) {
 	assert.Len(t, applied, 3)
 
 	// Check timestamps are in order (1 < 2 < 3)
-	assert.True(t, applied[int64(1)].Before(applied[int64(2)]))
-	assert.True(t, applied[int64(2)].Before(applied[int64(3)]))
+	assert.True(t, applied[int64(1)].appliedAt.Before(applied[int64(2)].appliedAt))
+	assert.True(t, applied[int64(2)].appliedAt.Before(applied[int64(3)].appliedAt))
 }
 
 func TestMigrator_TransactionRollback(t *testing.T) {
@@ -305,6 +375,268 @@ func TestMigrator_TransactionRollback(t *testing.T) {
 	assert.Len(t, applied, 0)
 }
 
+func TestMigrator_MultipleStatements(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+
+	migrator.Register(Migration{
+		Version: 20260101000001,
+		Name:    "create_tags",
+		Up: `
+CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT);
+INSERT INTO tags (name) VALUES ('a;b');
+INSERT INTO tags (name) VALUES ('c');
+`,
+		Down: "DROP TABLE tags;",
+	})
+
+	require.NoError(t, migrator.Up(ctx))
+
+	var count int
+	require.NoError(t, migrator.db.QueryRow(ctx, "SELECT COUNT(*) FROM tags").Scan(&count))
+	assert.Equal(t, 2, count)
+}
+
+func TestMigrator_DialectOverride(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+
+	migrator.Register(Migration{
+		Version: 20260101000001,
+		Name:    "create_items",
+		Up:      "INVALID GENERIC SQL",
+		Down:    "DROP TABLE items",
+		Dialects: map[string]Script{
+			database.SQLite:   {Up: "CREATE TABLE items (id INTEGER PRIMARY KEY)"},
+			database.Postgres: {Up: "ALSO INVALID"},
+		},
+	})
+
+	// the sqlite override is used for the up script, the generic one for down
+	require.NoError(t, migrator.Up(ctx))
+	require.NoError(t, migrator.Down(ctx))
+
+	applied, err := migrator.getAppliedMigrations(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestMigration_ScriptFor(t *testing.T) {
+	m := Migration{
+		Up:   "generic up",
+		Down: "generic down",
+		Dialects: map[string]Script{
+			database.Postgres: {Up: "pg up"},
+		},
+	}
+
+	assert.Equal(t, "pg up", m.UpFor(database.Postgres))
+	assert.Equal(t, "generic down", m.DownFor(database.Postgres))
+	assert.Equal(t, "generic up", m.UpFor(database.MySQL))
+}
+
+func TestMigrator_UpFunc(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+
+	migrator.RegisterMultiple([]Migration{
+		{
+			Version: 1,
+			Name:    "create_users",
+			Up:      "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, slug TEXT)",
+			Down:    "DROP TABLE users",
+		},
+		{
+			Version: 2,
+			Name:    "backfill_slugs",
+			UpFunc: func(ctx context.Context, db database.Database) error {
+				if _, err := db.Exec(ctx, "INSERT INTO users (name) VALUES ('Ann'), ('Bob')"); err != nil {
+					return err
+				}
+				_, err := db.Exec(ctx, "UPDATE users SET slug = LOWER(name)")
+				return err
+			},
+			DownFunc: func(ctx context.Context, db database.Database) error {
+				_, err := db.Exec(ctx, "DELETE FROM users")
+				return err
+			},
+		},
+	})
+
+	require.NoError(t, migrator.Up(ctx))
+
+	var slug string
+	require.NoError(t, migrator.db.QueryRow(ctx, "SELECT slug FROM users WHERE name = 'Bob'").Scan(&slug))
+	assert.Equal(t, "bob", slug)
+
+	require.NoError(t, migrator.Down(ctx))
+
+	var count int
+	require.NoError(t, migrator.db.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestMigrator_UpFuncRollback(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+
+	migrator.Register(Migration{
+		Version: 1,
+		Name:    "failing_backfill",
+		Up:      "CREATE TABLE items (id INTEGER PRIMARY KEY)",
+		UpFunc: func(ctx context.Context, db database.Database) error {
+			if _, err := db.Exec(ctx, "INSERT INTO items (id) VALUES (1)"); err != nil {
+				return err
+			}
+			return errors.New("backfill failed")
+		},
+	})
+
+	err := migrator.Up(ctx)
+	assert.ErrorIs(t, err, database.ErrMigrationFailed)
+	assert.ErrorContains(t, err, "backfill failed")
+
+	// the script and the function share the transaction
+	var name string
+	err = migrator.db.QueryRow(ctx, `SELECT name FROM sqlite_master WHERE type='table' AND name='items'`).Scan(&name)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	applied, err := migrator.getAppliedMigrations(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func registerNumbered(migrator *Migrator, versions ...int64) {
+	for _, v := range versions {
+		table := fmt.Sprintf("t%d", v)
+		migrator.Register(Migration{
+			Version: v,
+			Name:    "create_" + table,
+			Up:      "CREATE TABLE " + table + " (id INTEGER PRIMARY KEY)",
+			Down:    "DROP TABLE " + table,
+		})
+	}
+}
+
+func TestMigrator_UpTo(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+	registerNumbered(migrator, 1, 2, 3)
+
+	require.NoError(t, migrator.UpTo(ctx, 2))
+
+	applied, err := migrator.getAppliedMigrations(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.NotContains(t, applied, int64(3))
+}
+
+func TestMigrator_Redo(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+	registerNumbered(migrator, 1, 2)
+
+	require.NoError(t, migrator.Up(ctx))
+	_, err := migrator.db.Exec(ctx, "INSERT INTO t2 (id) VALUES (1)")
+	require.NoError(t, err)
+
+	require.NoError(t, migrator.Redo(ctx))
+
+	// t2 was dropped and created again
+	var count int
+	require.NoError(t, migrator.db.QueryRow(ctx, "SELECT COUNT(*) FROM t2").Scan(&count))
+	assert.Equal(t, 0, count)
+
+	applied, err := migrator.getAppliedMigrations(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+}
+
+func TestMigrator_Plan(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+	registerNumbered(migrator, 1, 2, 3)
+
+	require.NoError(t, migrator.UpTo(ctx, 1))
+
+	steps, err := migrator.Plan(ctx)
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.Equal(t, DirectionUp, steps[0].Direction)
+	assert.Equal(t, int64(2), steps[0].Migration.Version)
+	assert.Equal(t, []string{"CREATE TABLE t2 (id INTEGER PRIMARY KEY)"}, steps[0].Statements)
+	assert.Equal(t, int64(3), steps[1].Migration.Version)
+
+	// nothing was executed
+	applied, err := migrator.getAppliedMigrations(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 1)
+
+	require.NoError(t, migrator.Up(ctx))
+
+	steps, err = migrator.PlanDownTo(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.Equal(t, DirectionDown, steps[0].Direction)
+	assert.Equal(t, int64(3), steps[0].Migration.Version)
+	assert.Equal(t, int64(2), steps[1].Migration.Version)
+
+	steps, err = migrator.PlanDown(ctx)
+	require.NoError(t, err)
+	require.Len(t, steps, 1)
+	assert.Equal(t, int64(3), steps[0].Migration.Version)
+}
+
+func TestMigrator_PlanReadOnly(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+	registerNumbered(migrator, 1, 2)
+
+	// a plan on a new database does not create the migrations table
+	steps, err := migrator.Plan(ctx)
+	require.NoError(t, err)
+	assert.Len(t, steps, 2)
+	assert.False(t, migrator.canQuery(ctx, `SELECT 1 FROM schema_migrations`))
+
+	// nor adds the checksum column to a table created before checksums
+	_, err = migrator.db.Exec(ctx, `
+		CREATE TABLE schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	require.NoError(t, err)
+	_, err = migrator.db.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES (1, 'create_t1')`)
+	require.NoError(t, err)
+
+	steps, err = migrator.Plan(ctx)
+	require.NoError(t, err)
+	require.Len(t, steps, 1)
+	assert.Equal(t, int64(2), steps[0].Migration.Version)
+	assert.False(t, migrator.canQuery(ctx, `SELECT checksum FROM schema_migrations`))
+}
+
+func TestMigrator_OutOfOrder(t *testing.T) {
+	migrator := setupTestMigrator(t)
+	ctx := context.Background()
+	registerNumbered(migrator, 1, 3)
+	require.NoError(t, migrator.Up(ctx))
+
+	// a migration from another branch, older than the latest applied one
+	registerNumbered(migrator, 2)
+
+	steps, err := migrator.Plan(ctx)
+	require.NoError(t, err)
+	require.Len(t, steps, 1)
+	assert.True(t, steps[0].OutOfOrder)
+
+	err = migrator.Up(ctx)
+	assert.ErrorIs(t, err, database.ErrMigrationOutOfOrder)
+	assert.ErrorContains(t, err, "2 (create_t2)")
+
+	require.NoError(t, migrator.WithOutOfOrder(true).Up(ctx))
+}
+
 func TestGenerateVersion(t *testing.T) {
 	version := GenerateVersion()
 	assert.Greater(t, version, int64(20260101000000))
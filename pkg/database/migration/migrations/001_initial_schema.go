@@ -1,13 +1,41 @@
 package migrations
 
-import "github.com/jorgefuertes/martian-stack/pkg/database/migration"
+import (
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+)
 
 // InitialSchema creates the initial accounts table
 var InitialSchema = migration.Migration{
 	Version:     20260212000001,
 	Name:        "initial_schema",
 	Description: "Create accounts table with indexes",
-	Up: `
+	Up:          initialSchemaUp("BLOB", createIndexIfNotExists),
+	Down: `
+DROP INDEX IF EXISTS idx_accounts_role;
+DROP INDEX IF EXISTS idx_accounts_enabled;
+DROP INDEX IF EXISTS idx_accounts_email;
+DROP INDEX IF EXISTS idx_accounts_username;
+DROP TABLE IF EXISTS accounts;
+`,
+	Dialects: map[string]migration.Script{
+		// PostgreSQL has no BLOB type
+		database.Postgres: {
+			Up: initialSchemaUp(database.DialectFor(database.Postgres).BlobType(), createIndexIfNotExists),
+		},
+		// MySQL has no CREATE INDEX IF NOT EXISTS nor DROP INDEX IF EXISTS,
+		// the indexes go away with the table
+		database.MySQL: {
+			Up:   initialSchemaUp("BLOB", createIndex),
+			Down: `DROP TABLE IF EXISTS accounts;`,
+		},
+	},
+}
+
+// initialSchemaUp creates the accounts table and its indexes, with blobType for
+// the password hash and createIndex to start the index statements
+func initialSchemaUp(blobType, createIndex string) string {
+	return `
 CREATE TABLE IF NOT EXISTS accounts (
 	id VARCHAR(36) PRIMARY KEY,
 	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -18,19 +46,12 @@ CREATE TABLE IF NOT EXISTS accounts (
 	email VARCHAR(255) NOT NULL UNIQUE,
 	enabled BOOLEAN NOT NULL DEFAULT true,
 	role VARCHAR(10) NOT NULL DEFAULT 'user',
-	crypted_password BLOB NOT NULL
+	crypted_password ` + blobType + ` NOT NULL
 );
 
-CREATE INDEX idx_accounts_username ON accounts(username);
-CREATE INDEX idx_accounts_email ON accounts(email);
-CREATE INDEX idx_accounts_enabled ON accounts(enabled);
-CREATE INDEX idx_accounts_role ON accounts(role);
-`,
-	Down: `
-DROP INDEX IF EXISTS idx_accounts_role;
-DROP INDEX IF EXISTS idx_accounts_enabled;
-DROP INDEX IF EXISTS idx_accounts_email;
-DROP INDEX IF EXISTS idx_accounts_username;
-DROP TABLE IF EXISTS accounts;
-`,
+` + createIndex + ` idx_accounts_username ON accounts(username);
+` + createIndex + ` idx_accounts_email ON accounts(email);
+` + createIndex + ` idx_accounts_enabled ON accounts(enabled);
+` + createIndex + ` idx_accounts_role ON accounts(role);
+`
 }
@@ -1,13 +1,44 @@
 package migrations
 
-import "github.com/jorgefuertes/martian-stack/pkg/database/migration"
+import (
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+)
 
 // AddTokenTables creates tables for refresh tokens and password reset tokens
 var AddTokenTables = migration.Migration{
 	Version:     20260213000001,
 	Name:        "add_token_tables",
 	Description: "Create refresh_tokens and password_reset_tokens tables",
-	Up: `
+	Up:          addTokenTablesUp(createIndexIfNotExists),
+	Down: `
+DROP INDEX IF EXISTS idx_password_reset_tokens_expires_at;
+DROP INDEX IF EXISTS idx_password_reset_tokens_token_hash;
+DROP INDEX IF EXISTS idx_password_reset_tokens_user_id;
+DROP TABLE IF EXISTS password_reset_tokens;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
+DROP INDEX IF EXISTS idx_refresh_tokens_token_hash;
+DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
+DROP TABLE IF EXISTS refresh_tokens;
+`,
+	Dialects: map[string]migration.Script{
+		// MySQL has no CREATE INDEX IF NOT EXISTS nor DROP INDEX IF EXISTS,
+		// the indexes go away with the tables
+		database.MySQL: {
+			Up: addTokenTablesUp(createIndex),
+			Down: `
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+`,
+		},
+	},
+}
+
+// addTokenTablesUp creates the token tables and their indexes, with createIndex
+// to start the index statements
+func addTokenTablesUp(createIndex string) string {
+	return `
 CREATE TABLE IF NOT EXISTS refresh_tokens (
 	id VARCHAR(36) PRIMARY KEY,
 	user_id VARCHAR(36) NOT NULL,
@@ -18,9 +49,9 @@ CREATE TABLE IF NOT EXISTS refresh_tokens (
 	FOREIGN KEY (user_id) REFERENCES accounts(id) ON DELETE CASCADE
 );
 
-CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
-CREATE INDEX idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
-CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+` + createIndex + ` idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+` + createIndex + ` idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+` + createIndex + ` idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
 
 CREATE TABLE IF NOT EXISTS password_reset_tokens (
 	id VARCHAR(36) PRIMARY KEY,
@@ -32,19 +63,8 @@ CREATE TABLE IF NOT EXISTS password_reset_tokens (
 	FOREIGN KEY (user_id) REFERENCES accounts(id) ON DELETE CASCADE
 );
 
-CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
-CREATE INDEX idx_password_reset_tokens_token_hash ON password_reset_tokens(token_hash);
-CREATE INDEX idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at);
-`,
-	Down: `
-DROP INDEX IF EXISTS idx_password_reset_tokens_expires_at;
-DROP INDEX IF EXISTS idx_password_reset_tokens_token_hash;
-DROP INDEX IF EXISTS idx_password_reset_tokens_user_id;
-DROP TABLE IF EXISTS password_reset_tokens;
-
-DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
-DROP INDEX IF EXISTS idx_refresh_tokens_token_hash;
-DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
-DROP TABLE IF EXISTS refresh_tokens;
-`,
+` + createIndex + ` idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+` + createIndex + ` idx_password_reset_tokens_token_hash ON password_reset_tokens(token_hash);
+` + createIndex + ` idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at);
+`
 }
@@ -10,3 +10,10 @@ func All() []migration.Migration {
 		// Add new migrations here
 	}
 }
+
+// The index statements: the existing indexes are kept, except on MySQL,
+// which has no CREATE INDEX IF NOT EXISTS
+const (
+	createIndexIfNotExists = "CREATE INDEX IF NOT EXISTS"
+	createIndex            = "CREATE INDEX"
+)
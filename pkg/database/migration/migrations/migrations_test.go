@@ -0,0 +1,72 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration/migrations"
+	"github.com/jorgefuertes/martian-stack/pkg/database/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll_UpAndDown(t *testing.T) {
+	db, err := sqlite.NewInMemory()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	m := migration.New(db)
+	m.RegisterMultiple(migrations.All())
+
+	require.NoError(t, m.Up(ctx))
+
+	for _, table := range []string{"accounts", "refresh_tokens", "password_reset_tokens"} {
+		var n int
+		require.NoError(t, db.QueryRow(ctx, "SELECT COUNT(*) FROM "+table).Scan(&n))
+	}
+
+	require.NoError(t, m.DownTo(ctx, 0))
+
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range status {
+		assert.Nil(t, s.AppliedAt)
+	}
+}
+
+func TestAll_EveryDialectHasStatements(t *testing.T) {
+	for _, m := range migrations.All() {
+		for _, dialect := range []string{database.SQLite, database.Postgres, database.MySQL} {
+			assert.NotEmpty(t, migration.SplitStatements(m.UpFor(dialect)), "%s up on %s", m.Name, dialect)
+			assert.NotEmpty(t, migration.SplitStatements(m.DownFor(dialect)), "%s down on %s", m.Name, dialect)
+		}
+
+		// PostgreSQL has no BLOB type and MySQL no CREATE/DROP INDEX IF (NOT) EXISTS
+		assert.NotContains(t, m.UpFor(database.Postgres), "BLOB", m.Name)
+		assert.NotContains(t, m.UpFor(database.MySQL), "INDEX IF NOT EXISTS", m.Name)
+		assert.Contains(t, m.UpFor(database.MySQL), "CREATE INDEX", m.Name)
+		assert.NotContains(t, m.DownFor(database.MySQL), "DROP INDEX", m.Name)
+
+		// the others keep the indexes already there
+		for _, dialect := range []string{database.SQLite, database.Postgres} {
+			assert.NotRegexp(t, `CREATE INDEX idx`, m.UpFor(dialect), "%s up on %s", m.Name, dialect)
+		}
+	}
+}
+
+func TestAll_UpOnExistingAccountsTable(t *testing.T) {
+	db, err := sqlite.NewInMemory()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	// the table and its indexes, created before the migrations
+	require.NoError(t, sqlite.CreateAccountsTable(db))
+
+	m := migration.New(db)
+	m.RegisterMultiple(migrations.All())
+
+	assert.NoError(t, m.Up(context.Background()))
+}
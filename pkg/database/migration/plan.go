@@ -0,0 +1,256 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+)
+
+// Direction tells whether a step applies or rolls back a migration
+type Direction string
+
+const (
+	DirectionUp   Direction = "up"
+	DirectionDown Direction = "down"
+)
+
+// Step is a migration the migrator would apply or roll back
+type Step struct {
+	Direction Direction
+	Migration Migration
+
+	// Statements are the SQL statements the step runs for the connection dialect
+	Statements []string
+
+	// HasFunc is set when the step also runs a Go function
+	HasFunc bool
+
+	// OutOfOrder is set for pending migrations older than the latest applied one
+	OutOfOrder bool
+}
+
+// WithOutOfOrder allows Up and UpTo to apply pending migrations older than the
+// latest applied one. Without it they fail with database.ErrMigrationOutOfOrder.
+func (m *Migrator) WithOutOfOrder(allow bool) *Migrator {
+	m.allowOutOfOrder = allow
+	return m
+}
+
+// Plan returns the steps Up would run, in order, without executing them
+func (m *Migrator) Plan(ctx context.Context) ([]Step, error) {
+	return m.PlanUpTo(ctx, math.MaxInt64)
+}
+
+// PlanUpTo returns the steps UpTo would run, in order, without executing them
+func (m *Migrator) PlanUpTo(ctx context.Context, version int64) ([]Step, error) {
+	return m.dryRun(ctx, m.upPlanner(version))
+}
+
+// PlanDown returns the step Down would run, without executing it
+func (m *Migrator) PlanDown(ctx context.Context) ([]Step, error) {
+	return m.dryRun(ctx, m.downPlanner(math.MinInt64, 1))
+}
+
+// PlanDownTo returns the steps DownTo would run, in order, without executing them
+func (m *Migrator) PlanDownTo(ctx context.Context, version int64) ([]Step, error) {
+	return m.dryRun(ctx, m.downPlanner(version, -1))
+}
+
+// planner plans the steps of a run against the applied migrations
+type planner func(applied map[int64]appliedMigration) ([]Step, error)
+
+// dryRun plans the steps against the applied migrations without executing them
+func (m *Migrator) dryRun(ctx context.Context, plan planner) ([]Step, error) {
+	applied, err := m.planState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan(applied)
+}
+
+// planState loads the applied migrations for a dry run. It only reads the
+// migrations table, so a plan never changes the schema.
+func (m *Migrator) planState(ctx context.Context) (map[int64]appliedMigration, error) {
+	applied := map[int64]appliedMigration{}
+	var err error
+
+	switch {
+	case !m.canQuery(ctx, `SELECT version FROM schema_migrations WHERE 1 = 0`):
+		// no table yet, nothing applied
+	case !m.canQuery(ctx, `SELECT checksum FROM schema_migrations WHERE 1 = 0`):
+		// created before checksums were stored, nothing to compare
+		applied, err = m.queryApplied(ctx, "''")
+	default:
+		applied, err = m.getAppliedMigrations(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, m.verify(applied)
+}
+
+// canQuery tells if the query runs, to probe for tables and columns
+func (m *Migrator) canQuery(ctx context.Context, query string) bool {
+	rows, err := m.db.Query(ctx, query)
+	if err != nil {
+		return false
+	}
+
+	return rows.Close() == nil
+}
+
+// upPlanner plans the pending migrations up to target
+func (m *Migrator) upPlanner(target int64) planner {
+	return func(applied map[int64]appliedMigration) ([]Step, error) {
+		return m.planUp(applied, target), nil
+	}
+}
+
+// downPlanner plans the rollback of the applied migrations newer than target,
+// limited to limit steps (no limit when negative)
+func (m *Migrator) downPlanner(target int64, limit int) planner {
+	return func(applied map[int64]appliedMigration) ([]Step, error) {
+		return m.planDown(applied, target, limit)
+	}
+}
+
+// redoPlanner plans the rollback of the latest applied migration and its new apply
+func (m *Migrator) redoPlanner() planner {
+	return func(applied map[int64]appliedMigration) ([]Step, error) {
+		steps, err := m.planDown(applied, math.MinInt64, 1)
+		if err != nil || len(steps) == 0 {
+			return steps, err
+		}
+
+		redo := steps[0]
+		redo.Direction = DirectionUp
+		redo.Statements = SplitStatements(redo.Migration.UpFor(m.db.Dialect().Name()))
+		redo.HasFunc = redo.Migration.UpFunc != nil
+
+		return append(steps, redo), nil
+	}
+}
+
+// toPlanner plans the steps that leave version as the latest applied migration:
+// the rollbacks of the newer ones followed by the pending ones up to version
+func (m *Migrator) toPlanner(version int64) planner {
+	return func(applied map[int64]appliedMigration) ([]Step, error) {
+		down, err := m.planDown(applied, version, -1)
+		if err != nil {
+			return nil, err
+		}
+
+		// the pending ones are planned as if the rollbacks were done
+		remaining := maps.Clone(applied)
+		maps.DeleteFunc(remaining, func(v int64, _ appliedMigration) bool {
+			return v > version
+		})
+
+		return append(down, m.planUp(remaining, version)...), nil
+	}
+}
+
+// planUp returns the pending migrations up to target, in ascending order
+func (m *Migrator) planUp(applied map[int64]appliedMigration, target int64) []Step {
+	var latest int64
+	for version := range applied {
+		latest = max(latest, version)
+	}
+
+	dialect := m.db.Dialect().Name()
+
+	var steps []Step
+	for _, migration := range m.migrations {
+		if migration.Version > target {
+			break
+		}
+		if _, exists := applied[migration.Version]; exists {
+			continue // Already applied
+		}
+
+		steps = append(steps, Step{
+			Direction:  DirectionUp,
+			Migration:  migration,
+			Statements: SplitStatements(migration.UpFor(dialect)),
+			HasFunc:    migration.UpFunc != nil,
+			OutOfOrder: migration.Version < latest,
+		})
+	}
+
+	return steps
+}
+
+// planDown returns the applied migrations newer than target, in descending order,
+// limited to limit steps (no limit when negative)
+func (m *Migrator) planDown(applied map[int64]appliedMigration, target int64, limit int) ([]Step, error) {
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		if version > target {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+
+	if limit >= 0 && len(versions) > limit {
+		versions = versions[:limit]
+	}
+
+	dialect := m.db.Dialect().Name()
+
+	steps := make([]Step, 0, len(versions))
+	for _, version := range versions {
+		i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].Version >= version })
+		if i == len(m.migrations) || m.migrations[i].Version != version {
+			return nil, fmt.Errorf(
+				"%w: migration %d not found in registered migrations",
+				database.ErrMigrationFailed,
+				version,
+			)
+		}
+
+		migration := m.migrations[i]
+		if migration.DownFor(dialect) == "" && migration.DownFunc == nil {
+			return nil, fmt.Errorf("%w: migration %d has no down script", database.ErrMigrationFailed, version)
+		}
+
+		steps = append(steps, Step{
+			Direction:  DirectionDown,
+			Migration:  migration,
+			Statements: SplitStatements(migration.DownFor(dialect)),
+			HasFunc:    migration.DownFunc != nil,
+		})
+	}
+
+	return steps, nil
+}
+
+// checkOrder refuses to apply out of order migrations unless they are allowed
+func (m *Migrator) checkOrder(steps []Step) error {
+	if m.allowOutOfOrder {
+		return nil
+	}
+
+	var outOfOrder []string
+	for _, step := range steps {
+		if step.Direction == DirectionUp && step.OutOfOrder {
+			outOfOrder = append(outOfOrder, strconv.FormatInt(step.Migration.Version, 10)+" ("+step.Migration.Name+")")
+		}
+	}
+
+	if len(outOfOrder) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: pending migrations older than the latest applied one: %s",
+		database.ErrMigrationOutOfOrder, strings.Join(outOfOrder, ", "))
+}
@@ -0,0 +1,62 @@
+package migration
+
+import (
+	"strings"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database/internal/sqltext"
+)
+
+// SplitStatements splits a SQL script into statements on semicolons, ignoring the
+// semicolons inside quoted strings, quoted identifiers, comments and PostgreSQL
+// dollar-quoted bodies ($$ ... $$ or $tag$ ... $tag$).
+// Statements are trimmed and the empty ones (only whitespace or comments) dropped.
+func SplitStatements(script string) []string {
+	var statements []string
+
+	start := 0
+	content := false // the current statement has something besides comments
+
+	flush := func(end int) {
+		if content {
+			statements = append(statements, strings.TrimSpace(script[start:end]))
+		}
+		start = end + 1
+		content = false
+	}
+
+	for i := 0; i < len(script); i++ {
+		ch := script[i]
+
+		switch {
+		case ch == ';':
+			flush(i)
+		case ch == '\'' || ch == '"' || ch == '`':
+			i = sqltext.SkipQuoted(script, i, ch) - 1
+			content = true
+		case ch == '-' && i+1 < len(script) && script[i+1] == '-':
+			end := strings.IndexByte(script[i:], '\n')
+			if end == -1 {
+				end = len(script) - i
+			}
+			i += end - 1
+		case ch == '/' && i+1 < len(script) && script[i+1] == '*':
+			end := strings.Index(script[i+2:], "*/")
+			if end == -1 {
+				i = len(script) - 1
+			} else {
+				i += 2 + end + 1
+			}
+		case ch == '$':
+			if end, ok := sqltext.SkipDollarQuoted(script, i); ok {
+				i = end - 1
+			}
+			content = true
+		case ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r':
+			content = true
+		}
+	}
+
+	flush(len(script))
+
+	return statements
+}
@@ -0,0 +1,52 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{"empty", "  \n ", nil},
+		{"single without semicolon", "SELECT 1", []string{"SELECT 1"}},
+		{
+			"several",
+			"CREATE TABLE a (id INT);\n\nCREATE INDEX idx_a ON a(id);\n",
+			[]string{"CREATE TABLE a (id INT)", "CREATE INDEX idx_a ON a(id)"},
+		},
+		{
+			"quoted strings and identifiers",
+			`INSERT INTO t VALUES ('a;b', 'it''s;'); SELECT "x;y", ` + "`z;w`" + ` FROM t`,
+			[]string{`INSERT INTO t VALUES ('a;b', 'it''s;')`, `SELECT "x;y", ` + "`z;w`" + ` FROM t`},
+		},
+		{
+			"comments",
+			"-- first; comment\nSELECT 1; /* block; comment */ SELECT 2;\n-- trailing comment only",
+			[]string{"-- first; comment\nSELECT 1", "/* block; comment */ SELECT 2"},
+		},
+		{
+			"dollar quoted body",
+			"CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  NEW.a := 1;\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\nSELECT 1;",
+			[]string{
+				"CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  NEW.a := 1;\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql",
+				"SELECT 1",
+			},
+		},
+		{
+			"tagged dollar quote",
+			"DO $body$ BEGIN PERFORM 1; END $body$; SELECT $1;",
+			[]string{"DO $body$ BEGIN PERFORM 1; END $body$", "SELECT $1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SplitStatements(tt.script))
+		})
+	}
+}
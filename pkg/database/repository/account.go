@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -24,10 +26,7 @@ func NewSQLAccountRepository(db database.Database) *SQLAccountRepository {
 }
 
 // Get retrieves an account by ID
-func (r *SQLAccountRepository) Get(id string) (*adapter.Account, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLAccountRepository) Get(ctx context.Context, id string) (*adapter.Account, error) {
 	query := `
 		SELECT id, created_at, updated_at, last_login, username, name, email, enabled, role, crypted_password
 		FROM accounts
@@ -37,7 +36,7 @@ func (r *SQLAccountRepository) Get(id string) (*adapter.Account, error) {
 	var acc adapter.Account
 	var lastLogin sql.NullTime
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, r.db.Dialect().Rebind(query), id).Scan(
 		&acc.ID,
 		&acc.CreatedAt,
 		&acc.UpdatedAt,
@@ -64,14 +63,11 @@ func (r *SQLAccountRepository) Get(id string) (*adapter.Account, error) {
 }
 
 // Exists checks if an account with the given ID exists
-func (r *SQLAccountRepository) Exists(id string) bool {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLAccountRepository) Exists(ctx context.Context, id string) bool {
 	query := `SELECT COUNT(*) FROM accounts WHERE id = ?`
 
 	var count int
-	err := r.db.QueryRow(ctx, query, id).Scan(&count)
+	err := r.db.QueryRow(ctx, r.db.Dialect().Rebind(query), id).Scan(&count)
 	if err != nil {
 		return false
 	}
@@ -80,10 +76,7 @@ func (r *SQLAccountRepository) Exists(id string) bool {
 }
 
 // GetByEmail retrieves an account by email
-func (r *SQLAccountRepository) GetByEmail(email string) (*adapter.Account, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLAccountRepository) GetByEmail(ctx context.Context, email string) (*adapter.Account, error) {
 	query := `
 		SELECT id, created_at, updated_at, last_login, username, name, email, enabled, role, crypted_password
 		FROM accounts
@@ -93,7 +86,7 @@ func (r *SQLAccountRepository) GetByEmail(email string) (*adapter.Account, error
 	var acc adapter.Account
 	var lastLogin sql.NullTime
 
-	err := r.db.QueryRow(ctx, query, email).Scan(
+	err := r.db.QueryRow(ctx, r.db.Dialect().Rebind(query), email).Scan(
 		&acc.ID,
 		&acc.CreatedAt,
 		&acc.UpdatedAt,
@@ -120,10 +113,7 @@ func (r *SQLAccountRepository) GetByEmail(email string) (*adapter.Account, error
 }
 
 // GetByUsername retrieves an account by username
-func (r *SQLAccountRepository) GetByUsername(username string) (*adapter.Account, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLAccountRepository) GetByUsername(ctx context.Context, username string) (*adapter.Account, error) {
 	query := `
 		SELECT id, created_at, updated_at, last_login, username, name, email, enabled, role, crypted_password
 		FROM accounts
@@ -133,7 +123,7 @@ func (r *SQLAccountRepository) GetByUsername(username string) (*adapter.Account,
 	var acc adapter.Account
 	var lastLogin sql.NullTime
 
-	err := r.db.QueryRow(ctx, query, username).Scan(
+	err := r.db.QueryRow(ctx, r.db.Dialect().Rebind(query), username).Scan(
 		&acc.ID,
 		&acc.CreatedAt,
 		&acc.UpdatedAt,
@@ -160,7 +150,7 @@ func (r *SQLAccountRepository) GetByUsername(username string) (*adapter.Account,
 }
 
 // Create creates a new account
-func (r *SQLAccountRepository) Create(a *adapter.Account) error {
+func (r *SQLAccountRepository) Create(ctx context.Context, a *adapter.Account) error {
 	if err := a.Validate(); err != nil {
 		return err
 	}
@@ -173,9 +163,6 @@ func (r *SQLAccountRepository) Create(a *adapter.Account) error {
 		return adapter.ErrPasswordNotSet
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
 	// Generate new UUID
 	a.ID = uuid.NewString()
 	now := time.Now()
@@ -192,7 +179,7 @@ func (r *SQLAccountRepository) Create(a *adapter.Account) error {
 		lastLogin = a.LastLogin
 	}
 
-	_, err := r.db.Exec(ctx, query,
+	_, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query),
 		a.ID,
 		a.CreatedAt,
 		a.UpdatedAt,
@@ -216,7 +203,7 @@ func (r *SQLAccountRepository) Create(a *adapter.Account) error {
 }
 
 // Update updates an existing account
-func (r *SQLAccountRepository) Update(a *adapter.Account) error {
+func (r *SQLAccountRepository) Update(ctx context.Context, a *adapter.Account) error {
 	if err := a.Validate(); err != nil {
 		return err
 	}
@@ -225,9 +212,6 @@ func (r *SQLAccountRepository) Update(a *adapter.Account) error {
 		return adapter.ErrPasswordNotSet
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
 	a.UpdatedAt = time.Now()
 
 	query := `
@@ -241,7 +225,7 @@ func (r *SQLAccountRepository) Update(a *adapter.Account) error {
 		lastLogin = a.LastLogin
 	}
 
-	result, err := r.db.Exec(ctx, query,
+	result, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query),
 		a.UpdatedAt,
 		lastLogin,
 		a.Username,
@@ -272,13 +256,10 @@ func (r *SQLAccountRepository) Update(a *adapter.Account) error {
 }
 
 // Delete deletes an account by ID
-func (r *SQLAccountRepository) Delete(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLAccountRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM accounts WHERE id = ?`
 
-	result, err := r.db.Exec(ctx, query, id)
+	result, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query), id)
 	if err != nil {
 		return err
 	}
@@ -295,6 +276,197 @@ func (r *SQLAccountRepository) Delete(id string) error {
 	return nil
 }
 
+// List returns a page of accounts matching the query filters, in keyset order.
+// Offset pagination is used unless the query carries a cursor from a previous page.
+func (r *SQLAccountRepository) List(ctx context.Context, q adapter.AccountQuery) (*adapter.AccountPage, error) {
+	keyset, after, err := q.Keyset()
+	if err != nil {
+		return nil, err
+	}
+
+	d := r.db.Dialect()
+	where, args := accountFilters(d, q)
+
+	page := &adapter.AccountPage{
+		PageInfo: adapter.PageInfo{Page: q.PageNumber(), Limit: q.PageSize()},
+		Accounts: make([]adapter.Account, 0, q.PageSize()),
+	}
+
+	countQuery := `SELECT COUNT(*) FROM accounts` + whereClause(where)
+	if err := r.db.QueryRow(ctx, d.Rebind(countQuery), args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	if after != nil {
+		cond, condArgs := keysetCondition(d, keyset, after)
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+
+	order := make([]string, len(keyset))
+	for i, f := range keyset {
+		col, _ := sortExpr(d, f.Field)
+		order[i] = col + " ASC"
+		if f.Desc {
+			order[i] = col + " DESC"
+		}
+	}
+
+	// fetch one extra row to know if there is a next page
+	query := `
+		SELECT id, created_at, updated_at, last_login, username, name, email, enabled, role, crypted_password
+		FROM accounts` + whereClause(where) + `
+		ORDER BY ` + strings.Join(order, ", ") + `
+		LIMIT ? OFFSET ?
+	`
+	args = append(args, q.PageSize()+1, q.Offset())
+
+	rows, err := r.db.Query(ctx, d.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hasMore := false
+	for rows.Next() {
+		if len(page.Accounts) == q.PageSize() {
+			hasMore = true
+			break
+		}
+
+		var acc adapter.Account
+		var lastLogin sql.NullTime
+
+		err := rows.Scan(
+			&acc.ID,
+			&acc.CreatedAt,
+			&acc.UpdatedAt,
+			&lastLogin,
+			&acc.Username,
+			&acc.Name,
+			&acc.Email,
+			&acc.Enabled,
+			&acc.Role,
+			&acc.CryptedPassword,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if lastLogin.Valid {
+			acc.LastLogin = lastLogin.Time
+		}
+
+		page.Accounts = append(page.Accounts, acc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if hasMore {
+		page.NextCursor = page.Accounts[len(page.Accounts)-1].Cursor(keyset)
+	}
+
+	return page, nil
+}
+
+// accountFilters returns the WHERE conditions and arguments for the query filters
+func accountFilters(d database.Dialect, q adapter.AccountQuery) ([]string, []interface{}) {
+	var where []string
+	var args []interface{}
+
+	if q.Role != "" {
+		where = append(where, "role = ?")
+		args = append(args, q.Role)
+	}
+
+	if q.Enabled != nil {
+		where = append(where, "enabled = ?")
+		args = append(args, *q.Enabled)
+	}
+
+	createdAt, param := sortExpr(d, "created_at")
+
+	if !q.CreatedFrom.IsZero() {
+		where = append(where, createdAt+" >= "+param)
+		args = append(args, q.CreatedFrom)
+	}
+
+	if !q.CreatedTo.IsZero() {
+		where = append(where, createdAt+" < "+param)
+		args = append(args, q.CreatedTo)
+	}
+
+	if q.Search != "" {
+		// ! is used as LIKE escape character since backslash behaves differently on MySQL
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(q.Search)) + "%"
+		cols := []string{"username", "name", "email"}
+		conds := make([]string, len(cols))
+		for i, col := range cols {
+			conds[i] = "LOWER(" + d.Quote(col) + ") LIKE ? ESCAPE '!'"
+			args = append(args, pattern)
+		}
+		where = append(where, "("+strings.Join(conds, " OR ")+")")
+	}
+
+	return where, args
+}
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// keysetCondition builds the condition selecting the rows after the given keyset values:
+// (a > ?) OR (a = ? AND b > ?) OR ..., using < for descending fields
+func keysetCondition(d database.Dialect, keyset []adapter.SortField, after []any) (string, []interface{}) {
+	var args []interface{}
+	ors := make([]string, len(keyset))
+
+	for i, f := range keyset {
+		ands := make([]string, 0, i+1)
+		for j := range i {
+			col, param := sortExpr(d, keyset[j].Field)
+			ands = append(ands, col+" = "+param)
+			args = append(args, after[j])
+		}
+
+		col, param := sortExpr(d, f.Field)
+		op := " > "
+		if f.Desc {
+			op = " < "
+		}
+		ands = append(ands, col+op+param)
+		args = append(args, after[i])
+
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// sortExpr returns the expression a field is ordered and compared by, and the one
+// for the values it is compared to. SQLite stores the timestamps as text, which
+// only compares right in a single format and time zone, so they are compared as
+// julian day numbers there, precise to the millisecond; the id breaks the ties.
+func sortExpr(d database.Dialect, field string) (string, string) {
+	col := d.Quote(field)
+	if d.Name() == database.SQLite && slices.Contains(timestampFields, field) {
+		return "julianday(" + col + ")", "julianday(?)"
+	}
+
+	return col, "?"
+}
+
+// timestampFields are the account columns holding timestamps
+var timestampFields = []string{"created_at", "updated_at", "last_login"}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
 // isDuplicateKeyError checks if the error is a duplicate key constraint violation
 func isDuplicateKeyError(err error) bool {
 	if err == nil {
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -40,7 +41,7 @@ func TestSQLAccountRepository_Create(t *testing.T) {
 	repo := setupTestDB(t)
 	acc := createTestAccount(t)
 
-	err := repo.Create(acc)
+	err := repo.Create(context.Background(), acc)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, acc.ID, "ID should be generated")
 	assert.False(t, acc.CreatedAt.IsZero(), "CreatedAt should be set")
@@ -52,7 +53,7 @@ func TestSQLAccountRepository_CreateWithID(t *testing.T) {
 	acc := createTestAccount(t)
 	acc.ID = "some-id"
 
-	err := repo.Create(acc)
+	err := repo.Create(context.Background(), acc)
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrCannotCreateWithID, err)
 }
@@ -67,7 +68,7 @@ func TestSQLAccountRepository_CreateWithoutPassword(t *testing.T) {
 		Role:     "user",
 	}
 
-	err := repo.Create(acc)
+	err := repo.Create(context.Background(), acc)
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrPasswordNotSet, err)
 }
@@ -76,11 +77,11 @@ func TestSQLAccountRepository_Get(t *testing.T) {
 	repo := setupTestDB(t)
 	acc := createTestAccount(t)
 
-	err := repo.Create(acc)
+	err := repo.Create(context.Background(), acc)
 	require.NoError(t, err)
 
 	// Get by ID
-	retrieved, err := repo.Get(acc.ID)
+	retrieved, err := repo.Get(context.Background(), acc.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, acc.ID, retrieved.ID)
 	assert.Equal(t, acc.Username, retrieved.Username)
@@ -91,7 +92,7 @@ func TestSQLAccountRepository_Get(t *testing.T) {
 func TestSQLAccountRepository_GetNotFound(t *testing.T) {
 	repo := setupTestDB(t)
 
-	retrieved, err := repo.Get("non-existent-id")
+	retrieved, err := repo.Get(context.Background(), "non-existent-id")
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrAccountNotFound, err)
 	assert.Nil(t, retrieved)
@@ -101,15 +102,15 @@ func TestSQLAccountRepository_Exists(t *testing.T) {
 	repo := setupTestDB(t)
 	acc := createTestAccount(t)
 
-	err := repo.Create(acc)
+	err := repo.Create(context.Background(), acc)
 	require.NoError(t, err)
 
 	// Should exist
-	exists := repo.Exists(acc.ID)
+	exists := repo.Exists(context.Background(), acc.ID)
 	assert.True(t, exists)
 
 	// Should not exist
-	exists = repo.Exists("non-existent-id")
+	exists = repo.Exists(context.Background(), "non-existent-id")
 	assert.False(t, exists)
 }
 
@@ -117,11 +118,11 @@ func TestSQLAccountRepository_GetByEmail(t *testing.T) {
 	repo := setupTestDB(t)
 	acc := createTestAccount(t)
 
-	err := repo.Create(acc)
+	err := repo.Create(context.Background(), acc)
 	require.NoError(t, err)
 
 	// Get by email
-	retrieved, err := repo.GetByEmail(acc.Email)
+	retrieved, err := repo.GetByEmail(context.Background(), acc.Email)
 	assert.NoError(t, err)
 	assert.Equal(t, acc.ID, retrieved.ID)
 	assert.Equal(t, acc.Email, retrieved.Email)
@@ -130,7 +131,7 @@ func TestSQLAccountRepository_GetByEmail(t *testing.T) {
 func TestSQLAccountRepository_GetByEmailNotFound(t *testing.T) {
 	repo := setupTestDB(t)
 
-	retrieved, err := repo.GetByEmail("nonexistent@example.com")
+	retrieved, err := repo.GetByEmail(context.Background(), "nonexistent@example.com")
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrAccountNotFound, err)
 	assert.Nil(t, retrieved)
@@ -140,11 +141,11 @@ func TestSQLAccountRepository_GetByUsername(t *testing.T) {
 	repo := setupTestDB(t)
 	acc := createTestAccount(t)
 
-	err := repo.Create(acc)
+	err := repo.Create(context.Background(), acc)
 	require.NoError(t, err)
 
 	// Get by username
-	retrieved, err := repo.GetByUsername(acc.Username)
+	retrieved, err := repo.GetByUsername(context.Background(), acc.Username)
 	assert.NoError(t, err)
 	assert.Equal(t, acc.ID, retrieved.ID)
 	assert.Equal(t, acc.Username, retrieved.Username)
@@ -153,7 +154,7 @@ func TestSQLAccountRepository_GetByUsername(t *testing.T) {
 func TestSQLAccountRepository_GetByUsernameNotFound(t *testing.T) {
 	repo := setupTestDB(t)
 
-	retrieved, err := repo.GetByUsername("nonexistentuser")
+	retrieved, err := repo.GetByUsername(context.Background(), "nonexistentuser")
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrAccountNotFound, err)
 	assert.Nil(t, retrieved)
@@ -163,7 +164,7 @@ func TestSQLAccountRepository_Update(t *testing.T) {
 	repo := setupTestDB(t)
 	acc := createTestAccount(t)
 
-	err := repo.Create(acc)
+	err := repo.Create(context.Background(), acc)
 	require.NoError(t, err)
 
 	// Wait a bit to ensure UpdatedAt changes
@@ -173,11 +174,11 @@ func TestSQLAccountRepository_Update(t *testing.T) {
 	acc.Name = "Updated Name"
 	acc.Email = "updated@example.com"
 
-	err = repo.Update(acc)
+	err = repo.Update(context.Background(), acc)
 	assert.NoError(t, err)
 
 	// Verify update
-	retrieved, err := repo.Get(acc.ID)
+	retrieved, err := repo.Get(context.Background(), acc.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, "Updated Name", retrieved.Name)
 	assert.Equal(t, "updated@example.com", retrieved.Email)
@@ -189,7 +190,7 @@ func TestSQLAccountRepository_UpdateNotFound(t *testing.T) {
 	acc := createTestAccount(t)
 	acc.ID = "non-existent-id"
 
-	err := repo.Update(acc)
+	err := repo.Update(context.Background(), acc)
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrAccountNotFound, err)
 }
@@ -198,22 +199,22 @@ func TestSQLAccountRepository_Delete(t *testing.T) {
 	repo := setupTestDB(t)
 	acc := createTestAccount(t)
 
-	err := repo.Create(acc)
+	err := repo.Create(context.Background(), acc)
 	require.NoError(t, err)
 
 	// Delete account
-	err = repo.Delete(acc.ID)
+	err = repo.Delete(context.Background(), acc.ID)
 	assert.NoError(t, err)
 
 	// Verify deletion
-	exists := repo.Exists(acc.ID)
+	exists := repo.Exists(context.Background(), acc.ID)
 	assert.False(t, exists)
 }
 
 func TestSQLAccountRepository_DeleteNotFound(t *testing.T) {
 	repo := setupTestDB(t)
 
-	err := repo.Delete("non-existent-id")
+	err := repo.Delete(context.Background(), "non-existent-id")
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrAccountNotFound, err)
 }
@@ -223,7 +224,7 @@ func TestSQLAccountRepository_DuplicateEmail(t *testing.T) {
 
 	// Create first account
 	acc1 := createTestAccount(t)
-	err := repo.Create(acc1)
+	err := repo.Create(context.Background(), acc1)
 	require.NoError(t, err)
 
 	// Try to create second account with same email
@@ -237,7 +238,7 @@ func TestSQLAccountRepository_DuplicateEmail(t *testing.T) {
 	err = acc2.SetPassword("password123")
 	require.NoError(t, err)
 
-	err = repo.Create(acc2)
+	err = repo.Create(context.Background(), acc2)
 	assert.Error(t, err)
 }
 
@@ -246,7 +247,7 @@ func TestSQLAccountRepository_DuplicateUsername(t *testing.T) {
 
 	// Create first account
 	acc1 := createTestAccount(t)
-	err := repo.Create(acc1)
+	err := repo.Create(context.Background(), acc1)
 	require.NoError(t, err)
 
 	// Try to create second account with same username
@@ -260,7 +261,7 @@ func TestSQLAccountRepository_DuplicateUsername(t *testing.T) {
 	err = acc2.SetPassword("password123")
 	require.NoError(t, err)
 
-	err = repo.Create(acc2)
+	err = repo.Create(context.Background(), acc2)
 	assert.Error(t, err)
 }
 
@@ -269,22 +270,22 @@ func TestSQLAccountRepository_LastLogin(t *testing.T) {
 	acc := createTestAccount(t)
 
 	// Create without last login
-	err := repo.Create(acc)
+	err := repo.Create(context.Background(), acc)
 	require.NoError(t, err)
 
 	// Verify last login is zero
-	retrieved, err := repo.Get(acc.ID)
+	retrieved, err := repo.Get(context.Background(), acc.ID)
 	assert.NoError(t, err)
 	assert.True(t, retrieved.LastLogin.IsZero())
 
 	// Update with last login
 	now := time.Now()
 	acc.LastLogin = now
-	err = repo.Update(acc)
+	err = repo.Update(context.Background(), acc)
 	assert.NoError(t, err)
 
 	// Verify last login is set
-	retrieved, err = repo.Get(acc.ID)
+	retrieved, err = repo.Get(context.Background(), acc.ID)
 	assert.NoError(t, err)
 	assert.False(t, retrieved.LastLogin.IsZero())
 	assert.WithinDuration(t, now, retrieved.LastLogin, time.Second)
@@ -298,11 +299,11 @@ func TestSQLAccountRepository_PasswordValidation(t *testing.T) {
 	err := acc.SetPassword(password)
 	require.NoError(t, err)
 
-	err = repo.Create(acc)
+	err = repo.Create(context.Background(), acc)
 	require.NoError(t, err)
 
 	// Retrieve and validate password
-	retrieved, err := repo.Get(acc.ID)
+	retrieved, err := repo.Get(context.Background(), acc.ID)
 	assert.NoError(t, err)
 
 	// Correct password
@@ -313,3 +314,191 @@ func TestSQLAccountRepository_PasswordValidation(t *testing.T) {
 	err = retrieved.ValidatePassword("wrongpassword")
 	assert.Error(t, err)
 }
+
+func TestSQLAccountRepository_CanceledContext(t *testing.T) {
+	repo := setupTestDB(t)
+	acc := createTestAccount(t)
+
+	err := repo.Create(context.Background(), acc)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A canceled request must not reach the database
+	retrieved, err := repo.Get(ctx, acc.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, retrieved)
+
+	err = repo.Delete(ctx, acc.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, repo.Exists(context.Background(), acc.ID))
+}
+
+func seedListAccounts(t *testing.T, repo *SQLAccountRepository) []*adapter.Account {
+	seed := []struct{ username, name, role string }{
+		{"alice", "Alice Smith", "admin"},
+		{"bob_b", "Bob Brown", "user"},
+		{"carol", "Carol Smith", "user"},
+		{"dave", "Dave Jones", "user"},
+		{"erin", "Erin Adams", "admin"},
+	}
+
+	accounts := make([]*adapter.Account, 0, len(seed))
+	for i, s := range seed {
+		acc := &adapter.Account{
+			Username: s.username,
+			Name:     s.name,
+			Email:    s.username + "@example.com",
+			Enabled:  i != 3,
+			Role:     s.role,
+		}
+		require.NoError(t, acc.SetPassword("password123"))
+		require.NoError(t, repo.Create(context.Background(), acc))
+		accounts = append(accounts, acc)
+	}
+
+	return accounts
+}
+
+func usernames(accounts []adapter.Account) []string {
+	names := make([]string, len(accounts))
+	for i, a := range accounts {
+		names[i] = a.Username
+	}
+
+	return names
+}
+
+func TestSQLAccountRepository_ListOffset(t *testing.T) {
+	repo := setupTestDB(t)
+	seedListAccounts(t, repo)
+
+	q := adapter.AccountQuery{ListQuery: adapter.ListQuery{Limit: 2, Sort: adapter.ParseSort("username")}}
+
+	var listed []string
+	for page := 1; page <= 3; page++ {
+		q.Page = page
+		result, err := repo.List(context.Background(), q)
+		require.NoError(t, err)
+		assert.Equal(t, 5, result.Total)
+		assert.Equal(t, page, result.Page)
+		assert.Equal(t, page < 3, result.NextCursor != "")
+		listed = append(listed, usernames(result.Accounts)...)
+	}
+
+	assert.Equal(t, []string{"alice", "bob_b", "carol", "dave", "erin"}, listed)
+}
+
+func TestSQLAccountRepository_ListCursor(t *testing.T) {
+	repo := setupTestDB(t)
+	seedListAccounts(t, repo)
+
+	q := adapter.AccountQuery{ListQuery: adapter.ListQuery{Limit: 2, Sort: adapter.ParseSort("role,-username")}}
+
+	var listed []string
+	for {
+		result, err := repo.List(context.Background(), q)
+		require.NoError(t, err)
+		assert.Equal(t, 5, result.Total)
+		listed = append(listed, usernames(result.Accounts)...)
+
+		if result.NextCursor == "" {
+			break
+		}
+		q.Cursor = result.NextCursor
+	}
+
+	assert.Equal(t, []string{"erin", "alice", "dave", "carol", "bob_b"}, listed)
+}
+
+func TestSQLAccountRepository_ListCursorTimestamps(t *testing.T) {
+	repo := setupTestDB(t)
+	accounts := seedListAccounts(t, repo)
+
+	// equal and sub-second apart instants, written in different time zones
+	base := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	created := map[string]time.Time{
+		"alice": base.Add(500 * time.Millisecond),
+		"bob_b": base.Add(500 * time.Millisecond).In(time.FixedZone("CEST", 2*3600)),
+		"carol": base.Add(250 * time.Millisecond).In(time.FixedZone("EST", -5*3600)),
+		"dave":  base.Add(time.Second).In(time.FixedZone("CET", 3600)),
+		"erin":  base.Add(750 * time.Millisecond),
+	}
+	for _, acc := range accounts {
+		_, err := repo.db.Exec(context.Background(),
+			`UPDATE accounts SET created_at = ? WHERE id = ?`, created[acc.Username], acc.ID)
+		require.NoError(t, err)
+	}
+
+	// the equal instants come in id order
+	tied := []string{"alice", "bob_b"}
+	if accounts[1].ID < accounts[0].ID {
+		tied = []string{"bob_b", "alice"}
+	}
+
+	list := func(sort string) []string {
+		q := adapter.AccountQuery{ListQuery: adapter.ListQuery{Limit: 2, Sort: adapter.ParseSort(sort)}}
+
+		var listed []string
+		for {
+			result, err := repo.List(context.Background(), q)
+			require.NoError(t, err)
+			listed = append(listed, usernames(result.Accounts)...)
+
+			if result.NextCursor == "" {
+				return listed
+			}
+			q.Cursor = result.NextCursor
+		}
+	}
+
+	assert.Equal(t, []string{"carol", tied[0], tied[1], "erin", "dave"}, list("created_at"))
+	assert.Equal(t, []string{"dave", "erin", tied[0], tied[1], "carol"}, list("-created_at,id"))
+
+	result, err := repo.List(context.Background(), adapter.AccountQuery{
+		CreatedFrom: base.Add(500 * time.Millisecond).In(time.FixedZone("PST", -8*3600)),
+		CreatedTo:   base.Add(time.Second),
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alice", "bob_b", "erin"}, usernames(result.Accounts))
+}
+
+func TestSQLAccountRepository_ListFilters(t *testing.T) {
+	repo := setupTestDB(t)
+	accounts := seedListAccounts(t, repo)
+
+	list := func(q adapter.AccountQuery) []string {
+		q.Sort = adapter.ParseSort("username")
+		result, err := repo.List(context.Background(), q)
+		require.NoError(t, err)
+		assert.Equal(t, len(result.Accounts), result.Total)
+
+		return usernames(result.Accounts)
+	}
+
+	disabled := false
+	assert.Equal(t, []string{"alice", "erin"}, list(adapter.AccountQuery{Role: "admin"}))
+	assert.Equal(t, []string{"dave"}, list(adapter.AccountQuery{Enabled: &disabled}))
+	assert.Equal(t, []string{"alice", "carol"}, list(adapter.AccountQuery{Search: "SMITH"}))
+	// LIKE wildcards in the search are matched literally
+	assert.Equal(t, []string{"bob_b"}, list(adapter.AccountQuery{Search: "_b"}))
+	assert.Equal(t, []string{"carol", "dave"}, list(adapter.AccountQuery{
+		CreatedFrom: accounts[2].CreatedAt,
+		CreatedTo:   accounts[4].CreatedAt,
+	}))
+}
+
+func TestSQLAccountRepository_ListInvalid(t *testing.T) {
+	repo := setupTestDB(t)
+
+	_, err := repo.List(context.Background(), adapter.AccountQuery{
+		ListQuery: adapter.ListQuery{Sort: adapter.ParseSort("crypted_password")},
+	})
+	assert.ErrorIs(t, err, adapter.ErrInvalidSort)
+
+	_, err = repo.List(context.Background(), adapter.AccountQuery{
+		ListQuery: adapter.ListQuery{Cursor: "not-a-cursor"},
+	})
+	assert.ErrorIs(t, err, adapter.ErrInvalidCursor)
+}
@@ -24,10 +24,7 @@ func NewSQLPasswordResetTokenRepository(db database.Database) *SQLPasswordResetT
 }
 
 // Create stores a new password reset token
-func (r *SQLPasswordResetTokenRepository) Create(token *adapter.PasswordResetToken) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLPasswordResetTokenRepository) Create(ctx context.Context, token *adapter.PasswordResetToken) error {
 	// Generate new UUID if not set
 	if token.ID == "" {
 		token.ID = uuid.NewString()
@@ -48,7 +45,7 @@ func (r *SQLPasswordResetTokenRepository) Create(token *adapter.PasswordResetTok
 		usedAt = *token.UsedAt
 	}
 
-	_, err := r.db.Exec(ctx, query,
+	_, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query),
 		token.ID,
 		token.UserID,
 		token.TokenHash,
@@ -61,10 +58,10 @@ func (r *SQLPasswordResetTokenRepository) Create(token *adapter.PasswordResetTok
 }
 
 // GetByTokenHash retrieves a password reset token by its hash
-func (r *SQLPasswordResetTokenRepository) GetByTokenHash(tokenHash string) (*adapter.PasswordResetToken, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLPasswordResetTokenRepository) GetByTokenHash(
+	ctx context.Context,
+	tokenHash string,
+) (*adapter.PasswordResetToken, error) {
 	query := `
 		SELECT id, user_id, token_hash, expires_at, created_at, used_at
 		FROM password_reset_tokens
@@ -74,7 +71,7 @@ func (r *SQLPasswordResetTokenRepository) GetByTokenHash(tokenHash string) (*ada
 	var token adapter.PasswordResetToken
 	var usedAt sql.NullTime
 
-	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+	err := r.db.QueryRow(ctx, r.db.Dialect().Rebind(query), tokenHash).Scan(
 		&token.ID,
 		&token.UserID,
 		&token.TokenHash,
@@ -97,17 +94,14 @@ func (r *SQLPasswordResetTokenRepository) GetByTokenHash(tokenHash string) (*ada
 }
 
 // MarkAsUsed marks a password reset token as used
-func (r *SQLPasswordResetTokenRepository) MarkAsUsed(tokenHash string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLPasswordResetTokenRepository) MarkAsUsed(ctx context.Context, tokenHash string) error {
 	query := `
 		UPDATE password_reset_tokens
 		SET used_at = ?
 		WHERE token_hash = ? AND used_at IS NULL
 	`
 
-	result, err := r.db.Exec(ctx, query, time.Now(), tokenHash)
+	result, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query), time.Now(), tokenHash)
 	if err != nil {
 		return err
 	}
@@ -125,29 +119,23 @@ func (r *SQLPasswordResetTokenRepository) MarkAsUsed(tokenHash string) error {
 }
 
 // DeleteExpired removes all expired tokens
-func (r *SQLPasswordResetTokenRepository) DeleteExpired() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLPasswordResetTokenRepository) DeleteExpired(ctx context.Context) error {
 	query := `
 		DELETE FROM password_reset_tokens
 		WHERE expires_at < ?
 	`
 
-	_, err := r.db.Exec(ctx, query, time.Now())
+	_, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query), time.Now())
 	return err
 }
 
 // DeleteByUserID removes all password reset tokens for a user
-func (r *SQLPasswordResetTokenRepository) DeleteByUserID(userID string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLPasswordResetTokenRepository) DeleteByUserID(ctx context.Context, userID string) error {
 	query := `
 		DELETE FROM password_reset_tokens
 		WHERE user_id = ?
 	`
 
-	_, err := r.db.Exec(ctx, query, userID)
+	_, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query), userID)
 	return err
 }
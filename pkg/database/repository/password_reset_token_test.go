@@ -36,7 +36,7 @@ func TestPasswordResetTokenRepository_Create(t *testing.T) {
 
 	token := adapter.NewPasswordResetToken(user.ID, tokenHash, 1*time.Hour)
 
-	err = tokenRepo.Create(token)
+	err = tokenRepo.Create(context.Background(), token)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token.ID, "ID should be generated")
 	assert.False(t, token.CreatedAt.IsZero(), "CreatedAt should be set")
@@ -50,11 +50,11 @@ func TestPasswordResetTokenRepository_GetByTokenHash(t *testing.T) {
 	require.NoError(t, err)
 
 	token := adapter.NewPasswordResetToken(user.ID, tokenHash, 1*time.Hour)
-	err = tokenRepo.Create(token)
+	err = tokenRepo.Create(context.Background(), token)
 	require.NoError(t, err)
 
 	// Get by token hash
-	retrieved, err := tokenRepo.GetByTokenHash(tokenHash)
+	retrieved, err := tokenRepo.GetByTokenHash(context.Background(), tokenHash)
 	assert.NoError(t, err)
 	assert.Equal(t, token.ID, retrieved.ID)
 	assert.Equal(t, token.UserID, retrieved.UserID)
@@ -65,7 +65,7 @@ func TestPasswordResetTokenRepository_GetByTokenHash(t *testing.T) {
 func TestPasswordResetTokenRepository_GetByTokenHash_NotFound(t *testing.T) {
 	tokenRepo, _ := setupPasswordResetTokenTestDB(t)
 
-	retrieved, err := tokenRepo.GetByTokenHash("nonexistent")
+	retrieved, err := tokenRepo.GetByTokenHash(context.Background(), "nonexistent")
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrTokenNotFound, err)
 	assert.Nil(t, retrieved)
@@ -79,15 +79,15 @@ func TestPasswordResetTokenRepository_MarkAsUsed(t *testing.T) {
 	require.NoError(t, err)
 
 	token := adapter.NewPasswordResetToken(user.ID, tokenHash, 1*time.Hour)
-	err = tokenRepo.Create(token)
+	err = tokenRepo.Create(context.Background(), token)
 	require.NoError(t, err)
 
 	// Mark token as used
-	err = tokenRepo.MarkAsUsed(tokenHash)
+	err = tokenRepo.MarkAsUsed(context.Background(), tokenHash)
 	assert.NoError(t, err)
 
 	// Verify token is marked as used
-	retrieved, err := tokenRepo.GetByTokenHash(tokenHash)
+	retrieved, err := tokenRepo.GetByTokenHash(context.Background(), tokenHash)
 	assert.NoError(t, err)
 	assert.True(t, retrieved.IsUsed())
 	assert.False(t, retrieved.IsValid())
@@ -102,7 +102,7 @@ func TestPasswordResetTokenRepository_DeleteExpired(t *testing.T) {
 	require.NoError(t, err)
 
 	expiredToken := adapter.NewPasswordResetToken(user.ID, expiredHash, -1*time.Hour)
-	err = tokenRepo.Create(expiredToken)
+	err = tokenRepo.Create(context.Background(), expiredToken)
 	require.NoError(t, err)
 
 	// Create a valid token
@@ -110,20 +110,20 @@ func TestPasswordResetTokenRepository_DeleteExpired(t *testing.T) {
 	require.NoError(t, err)
 
 	validToken := adapter.NewPasswordResetToken(user.ID, validHash, 1*time.Hour)
-	err = tokenRepo.Create(validToken)
+	err = tokenRepo.Create(context.Background(), validToken)
 	require.NoError(t, err)
 
 	// Delete expired tokens
-	err = tokenRepo.DeleteExpired()
+	err = tokenRepo.DeleteExpired(context.Background())
 	assert.NoError(t, err)
 
 	// Verify expired token is deleted
-	_, err = tokenRepo.GetByTokenHash(expiredHash)
+	_, err = tokenRepo.GetByTokenHash(context.Background(), expiredHash)
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrTokenNotFound, err)
 
 	// Verify valid token still exists
-	retrieved, err := tokenRepo.GetByTokenHash(validHash)
+	retrieved, err := tokenRepo.GetByTokenHash(context.Background(), validHash)
 	assert.NoError(t, err)
 	assert.NotNil(t, retrieved)
 }
@@ -140,17 +140,17 @@ func TestPasswordResetTokenRepository_DeleteByUserID(t *testing.T) {
 		tokenHashes = append(tokenHashes, tokenHash)
 
 		token := adapter.NewPasswordResetToken(user.ID, tokenHash, 1*time.Hour)
-		err = tokenRepo.Create(token)
+		err = tokenRepo.Create(context.Background(), token)
 		require.NoError(t, err)
 	}
 
 	// Delete all tokens for user
-	err := tokenRepo.DeleteByUserID(user.ID)
+	err := tokenRepo.DeleteByUserID(context.Background(), user.ID)
 	assert.NoError(t, err)
 
 	// Verify all tokens are deleted
 	for _, tokenHash := range tokenHashes {
-		_, err := tokenRepo.GetByTokenHash(tokenHash)
+		_, err := tokenRepo.GetByTokenHash(context.Background(), tokenHash)
 		assert.Error(t, err)
 		assert.Equal(t, adapter.ErrTokenNotFound, err)
 	}
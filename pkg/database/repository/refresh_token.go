@@ -24,10 +24,7 @@ func NewSQLRefreshTokenRepository(db database.Database) *SQLRefreshTokenReposito
 }
 
 // Create stores a new refresh token
-func (r *SQLRefreshTokenRepository) Create(token *adapter.RefreshToken) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLRefreshTokenRepository) Create(ctx context.Context, token *adapter.RefreshToken) error {
 	// Generate new UUID if not set
 	if token.ID == "" {
 		token.ID = uuid.NewString()
@@ -48,7 +45,7 @@ func (r *SQLRefreshTokenRepository) Create(token *adapter.RefreshToken) error {
 		revokedAt = *token.RevokedAt
 	}
 
-	_, err := r.db.Exec(ctx, query,
+	_, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query),
 		token.ID,
 		token.UserID,
 		token.TokenHash,
@@ -61,10 +58,10 @@ func (r *SQLRefreshTokenRepository) Create(token *adapter.RefreshToken) error {
 }
 
 // GetByTokenHash retrieves a refresh token by its hash
-func (r *SQLRefreshTokenRepository) GetByTokenHash(tokenHash string) (*adapter.RefreshToken, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLRefreshTokenRepository) GetByTokenHash(
+	ctx context.Context,
+	tokenHash string,
+) (*adapter.RefreshToken, error) {
 	query := `
 		SELECT id, user_id, token_hash, expires_at, created_at, revoked_at
 		FROM refresh_tokens
@@ -74,7 +71,7 @@ func (r *SQLRefreshTokenRepository) GetByTokenHash(tokenHash string) (*adapter.R
 	var token adapter.RefreshToken
 	var revokedAt sql.NullTime
 
-	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+	err := r.db.QueryRow(ctx, r.db.Dialect().Rebind(query), tokenHash).Scan(
 		&token.ID,
 		&token.UserID,
 		&token.TokenHash,
@@ -97,10 +94,7 @@ func (r *SQLRefreshTokenRepository) GetByTokenHash(tokenHash string) (*adapter.R
 }
 
 // GetByUserID retrieves all refresh tokens for a user
-func (r *SQLRefreshTokenRepository) GetByUserID(userID string) ([]*adapter.RefreshToken, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLRefreshTokenRepository) GetByUserID(ctx context.Context, userID string) ([]*adapter.RefreshToken, error) {
 	query := `
 		SELECT id, user_id, token_hash, expires_at, created_at, revoked_at
 		FROM refresh_tokens
@@ -108,7 +102,7 @@ func (r *SQLRefreshTokenRepository) GetByUserID(userID string) ([]*adapter.Refre
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(ctx, query, userID)
+	rows, err := r.db.Query(ctx, r.db.Dialect().Rebind(query), userID)
 	if err != nil {
 		return nil, err
 	}
@@ -142,17 +136,14 @@ func (r *SQLRefreshTokenRepository) GetByUserID(userID string) ([]*adapter.Refre
 }
 
 // Revoke marks a refresh token as revoked
-func (r *SQLRefreshTokenRepository) Revoke(tokenHash string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLRefreshTokenRepository) Revoke(ctx context.Context, tokenHash string) error {
 	query := `
 		UPDATE refresh_tokens
 		SET revoked_at = ?
 		WHERE token_hash = ? AND revoked_at IS NULL
 	`
 
-	result, err := r.db.Exec(ctx, query, time.Now(), tokenHash)
+	result, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query), time.Now(), tokenHash)
 	if err != nil {
 		return err
 	}
@@ -170,42 +161,33 @@ func (r *SQLRefreshTokenRepository) Revoke(tokenHash string) error {
 }
 
 // RevokeAll revokes all refresh tokens for a user
-func (r *SQLRefreshTokenRepository) RevokeAll(userID string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLRefreshTokenRepository) RevokeAll(ctx context.Context, userID string) error {
 	query := `
 		UPDATE refresh_tokens
 		SET revoked_at = ?
 		WHERE user_id = ? AND revoked_at IS NULL
 	`
 
-	_, err := r.db.Exec(ctx, query, time.Now(), userID)
+	_, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query), time.Now(), userID)
 	return err
 }
 
 // DeleteExpired removes all expired tokens
-func (r *SQLRefreshTokenRepository) DeleteExpired() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLRefreshTokenRepository) DeleteExpired(ctx context.Context) error {
 	query := `
 		DELETE FROM refresh_tokens
 		WHERE expires_at < ?
 	`
 
-	_, err := r.db.Exec(ctx, query, time.Now())
+	_, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query), time.Now())
 	return err
 }
 
 // Delete removes a specific token
-func (r *SQLRefreshTokenRepository) Delete(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func (r *SQLRefreshTokenRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM refresh_tokens WHERE id = ?`
 
-	result, err := r.db.Exec(ctx, query, id)
+	result, err := r.db.Exec(ctx, r.db.Dialect().Rebind(query), id)
 	if err != nil {
 		return err
 	}
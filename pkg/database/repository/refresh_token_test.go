@@ -38,7 +38,7 @@ func createTestUser(t *testing.T, repo *SQLAccountRepository) *adapter.Account {
 	err := acc.SetPassword("password123")
 	require.NoError(t, err)
 
-	err = repo.Create(acc)
+	err = repo.Create(context.Background(), acc)
 	require.NoError(t, err)
 
 	return acc
@@ -54,7 +54,7 @@ func TestRefreshTokenRepository_Create(t *testing.T) {
 
 	token := adapter.NewRefreshToken(user.ID, tokenHash, 7*24*time.Hour)
 
-	err = tokenRepo.Create(token)
+	err = tokenRepo.Create(context.Background(), token)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token.ID, "ID should be generated")
 	assert.False(t, token.CreatedAt.IsZero(), "CreatedAt should be set")
@@ -68,11 +68,11 @@ func TestRefreshTokenRepository_GetByTokenHash(t *testing.T) {
 	require.NoError(t, err)
 
 	token := adapter.NewRefreshToken(user.ID, tokenHash, 7*24*time.Hour)
-	err = tokenRepo.Create(token)
+	err = tokenRepo.Create(context.Background(), token)
 	require.NoError(t, err)
 
 	// Get by token hash
-	retrieved, err := tokenRepo.GetByTokenHash(tokenHash)
+	retrieved, err := tokenRepo.GetByTokenHash(context.Background(), tokenHash)
 	assert.NoError(t, err)
 	assert.Equal(t, token.ID, retrieved.ID)
 	assert.Equal(t, token.UserID, retrieved.UserID)
@@ -83,7 +83,7 @@ func TestRefreshTokenRepository_GetByTokenHash(t *testing.T) {
 func TestRefreshTokenRepository_GetByTokenHash_NotFound(t *testing.T) {
 	tokenRepo, _ := setupTokenTestDB(t)
 
-	retrieved, err := tokenRepo.GetByTokenHash("nonexistent")
+	retrieved, err := tokenRepo.GetByTokenHash(context.Background(), "nonexistent")
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrTokenNotFound, err)
 	assert.Nil(t, retrieved)
@@ -99,12 +99,12 @@ func TestRefreshTokenRepository_GetByUserID(t *testing.T) {
 		require.NoError(t, err)
 
 		token := adapter.NewRefreshToken(user.ID, tokenHash, 7*24*time.Hour)
-		err = tokenRepo.Create(token)
+		err = tokenRepo.Create(context.Background(), token)
 		require.NoError(t, err)
 	}
 
 	// Get all tokens for user
-	tokens, err := tokenRepo.GetByUserID(user.ID)
+	tokens, err := tokenRepo.GetByUserID(context.Background(), user.ID)
 	assert.NoError(t, err)
 	assert.Len(t, tokens, 3)
 }
@@ -117,15 +117,15 @@ func TestRefreshTokenRepository_Revoke(t *testing.T) {
 	require.NoError(t, err)
 
 	token := adapter.NewRefreshToken(user.ID, tokenHash, 7*24*time.Hour)
-	err = tokenRepo.Create(token)
+	err = tokenRepo.Create(context.Background(), token)
 	require.NoError(t, err)
 
 	// Revoke token
-	err = tokenRepo.Revoke(tokenHash)
+	err = tokenRepo.Revoke(context.Background(), tokenHash)
 	assert.NoError(t, err)
 
 	// Verify token is revoked
-	retrieved, err := tokenRepo.GetByTokenHash(tokenHash)
+	retrieved, err := tokenRepo.GetByTokenHash(context.Background(), tokenHash)
 	assert.NoError(t, err)
 	assert.True(t, retrieved.IsRevoked())
 	assert.False(t, retrieved.IsValid())
@@ -143,17 +143,17 @@ func TestRefreshTokenRepository_RevokeAll(t *testing.T) {
 		tokenHashes = append(tokenHashes, tokenHash)
 
 		token := adapter.NewRefreshToken(user.ID, tokenHash, 7*24*time.Hour)
-		err = tokenRepo.Create(token)
+		err = tokenRepo.Create(context.Background(), token)
 		require.NoError(t, err)
 	}
 
 	// Revoke all tokens
-	err := tokenRepo.RevokeAll(user.ID)
+	err := tokenRepo.RevokeAll(context.Background(), user.ID)
 	assert.NoError(t, err)
 
 	// Verify all tokens are revoked
 	for _, tokenHash := range tokenHashes {
-		retrieved, err := tokenRepo.GetByTokenHash(tokenHash)
+		retrieved, err := tokenRepo.GetByTokenHash(context.Background(), tokenHash)
 		assert.NoError(t, err)
 		assert.True(t, retrieved.IsRevoked())
 	}
@@ -168,7 +168,7 @@ func TestRefreshTokenRepository_DeleteExpired(t *testing.T) {
 	require.NoError(t, err)
 
 	expiredToken := adapter.NewRefreshToken(user.ID, expiredHash, -1*time.Hour) // Already expired
-	err = tokenRepo.Create(expiredToken)
+	err = tokenRepo.Create(context.Background(), expiredToken)
 	require.NoError(t, err)
 
 	// Create a valid token
@@ -176,20 +176,20 @@ func TestRefreshTokenRepository_DeleteExpired(t *testing.T) {
 	require.NoError(t, err)
 
 	validToken := adapter.NewRefreshToken(user.ID, validHash, 7*24*time.Hour)
-	err = tokenRepo.Create(validToken)
+	err = tokenRepo.Create(context.Background(), validToken)
 	require.NoError(t, err)
 
 	// Delete expired tokens
-	err = tokenRepo.DeleteExpired()
+	err = tokenRepo.DeleteExpired(context.Background())
 	assert.NoError(t, err)
 
 	// Verify expired token is deleted
-	_, err = tokenRepo.GetByTokenHash(expiredHash)
+	_, err = tokenRepo.GetByTokenHash(context.Background(), expiredHash)
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrTokenNotFound, err)
 
 	// Verify valid token still exists
-	retrieved, err := tokenRepo.GetByTokenHash(validHash)
+	retrieved, err := tokenRepo.GetByTokenHash(context.Background(), validHash)
 	assert.NoError(t, err)
 	assert.NotNil(t, retrieved)
 }
@@ -202,15 +202,15 @@ func TestRefreshTokenRepository_Delete(t *testing.T) {
 	require.NoError(t, err)
 
 	token := adapter.NewRefreshToken(user.ID, tokenHash, 7*24*time.Hour)
-	err = tokenRepo.Create(token)
+	err = tokenRepo.Create(context.Background(), token)
 	require.NoError(t, err)
 
 	// Delete token
-	err = tokenRepo.Delete(token.ID)
+	err = tokenRepo.Delete(context.Background(), token.ID)
 	assert.NoError(t, err)
 
 	// Verify token is deleted
-	_, err = tokenRepo.GetByTokenHash(tokenHash)
+	_, err = tokenRepo.GetByTokenHash(context.Background(), tokenHash)
 	assert.Error(t, err)
 	assert.Equal(t, adapter.ErrTokenNotFound, err)
 }
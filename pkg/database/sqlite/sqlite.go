@@ -10,6 +10,10 @@ import (
 
 const driverName = "sqlite"
 
+// timeFormat makes the driver write the timestamps as "2006-01-02 15:04:05.999999999-07:00",
+// which the SQLite date functions such as julianday understand, instead of the Go format
+const timeFormat = "_time_format=sqlite"
+
 // Config represents SQLite-specific configuration
 type Config struct {
 	// Path to the database file (:memory: for in-memory)
@@ -71,7 +75,7 @@ func NewInMemory() (database.Database, error) {
 // buildDSN builds the SQLite DSN from config
 func buildDSN(cfg *Config) string {
 	if cfg.Path == ":memory:" {
-		return ":memory:"
+		return ":memory:?" + timeFormat
 	}
 
 	// Clean the path
@@ -101,14 +105,9 @@ func buildDSN(cfg *Config) string {
 	}
 
 	// Build DSN
-	dsn := "file:" + path + "?"
-	first := true
+	dsn := "file:" + path + "?" + timeFormat
 	for k, v := range params {
-		if !first {
-			dsn += "&"
-		}
-		dsn += k + "=" + v
-		first = false
+		dsn += "&" + k + "=" + v
 	}
 
 	return dsn
@@ -110,7 +110,7 @@ func TestBuildDSN(t *testing.T) {
 			config: &Config{
 				Path: ":memory:",
 			},
-			contains: []string{":memory:"},
+			contains: []string{":memory:", "_time_format=sqlite"},
 		},
 		{
 			name: "file database with options",
@@ -129,6 +129,7 @@ func TestBuildDSN(t *testing.T) {
 				"_foreign_keys=1",
 				"_journal_mode=WAL",
 				"_busy_timeout=5000",
+				"_time_format=sqlite",
 			},
 		},
 	}
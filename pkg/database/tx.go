@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TxConfig configures how WithTxConfig runs a transaction
+type TxConfig struct {
+	// Isolation level, sql.LevelDefault uses the database default
+	Isolation sql.IsolationLevel
+
+	// ReadOnly starts a read-only transaction
+	ReadOnly bool
+
+	// MaxRetries is how many times the transaction is retried after
+	// a serialization failure, deadlock or busy database
+	MaxRetries int
+
+	// RetryDelay is the base delay between retries, multiplied by the attempt number
+	RetryDelay time.Duration
+}
+
+// DefaultTxConfig returns a TxConfig with sensible defaults
+func DefaultTxConfig() TxConfig {
+	return TxConfig{
+		Isolation:  sql.LevelDefault,
+		MaxRetries: 3,
+		RetryDelay: 20 * time.Millisecond,
+	}
+}
+
+// Executor is the query interface shared by *sql.DB and *sql.Tx
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+// txState is the transaction carried by a context
+type txState struct {
+	tx         *sql.Tx
+	db         *sql.DB
+	savepoints int
+}
+
+// TxFromContext returns the transaction carried by ctx, if any
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		return nil, false
+	}
+
+	return state.tx, true
+}
+
+// ExecutorFor returns the transaction carried by ctx when it belongs to db,
+// otherwise the plain *sql.DB
+func ExecutorFor(ctx context.Context, db *sql.DB) Executor {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok && state.db == db {
+		return state.tx
+	}
+
+	return db
+}
+
+// WithTx runs fn inside a transaction using DefaultTxConfig.
+// See WithTxConfig for details.
+func WithTx(ctx context.Context, db Database, fn func(ctx context.Context) error) error {
+	return WithTxConfig(ctx, db, DefaultTxConfig(), fn)
+}
+
+// WithTxConfig runs fn inside a transaction. The transaction is stored in the
+// context passed to fn, and every query made through db with that context joins it.
+// The transaction commits when fn returns nil and rolls back on error or panic.
+//
+// Nested calls with a context that already carries a transaction for db use a
+// savepoint, so only the nested work is rolled back when it fails.
+// The outermost call retries the whole transaction on serialization failures,
+// deadlocks and busy errors, so fn must be safe to run more than once.
+func WithTxConfig(ctx context.Context, db Database, cfg TxConfig, fn func(ctx context.Context) error) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok && state.db == db.DB() {
+		return withSavepoint(ctx, state, fn)
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = runTx(ctx, db, cfg, fn)
+		if err == nil || attempt >= cfg.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), err)
+		case <-time.After(cfg.RetryDelay * time.Duration(attempt+1)):
+		}
+	}
+}
+
+// runTx runs fn in a single transaction attempt
+func runTx(ctx context.Context, db Database, cfg TxConfig, fn func(ctx context.Context) error) error {
+	tx, err := db.DB().BeginTx(ctx, &sql.TxOptions{Isolation: cfg.Isolation, ReadOnly: cfg.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, &txState{tx: tx, db: db.DB()})); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+	}
+
+	return nil
+}
+
+// withSavepoint runs fn inside a savepoint of the ambient transaction
+func withSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	state.savepoints++
+	name := "sp_" + strconv.Itoa(state.savepoints)
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		if _, rbErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("%w: %w", ErrTransactionFailed, rbErr))
+		}
+
+		return err
+	}
+
+	if _, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+	}
+
+	return nil
+}
+
+// IsRetryable checks if the error is a transient concurrency failure
+// (serialization failure, deadlock, lock timeout or busy database)
+// after which the whole transaction can be retried
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	errMsg := err.Error()
+	for _, s := range []string{
+		"SQLITE_BUSY", "database is locked", // SQLite
+		"SQLSTATE 40001", "SQLSTATE 40P01", // PostgreSQL serialization failure, deadlock
+		"Error 1213", "Error 1205", // MySQL/MariaDB deadlock, lock wait timeout
+	} {
+		if strings.Contains(errMsg, s) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,135 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database"
+	"github.com/jorgefuertes/martian-stack/pkg/database/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTxDB(t *testing.T) database.Database {
+	db, err := sqlite.NewInMemory()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec(context.Background(), "CREATE TABLE items (name TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	return db
+}
+
+func countItems(t *testing.T, ctx context.Context, db database.Database) int {
+	var n int
+	require.NoError(t, db.QueryRow(ctx, "SELECT COUNT(*) FROM items").Scan(&n))
+
+	return n
+}
+
+func TestWithTx_Commit(t *testing.T) {
+	db := setupTxDB(t)
+
+	err := database.WithTx(context.Background(), db, func(ctx context.Context) error {
+		_, ok := database.TxFromContext(ctx)
+		assert.True(t, ok)
+
+		_, err := db.Exec(ctx, "INSERT INTO items (name) VALUES (?)", "one")
+		require.NoError(t, err)
+
+		// the insert is visible inside the transaction
+		assert.Equal(t, 1, countItems(t, ctx, db))
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, countItems(t, context.Background(), db))
+}
+
+func TestWithTx_RollbackOnError(t *testing.T) {
+	db := setupTxDB(t)
+	errBoom := errors.New("boom")
+
+	err := database.WithTx(context.Background(), db, func(ctx context.Context) error {
+		_, err := db.Exec(ctx, "INSERT INTO items (name) VALUES (?)", "one")
+		require.NoError(t, err)
+
+		return errBoom
+	})
+	assert.ErrorIs(t, err, errBoom)
+
+	assert.Equal(t, 0, countItems(t, context.Background(), db))
+}
+
+func TestWithTx_RollbackOnPanic(t *testing.T) {
+	db := setupTxDB(t)
+
+	assert.Panics(t, func() {
+		_ = database.WithTx(context.Background(), db, func(ctx context.Context) error {
+			_, err := db.Exec(ctx, "INSERT INTO items (name) VALUES (?)", "one")
+			require.NoError(t, err)
+
+			panic("boom")
+		})
+	})
+
+	assert.Equal(t, 0, countItems(t, context.Background(), db))
+}
+
+func TestWithTx_NestedSavepoint(t *testing.T) {
+	db := setupTxDB(t)
+	errBoom := errors.New("boom")
+
+	err := database.WithTx(context.Background(), db, func(ctx context.Context) error {
+		_, err := db.Exec(ctx, "INSERT INTO items (name) VALUES (?)", "outer")
+		require.NoError(t, err)
+
+		// a failing nested call only rolls back its own work
+		err = database.WithTx(ctx, db, func(ctx context.Context) error {
+			_, err := db.Exec(ctx, "INSERT INTO items (name) VALUES (?)", "inner")
+			require.NoError(t, err)
+
+			return errBoom
+		})
+		assert.ErrorIs(t, err, errBoom)
+
+		return database.WithTx(ctx, db, func(ctx context.Context) error {
+			_, err := db.Exec(ctx, "INSERT INTO items (name) VALUES (?)", "inner ok")
+			return err
+		})
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, countItems(t, context.Background(), db))
+}
+
+func TestWithTx_Retry(t *testing.T) {
+	db := setupTxDB(t)
+
+	attempts := 0
+	err := database.WithTx(context.Background(), db, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("database is locked (5) (SQLITE_BUSY)")
+		}
+
+		_, err := db.Exec(ctx, "INSERT INTO items (name) VALUES (?)", "one")
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 1, countItems(t, context.Background(), db))
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.False(t, database.IsRetryable(nil))
+	assert.False(t, database.IsRetryable(errors.New("syntax error")))
+	assert.True(t, database.IsRetryable(errors.New("database is locked")))
+	assert.True(t, database.IsRetryable(fmt.Errorf("wrapped: %w", errors.New("ERROR: deadlock (SQLSTATE 40P01)"))))
+	assert.True(t, database.IsRetryable(errors.New("Error 1213 (40001): Deadlock found")))
+}
@@ -28,7 +28,7 @@ type Account struct {
 	Email           string    `json:"email"        validate:"required,email"`
 	Enabled         bool      `json:"enabled"`
 	Role            string    `json:"role"         validate:"required,min=3,max=10"  default:"user"`
-	CryptedPassword []byte    `json:"_"`
+	CryptedPassword []byte    `json:"-"`
 }
 
 func (a Account) Validate() error {
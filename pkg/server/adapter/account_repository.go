@@ -1,9 +1,12 @@
 package adapter
 
 import (
+	"context"
 	"errors"
 	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -26,7 +29,11 @@ func NewInMemoryAccountRepository() *InMemoryAccountRepository {
 	}
 }
 
-func (r *InMemoryAccountRepository) Get(id string) (*Account, error) {
+func (r *InMemoryAccountRepository) Get(ctx context.Context, id string) (*Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
@@ -40,7 +47,11 @@ func (r *InMemoryAccountRepository) Get(id string) (*Account, error) {
 	return nil, ErrAccountNotFound
 }
 
-func (r *InMemoryAccountRepository) Exists(id string) bool {
+func (r *InMemoryAccountRepository) Exists(ctx context.Context, id string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
@@ -52,7 +63,11 @@ func (r *InMemoryAccountRepository) Exists(id string) bool {
 	return false
 }
 
-func (r *InMemoryAccountRepository) GetByEmail(email string) (*Account, error) {
+func (r *InMemoryAccountRepository) GetByEmail(ctx context.Context, email string) (*Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
@@ -66,7 +81,11 @@ func (r *InMemoryAccountRepository) GetByEmail(email string) (*Account, error) {
 	return nil, ErrAccountNotFound
 }
 
-func (r *InMemoryAccountRepository) GetByUsername(email string) (*Account, error) {
+func (r *InMemoryAccountRepository) GetByUsername(ctx context.Context, email string) (*Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
@@ -80,7 +99,11 @@ func (r *InMemoryAccountRepository) GetByUsername(email string) (*Account, error
 	return nil, ErrAccountNotFound
 }
 
-func (r *InMemoryAccountRepository) Create(a *Account) error {
+func (r *InMemoryAccountRepository) Create(ctx context.Context, a *Account) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if err := a.Validate(); err != nil {
 		return err
 	}
@@ -97,12 +120,19 @@ func (r *InMemoryAccountRepository) Create(a *Account) error {
 	defer r.lock.Unlock()
 
 	a.ID = uuid.NewString()
+	now := time.Now()
+	a.CreatedAt = now
+	a.UpdatedAt = now
 	r.accounts = append(r.accounts, *a)
 
 	return nil
 }
 
-func (r *InMemoryAccountRepository) Update(a *Account) error {
+func (r *InMemoryAccountRepository) Update(ctx context.Context, a *Account) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if err := a.Validate(); err != nil {
 		return err
 	}
@@ -116,6 +146,7 @@ func (r *InMemoryAccountRepository) Update(a *Account) error {
 
 	for i, acc := range r.accounts {
 		if acc.ID == a.ID {
+			a.UpdatedAt = time.Now()
 			r.accounts[i] = *a
 			return nil
 		}
@@ -124,7 +155,11 @@ func (r *InMemoryAccountRepository) Update(a *Account) error {
 	return ErrAccountNotFound
 }
 
-func (r *InMemoryAccountRepository) Delete(id string) error {
+func (r *InMemoryAccountRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
@@ -137,3 +172,77 @@ func (r *InMemoryAccountRepository) Delete(id string) error {
 
 	return ErrAccountNotFound
 }
+
+func (r *InMemoryAccountRepository) List(ctx context.Context, q AccountQuery) (*AccountPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	keyset, after, err := q.Keyset()
+	if err != nil {
+		return nil, err
+	}
+
+	r.lock.Lock()
+	matches := make([]Account, 0, len(r.accounts))
+	for _, a := range r.accounts {
+		if q.matches(a) {
+			matches = append(matches, a)
+		}
+	}
+	r.lock.Unlock()
+
+	slices.SortFunc(matches, func(a, b Account) int {
+		return compareKeyset(keyset, a.keysetValues(keyset), b.keysetValues(keyset))
+	})
+
+	page := &AccountPage{
+		PageInfo: PageInfo{Total: len(matches), Page: q.PageNumber(), Limit: q.PageSize()},
+		Accounts: make([]Account, 0, q.PageSize()),
+	}
+
+	start := min(q.Offset(), len(matches))
+	if after != nil {
+		start = len(matches)
+		for i, a := range matches {
+			if compareKeyset(keyset, a.keysetValues(keyset), after) > 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	end := min(start+q.PageSize(), len(matches))
+	page.Accounts = append(page.Accounts, matches[start:end]...)
+	if end < len(matches) {
+		page.NextCursor = matches[end-1].Cursor(keyset)
+	}
+
+	return page, nil
+}
+
+// matches reports whether a passes the query filters
+func (q AccountQuery) matches(a Account) bool {
+	if q.Role != "" && a.Role != q.Role {
+		return false
+	}
+	if q.Enabled != nil && a.Enabled != *q.Enabled {
+		return false
+	}
+	if !q.CreatedFrom.IsZero() && a.CreatedAt.Before(q.CreatedFrom) {
+		return false
+	}
+	if !q.CreatedTo.IsZero() && !a.CreatedAt.Before(q.CreatedTo) {
+		return false
+	}
+	if q.Search != "" {
+		search := strings.ToLower(q.Search)
+		if !strings.Contains(strings.ToLower(a.Username), search) &&
+			!strings.Contains(strings.ToLower(a.Name), search) &&
+			!strings.Contains(strings.ToLower(a.Email), search) {
+			return false
+		}
+	}
+
+	return true
+}
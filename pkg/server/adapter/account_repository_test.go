@@ -1,6 +1,7 @@
 package adapter_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
@@ -19,28 +20,28 @@ func TestAccountRepository(t *testing.T) {
 	// create
 	for _, a := range accounts {
 		a.SetPassword(a.Username + "-password")
-		err := r.Create(a)
+		err := r.Create(context.Background(), a)
 		require.NoError(t, err)
 		require.NotEmpty(t, a.ID)
 	}
 
 	// get by id
 	for _, a := range accounts {
-		a2, err := r.Get(a.ID)
+		a2, err := r.Get(context.Background(), a.ID)
 		require.NoError(t, err)
 		require.Equal(t, a, a2)
 	}
 
 	// get by email
 	for _, a := range accounts {
-		a2, err := r.GetByEmail(a.Email)
+		a2, err := r.GetByEmail(context.Background(), a.Email)
 		require.NoError(t, err)
 		require.Equal(t, a, a2)
 	}
 
 	// get by username
 	for _, a := range accounts {
-		a2, err := r.GetByUsername(a.Username)
+		a2, err := r.GetByUsername(context.Background(), a.Username)
 		require.NoError(t, err)
 		require.Equal(t, a, a2)
 	}
@@ -48,22 +49,22 @@ func TestAccountRepository(t *testing.T) {
 	// update
 	for _, a := range accounts {
 		a.Name = "Updated " + a.Name
-		err := r.Update(a)
+		err := r.Update(context.Background(), a)
 		require.NoError(t, err)
 	}
 	for _, a := range accounts {
-		a2, err := r.Get(a.ID)
+		a2, err := r.Get(context.Background(), a.ID)
 		require.NoError(t, err)
 		require.Equal(t, a, a2)
 	}
 
 	// delete
 	for _, a := range accounts {
-		err := r.Delete(a.ID)
+		err := r.Delete(context.Background(), a.ID)
 		require.NoError(t, err)
 	}
 	for _, a := range accounts {
-		a2, err := r.Get(a.ID)
+		a2, err := r.Get(context.Background(), a.ID)
 		require.Error(t, err)
 		require.ErrorIs(t, err, adapter.ErrAccountNotFound)
 		require.Nil(t, a2)
@@ -71,7 +72,85 @@ func TestAccountRepository(t *testing.T) {
 
 	// create with ID
 	accounts[0].ID = "test-id"
-	err := r.Create(accounts[0])
+	err := r.Create(context.Background(), accounts[0])
 	require.Error(t, err)
 	require.ErrorIs(t, err, adapter.ErrCannotCreateWithID)
 }
+
+func TestAccountRepository_CanceledContext(t *testing.T) {
+	r := adapter.NewInMemoryAccountRepository()
+
+	a := &adapter.Account{Username: "test", Name: "Test", Email: "test@test.com", Role: "user", Enabled: true}
+	a.SetPassword("test-password")
+	require.NoError(t, r.Create(context.Background(), a))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := r.Get(ctx, a.ID)
+	require.ErrorIs(t, err, context.Canceled)
+
+	err = r.Delete(ctx, a.ID)
+	require.ErrorIs(t, err, context.Canceled)
+	require.False(t, r.Exists(ctx, a.ID))
+	require.True(t, r.Exists(context.Background(), a.ID))
+}
+
+func TestAccountRepository_List(t *testing.T) {
+	r := adapter.NewInMemoryAccountRepository()
+
+	for i, name := range []string{"alice", "bob_b", "carol", "dave", "erin"} {
+		a := &adapter.Account{
+			Username: name,
+			Name:     name + " Test",
+			Email:    name + "@test.com",
+			Role:     "user",
+			Enabled:  i != 3,
+		}
+		a.SetPassword(name + "-password")
+		require.NoError(t, r.Create(context.Background(), a))
+	}
+
+	usernames := func(page *adapter.AccountPage) []string {
+		names := make([]string, len(page.Accounts))
+		for i, a := range page.Accounts {
+			names[i] = a.Username
+		}
+
+		return names
+	}
+
+	// offset
+	q := adapter.AccountQuery{ListQuery: adapter.ListQuery{Page: 2, Limit: 2, Sort: adapter.ParseSort("username")}}
+	page, err := r.List(context.Background(), q)
+	require.NoError(t, err)
+	require.Equal(t, 5, page.Total)
+	require.Equal(t, []string{"carol", "dave"}, usernames(page))
+
+	// cursor
+	q = adapter.AccountQuery{ListQuery: adapter.ListQuery{Limit: 2, Sort: adapter.ParseSort("-username")}}
+	var listed []string
+	for {
+		page, err := r.List(context.Background(), q)
+		require.NoError(t, err)
+		listed = append(listed, usernames(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	require.Equal(t, []string{"erin", "dave", "carol", "bob_b", "alice"}, listed)
+
+	// filters
+	enabled := true
+	page, err = r.List(context.Background(), adapter.AccountQuery{Enabled: &enabled, Search: "B_B"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob_b"}, usernames(page))
+
+	// invalid sort
+	_, err = r.List(
+		context.Background(),
+		adapter.AccountQuery{ListQuery: adapter.ListQuery{Sort: adapter.ParseSort("foo")}},
+	)
+	require.ErrorIs(t, err, adapter.ErrInvalidSort)
+}
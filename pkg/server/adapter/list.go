@@ -0,0 +1,304 @@
+package adapter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultListLimit is the page size used when a list query sets no limit
+	DefaultListLimit = 20
+
+	// MaxListLimit caps the page size of list queries
+	MaxListLimit = 100
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort field")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// SortField is a field to sort by and its direction
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a comma separated sort expression such as "-created_at,username".
+// A leading - sorts descending, a leading + or no prefix sorts ascending.
+func ParseSort(s string) []SortField {
+	var fields []SortField
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		f := SortField{Field: part}
+		switch part[0] {
+		case '-':
+			f = SortField{Field: part[1:], Desc: true}
+		case '+':
+			f = SortField{Field: part[1:]}
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// FormatSort is the inverse of ParseSort
+func FormatSort(fields []SortField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.Field
+		if f.Desc {
+			parts[i] = "-" + f.Field
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// ListQuery holds the pagination and sorting options of a list request
+type ListQuery struct {
+	// Page is the 1-based page number used by offset pagination
+	Page int
+
+	// Limit is the page size, DefaultListLimit when zero and capped at MaxListLimit
+	Limit int
+
+	// Cursor continues a keyset pagination from the NextCursor of a previous page.
+	// When set, Page is ignored.
+	Cursor string
+
+	// Sort fields, applied in order
+	Sort []SortField
+}
+
+// PageSize returns the effective page size
+func (q ListQuery) PageSize() int {
+	switch {
+	case q.Limit <= 0:
+		return DefaultListLimit
+	case q.Limit > MaxListLimit:
+		return MaxListLimit
+	default:
+		return q.Limit
+	}
+}
+
+// PageNumber returns the effective 1-based page, or zero for cursor pagination
+func (q ListQuery) PageNumber() int {
+	if q.Cursor != "" {
+		return 0
+	}
+
+	return max(q.Page, 1)
+}
+
+// Offset returns the number of items to skip, always zero for cursor pagination
+func (q ListQuery) Offset() int {
+	if q.Cursor != "" {
+		return 0
+	}
+
+	return (q.PageNumber() - 1) * q.PageSize()
+}
+
+// PageInfo describes a page of results
+type PageInfo struct {
+	// Total is the number of matching items across all pages
+	Total int `json:"total"`
+
+	// Page is the 1-based page number, zero for cursor pages
+	Page int `json:"page,omitempty"`
+
+	// Limit is the page size
+	Limit int `json:"limit"`
+
+	// NextCursor continues the listing after this page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// AccountQuery filters, sorts and paginates account listings
+type AccountQuery struct {
+	ListQuery
+
+	// Role matches the account role exactly, ignored when empty
+	Role string
+
+	// Enabled matches the enabled flag, ignored when nil
+	Enabled *bool
+
+	// CreatedFrom (inclusive) and CreatedTo (exclusive) bound the creation date,
+	// each ignored when zero
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	// Search is a case-insensitive substring of the username, name or email
+	Search string
+}
+
+// AccountPage is a page of accounts returned by List
+type AccountPage struct {
+	PageInfo
+	Accounts []Account `json:"accounts"`
+}
+
+// AccountSortFields are the fields accounts can be sorted by
+var AccountSortFields = []string{"id", "created_at", "updated_at", "username", "name", "email", "role"}
+
+// DefaultAccountSort is applied when an AccountQuery has no sort fields
+var DefaultAccountSort = []SortField{{Field: "created_at"}}
+
+// Keyset validates the query sort and returns it with the id appended as a tie-breaker,
+// along with the values decoded from the cursor (nil when there is no cursor).
+// Listing in keyset order gives a stable order for both offset and cursor pagination.
+func (q AccountQuery) Keyset() ([]SortField, []any, error) {
+	sort := q.Sort
+	if len(sort) == 0 {
+		sort = DefaultAccountSort
+	}
+
+	keyset := make([]SortField, 0, len(sort)+1)
+	for _, f := range sort {
+		if !slices.Contains(AccountSortFields, f.Field) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrInvalidSort, f.Field)
+		}
+		keyset = append(keyset, f)
+	}
+
+	if !slices.ContainsFunc(keyset, func(f SortField) bool { return f.Field == "id" }) {
+		keyset = append(keyset, SortField{Field: "id"})
+	}
+
+	if q.Cursor == "" {
+		return keyset, nil, nil
+	}
+
+	values, err := decodeAccountCursor(q.Cursor, keyset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keyset, values, nil
+}
+
+// FieldValue returns the value of a sortable field:
+// a time.Time for the timestamps and a string otherwise
+func (a Account) FieldValue(field string) any {
+	switch field {
+	case "id":
+		return a.ID
+	case "created_at":
+		return a.CreatedAt
+	case "updated_at":
+		return a.UpdatedAt
+	case "username":
+		return a.Username
+	case "name":
+		return a.Name
+	case "email":
+		return a.Email
+	case "role":
+		return a.Role
+	default:
+		return nil
+	}
+}
+
+// Cursor returns an opaque cursor that continues a keyset listing after a
+func (a Account) Cursor(keyset []SortField) string {
+	c := cursor{Sort: FormatSort(keyset), Values: make([]string, len(keyset))}
+	for i, f := range keyset {
+		switch v := a.FieldValue(f.Field).(type) {
+		case time.Time:
+			c.Values[i] = v.Format(time.RFC3339Nano)
+		case string:
+			c.Values[i] = v
+		}
+	}
+
+	b, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// cursor is the decoded form of a keyset cursor, bound to the sort it was built for
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func decodeAccountCursor(s string, keyset []SortField) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// a cursor only makes sense for the sort it was built for
+	if c.Sort != FormatSort(keyset) || len(c.Values) != len(keyset) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]any, len(keyset))
+	for i, f := range keyset {
+		if _, ok := (Account{}).FieldValue(f.Field).(time.Time); !ok {
+			values[i] = c.Values[i]
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, c.Values[i])
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = t
+	}
+
+	return values, nil
+}
+
+// compareKeyset compares two sets of keyset values honoring each field direction
+func compareKeyset(keyset []SortField, a, b []any) int {
+	for i, f := range keyset {
+		var c int
+		switch av := a[i].(type) {
+		case time.Time:
+			c = av.Compare(b[i].(time.Time))
+		case string:
+			c = strings.Compare(av, b[i].(string))
+		}
+
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// keysetValues returns the values of the keyset fields of a
+func (a Account) keysetValues(keyset []SortField) []any {
+	values := make([]any, len(keyset))
+	for i, f := range keyset {
+		values[i] = a.FieldValue(f.Field)
+	}
+
+	return values
+}
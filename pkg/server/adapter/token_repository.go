@@ -1,47 +1,50 @@
 package adapter
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // RefreshTokenRepository defines the interface for refresh token operations
 type RefreshTokenRepository interface {
 	// Create stores a new refresh token
-	Create(token *RefreshToken) error
+	Create(ctx context.Context, token *RefreshToken) error
 
 	// GetByTokenHash retrieves a refresh token by its hash
-	GetByTokenHash(tokenHash string) (*RefreshToken, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
 
 	// GetByUserID retrieves all refresh tokens for a user
-	GetByUserID(userID string) ([]*RefreshToken, error)
+	GetByUserID(ctx context.Context, userID string) ([]*RefreshToken, error)
 
 	// Revoke marks a refresh token as revoked
-	Revoke(tokenHash string) error
+	Revoke(ctx context.Context, tokenHash string) error
 
 	// RevokeAll revokes all refresh tokens for a user
-	RevokeAll(userID string) error
+	RevokeAll(ctx context.Context, userID string) error
 
 	// DeleteExpired removes all expired tokens
-	DeleteExpired() error
+	DeleteExpired(ctx context.Context) error
 
 	// Delete removes a specific token
-	Delete(id string) error
+	Delete(ctx context.Context, id string) error
 }
 
 // PasswordResetTokenRepository defines the interface for password reset token operations
 type PasswordResetTokenRepository interface {
 	// Create stores a new password reset token
-	Create(token *PasswordResetToken) error
+	Create(ctx context.Context, token *PasswordResetToken) error
 
 	// GetByTokenHash retrieves a password reset token by its hash
-	GetByTokenHash(tokenHash string) (*PasswordResetToken, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
 
 	// MarkAsUsed marks a password reset token as used
-	MarkAsUsed(tokenHash string) error
+	MarkAsUsed(ctx context.Context, tokenHash string) error
 
 	// DeleteExpired removes all expired tokens
-	DeleteExpired() error
+	DeleteExpired(ctx context.Context) error
 
 	// DeleteByUserID removes all password reset tokens for a user
-	DeleteByUserID(userID string) error
+	DeleteByUserID(ctx context.Context, userID string) error
 }
 
 // TokenRepositories combines both token repositories
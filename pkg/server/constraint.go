@@ -0,0 +1,90 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// namedConstraints are the constraint shortcuts accepted in {param:constraint} segments
+var namedConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+}
+
+// constraints maps the path params of a route to the regexp their values must match
+type constraints map[string]*regexp.Regexp
+
+// parseConstraints removes the constraints from the {param:constraint} segments of a
+// path, returning the plain pattern and the compiled constraints. A constraint is one
+// of the namedConstraints or a regexp matched against the whole value.
+// It panics on invalid regexps, like http.ServeMux does on invalid patterns.
+func parseConstraints(path string) (string, constraints) {
+	var b strings.Builder
+	var cs constraints
+
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			b.WriteString(path)
+			break
+		}
+
+		// find the closing brace, regexps may hold braces too
+		end, depth := -1, 0
+		for i := start; i < len(path) && end < 0; i++ {
+			switch path[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			panic(fmt.Sprintf("route %s: unclosed brace", path))
+		}
+
+		b.WriteString(path[:start])
+		name, constraint, ok := strings.Cut(path[start+1:end], ":")
+		b.WriteString("{" + name + "}")
+
+		if ok {
+			expr, named := namedConstraints[constraint]
+			if !named {
+				expr = constraint
+			}
+
+			re, err := regexp.Compile(`^(?:` + expr + `)$`)
+			if err != nil {
+				panic(fmt.Sprintf("route param %s: invalid constraint: %v", name, err))
+			}
+
+			if cs == nil {
+				cs = constraints{}
+			}
+			cs[strings.TrimSuffix(name, "...")] = re
+		}
+
+		path = path[end+1:]
+	}
+
+	return b.String(), cs
+}
+
+// match reports whether the path params of the request satisfy the constraints
+func (cs constraints) match(r *http.Request) bool {
+	for name, re := range cs {
+		if !re.MatchString(r.PathValue(name)) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -15,8 +15,9 @@ import (
 // this pointer ensures mutations (e.g. status code set by a handler)
 // are visible to middleware that runs after (e.g. logging).
 type state struct {
-	next       int
-	statusCode int
+	next         int
+	statusCode   int
+	errorHandled bool
 }
 
 type Ctx struct {
@@ -27,6 +28,11 @@ type Ctx struct {
 	wr       http.ResponseWriter
 	handlers []Handler
 	state    *state
+	urls     URLBuilder
+	onError  func(Ctx, error)
+
+	noQueryFallback bool
+	bodyLimit       int64
 }
 
 func New(wr http.ResponseWriter, req *http.Request, handlers ...Handler) Ctx {
@@ -40,7 +46,12 @@ func New(wr http.ResponseWriter, req *http.Request, handlers ...Handler) Ctx {
 	}
 	handlers = handlers[:n]
 
-	id := uuid.New().String()
+	// keep the ID of a Ctx up the chain, when the request crossed net/http handlers
+	id, ok := req.Context().Value(requestIDKey{}).(string)
+	if !ok {
+		id = uuid.New().String()
+		req = req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id))
+	}
 
 	return Ctx{
 		id:       id,
@@ -82,6 +93,17 @@ func (c Ctx) ID() string {
 	return c.id
 }
 
+// requestIDKey is the request context key of the Ctx ID
+type requestIDKey struct{}
+
+// RequestID returns the ID of the Ctx serving the request, for the net/http handlers
+// and middleware running inside a Ctx chain. It is empty outside of one.
+func RequestID(reqCtx context.Context) string {
+	id, _ := reqCtx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
 func (c Ctx) Status() int {
 	return c.state.statusCode
 }
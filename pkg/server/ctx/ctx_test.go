@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
@@ -87,3 +88,19 @@ func TestResponseWriting(t *testing.T) {
 		assert.EqualValues(t, obj, obj2)
 	})
 }
+
+func TestFormValue(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?page=2", strings.NewReader("name=John+Doe&enabled=true"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c := ctx.New(httptest.NewRecorder(), req)
+
+	assert.True(t, c.IsForm())
+	assert.Equal(t, "John Doe", c.FormValue("name"))
+	assert.Equal(t, "true", c.FormValue("enabled"))
+	assert.Equal(t, "2", c.FormValue("page"))
+	assert.Empty(t, c.FormValue("missing"))
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"John"}`))
+	req.Header.Set("Content-Type", "application/json")
+	assert.False(t, ctx.New(httptest.NewRecorder(), req).IsForm())
+}
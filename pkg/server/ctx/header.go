@@ -16,6 +16,11 @@ func (c Ctx) AddHeader(key, value string) {
 	c.wr.Header().Add(key, value)
 }
 
+// GetResponseHeader returns a header already set on the response
+func (c Ctx) GetResponseHeader(key string) string {
+	return c.wr.Header().Get(key)
+}
+
 func (c Ctx) GetRequestHeader(key string) string {
 	return c.req.Header.Get(textproto.CanonicalMIMEHeaderKey(key))
 }
@@ -0,0 +1,99 @@
+package ctx
+
+import "net/http"
+
+// Request returns the underlying http.Request.
+func (c Ctx) Request() *http.Request {
+	return c.req
+}
+
+// ResponseWriter returns the underlying http.ResponseWriter.
+func (c Ctx) ResponseWriter() http.ResponseWriter {
+	return c.wr
+}
+
+// WithRequest returns a copy of Ctx serving the given request.
+func (c Ctx) WithRequest(req *http.Request) Ctx {
+	c.req = req
+	return c
+}
+
+// WithResponseWriter returns a copy of Ctx writing the response to w.
+func (c Ctx) WithResponseWriter(w http.ResponseWriter) Ctx {
+	c.wr = w
+	return c
+}
+
+// WithErrorHandler returns a copy of Ctx whose HandleError calls h.
+// The server sets its ErrorHandler on every request.
+func (c Ctx) WithErrorHandler(h func(c Ctx, err error)) Ctx {
+	c.onError = h
+	return c
+}
+
+// HandleError passes the error to the error handler, once per request:
+// errors already handled down the chain are ignored.
+func (c Ctx) HandleError(err error) {
+	if err == nil || c.onError == nil || c.state.errorHandled {
+		return
+	}
+
+	c.state.errorHandled = true
+	c.onError(c, err)
+}
+
+// WrapHandler adapts an http.Handler to a Handler ending the chain. The status code
+// it writes is kept, so middleware like the logger see it.
+//
+//	srv.Route(web.MethodGet, "/metrics", ctx.WrapHandler(promhttp.Handler()))
+func WrapHandler(h http.Handler) Handler {
+	return func(c Ctx) error {
+		h.ServeHTTP(statusWriter{ResponseWriter: c.wr, state: c.state}, c.req)
+		return nil
+	}
+}
+
+// WrapMiddleware adapts a standard func(http.Handler) http.Handler middleware to a
+// Handler. The rest of the chain runs inside it with the request and response writer
+// it passes down, and its errors are handled there, so the middleware sees the error
+// response. The error is still returned up the chain, for middleware like the logger.
+// When the middleware does not call the next handler, the rest of the chain is skipped.
+//
+//	srv.Use(ctx.WrapMiddleware(otelhttp.NewMiddleware("api")))
+func WrapMiddleware(mw func(http.Handler) http.Handler) Handler {
+	return func(c Ctx) error {
+		var err error
+
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inner := c.WithRequest(r).WithResponseWriter(w)
+			if err = inner.Next(); err != nil {
+				inner.HandleError(err)
+			}
+		})
+
+		mw(next).ServeHTTP(statusWriter{ResponseWriter: c.wr, state: c.state}, c.req)
+
+		return err
+	}
+}
+
+// statusWriter records the status code written by net/http handlers in the Ctx state
+type statusWriter struct {
+	http.ResponseWriter
+	state *state
+}
+
+func (w statusWriter) WriteHeader(code int) {
+	w.state.statusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush keeps streaming working through the wrapper
+func (w statusWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap gives http.ResponseController access to the original writer
+func (w statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,69 @@
+package ctx_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapHTTP(t *testing.T) {
+	deny := ctx.WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Deny") != "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	teapot := ctx.WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, ctx.RequestID(r.Context()))
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	t.Run("status of the wrapped handler", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c := ctx.New(w, httptest.NewRequest(http.MethodGet, "/", nil), deny, teapot)
+
+		require.NoError(t, c.Next())
+		assert.Equal(t, http.StatusTeapot, w.Code)
+		assert.Equal(t, http.StatusTeapot, c.Status())
+	})
+
+	t.Run("middleware skipping the chain", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Deny", "1")
+		c := ctx.New(w, req, deny, teapot)
+
+		require.NoError(t, c.Next())
+		assert.Equal(t, http.StatusForbidden, c.Status())
+	})
+
+	t.Run("errors handled once", func(t *testing.T) {
+		calls := 0
+		fail := func(c ctx.Ctx) error {
+			return errors.New("boom")
+		}
+
+		w := httptest.NewRecorder()
+		c := ctx.New(w, httptest.NewRequest(http.MethodGet, "/", nil), deny, fail).
+			WithErrorHandler(func(c ctx.Ctx, err error) {
+				calls++
+				_ = c.WithStatus(http.StatusInternalServerError).SendString(err.Error())
+			})
+
+		err := c.Next()
+		require.EqualError(t, err, "boom")
+		c.HandleError(err)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "boom", w.Body.String())
+	})
+}
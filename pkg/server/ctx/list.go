@@ -0,0 +1,142 @@
+package ctx
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+)
+
+// ListQuery parses the ?page=&limit=&sort=&cursor= query parameters.
+// Malformed or negative numbers return a 400 error.
+func (c Ctx) ListQuery() (adapter.ListQuery, error) {
+	values := c.req.URL.Query()
+
+	page, err := c.queryNumber("page")
+	if err != nil {
+		return adapter.ListQuery{}, err
+	}
+
+	limit, err := c.queryNumber("limit")
+	if err != nil {
+		return adapter.ListQuery{}, err
+	}
+
+	return adapter.ListQuery{
+		Page:   page,
+		Limit:  limit,
+		Cursor: values.Get("cursor"),
+		Sort:   adapter.ParseSort(values.Get("sort")),
+	}, nil
+}
+
+// AccountQuery parses ListQuery plus the account filters
+// ?role=&enabled=&created_from=&created_to=&q=
+// Dates are RFC 3339 timestamps or YYYY-MM-DD days.
+func (c Ctx) AccountQuery() (adapter.AccountQuery, error) {
+	list, err := c.ListQuery()
+	if err != nil {
+		return adapter.AccountQuery{}, err
+	}
+
+	values := c.req.URL.Query()
+	q := adapter.AccountQuery{
+		ListQuery: list,
+		Role:      values.Get("role"),
+		Search:    values.Get("q"),
+	}
+
+	if s := values.Get("enabled"); s != "" {
+		enabled, err := strconv.ParseBool(s)
+		if err != nil {
+			return adapter.AccountQuery{}, c.Error(http.StatusBadRequest, "Invalid enabled value")
+		}
+		q.Enabled = &enabled
+	}
+
+	if q.CreatedFrom, err = c.queryTime("created_from"); err != nil {
+		return adapter.AccountQuery{}, err
+	}
+
+	if q.CreatedTo, err = c.queryTime("created_to"); err != nil {
+		return adapter.AccountQuery{}, err
+	}
+
+	return q, nil
+}
+
+// SetPageHeaders writes the X-Total-Count header and a Link header pointing to the
+// first, prev, next and last pages. Cursor pages (Page zero) only link to the next one.
+func (c Ctx) SetPageHeaders(p adapter.PageInfo) {
+	c.SetHeader(web.HeaderXTotalCount, strconv.Itoa(p.Total))
+
+	if p.Page == 0 {
+		if p.NextCursor != "" {
+			c.AddHeader(web.HeaderLink, c.pageLink("next", "cursor", p.NextCursor))
+		}
+
+		return
+	}
+
+	last := 1
+	if p.Limit > 0 && p.Total > 0 {
+		last = (p.Total + p.Limit - 1) / p.Limit
+	}
+
+	c.AddHeader(web.HeaderLink, c.pageLink("first", "page", "1"))
+	if p.Page > 1 {
+		c.AddHeader(web.HeaderLink, c.pageLink("prev", "page", strconv.Itoa(min(p.Page-1, last))))
+	}
+	if p.Page < last {
+		c.AddHeader(web.HeaderLink, c.pageLink("next", "page", strconv.Itoa(p.Page+1)))
+	}
+	c.AddHeader(web.HeaderLink, c.pageLink("last", "page", strconv.Itoa(last)))
+}
+
+// pageLink builds a Link header entry for the current URL with key set to value,
+// dropping the other pagination key so page and cursor are never mixed
+func (c Ctx) pageLink(rel, key, value string) string {
+	u := *c.req.URL
+	q := u.Query()
+
+	q.Del("page")
+	q.Del("cursor")
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+
+	return fmt.Sprintf("<%s>; rel=%q", u.String(), rel)
+}
+
+// queryNumber parses a non-negative integer query parameter, zero when missing
+func (c Ctx) queryNumber(key string) (int, error) {
+	s := c.req.URL.Query().Get(key)
+	if s == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, c.Error(http.StatusBadRequest, "Invalid "+key+" value")
+	}
+
+	return n, nil
+}
+
+// queryTime parses an RFC 3339 or YYYY-MM-DD query parameter, zero when missing
+func (c Ctx) queryTime(key string) (time.Time, error) {
+	s := c.req.URL.Query().Get(key)
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, c.Error(http.StatusBadRequest, "Invalid "+key+" value")
+}
@@ -0,0 +1,76 @@
+package ctx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/adapter"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/servererror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListQuery(t *testing.T) {
+	newCtx := func(target string) (*httptest.ResponseRecorder, ctx.Ctx) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+
+		return w, ctx.New(w, req)
+	}
+
+	t.Run("parses pagination and sort", func(t *testing.T) {
+		_, c := newCtx("/users?page=3&limit=10&sort=-created_at,username")
+
+		q, err := c.ListQuery()
+		require.NoError(t, err)
+		assert.Equal(t, 3, q.Page)
+		assert.Equal(t, 10, q.Limit)
+		assert.Equal(t, 20, q.Offset())
+		assert.Equal(t, []adapter.SortField{{Field: "created_at", Desc: true}, {Field: "username"}}, q.Sort)
+	})
+
+	t.Run("invalid number is a bad request", func(t *testing.T) {
+		_, c := newCtx("/users?page=-1")
+
+		_, err := c.ListQuery()
+		var srvErr servererror.Error
+		require.ErrorAs(t, err, &srvErr)
+		assert.Equal(t, http.StatusBadRequest, srvErr.Code)
+	})
+
+	t.Run("parses account filters", func(t *testing.T) {
+		_, c := newCtx("/users?role=admin&enabled=false&created_from=2025-01-01&q=john")
+
+		q, err := c.AccountQuery()
+		require.NoError(t, err)
+		assert.Equal(t, "admin", q.Role)
+		require.NotNil(t, q.Enabled)
+		assert.False(t, *q.Enabled)
+		assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), q.CreatedFrom)
+		assert.True(t, q.CreatedTo.IsZero())
+		assert.Equal(t, "john", q.Search)
+	})
+
+	t.Run("offset page headers", func(t *testing.T) {
+		w, c := newCtx("/users?page=2&limit=10&role=user")
+
+		c.SetPageHeaders(adapter.PageInfo{Total: 35, Page: 2, Limit: 10})
+		assert.Equal(t, "35", w.Header().Get("X-Total-Count"))
+		assert.Equal(t, []string{
+			`</users?limit=10&page=1&role=user>; rel="first"`,
+			`</users?limit=10&page=1&role=user>; rel="prev"`,
+			`</users?limit=10&page=3&role=user>; rel="next"`,
+			`</users?limit=10&page=4&role=user>; rel="last"`,
+		}, w.Header().Values("Link"))
+	})
+
+	t.Run("cursor page headers", func(t *testing.T) {
+		w, c := newCtx("/users?cursor=abc&limit=10")
+
+		c.SetPageHeaders(adapter.PageInfo{Total: 35, Limit: 10, NextCursor: "def"})
+		assert.Equal(t, []string{`</users?cursor=def&limit=10>; rel="next"`}, w.Header().Values("Link"))
+	})
+}
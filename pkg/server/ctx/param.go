@@ -0,0 +1,109 @@
+package ctx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// PathParam returns a path param, without the query string fallback of Param
+func (c Ctx) PathParam(key string) string {
+	return c.req.PathValue(key)
+}
+
+// WithoutQueryFallback returns a copy of Ctx whose Param and typed param accessors
+// only read path params, so ?id= cannot stand in for /users/{id}.
+// The server sets it on every request when built with DisableQueryFallback.
+func (c Ctx) WithoutQueryFallback() Ctx {
+	c.noQueryFallback = true
+	return c
+}
+
+// ParamInt returns Param as an int, the error is a 400 when it is missing or invalid
+func (c Ctx) ParamInt(key string) (int, error) {
+	s, err := c.requiredParam(key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, c.Error(http.StatusBadRequest, "Invalid "+key+" value")
+	}
+
+	return n, nil
+}
+
+// ParamUUID returns Param as an UUID, the error is a 400 when it is missing or invalid
+func (c Ctx) ParamUUID(key string) (uuid.UUID, error) {
+	s, err := c.requiredParam(key)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, c.Error(http.StatusBadRequest, "Invalid "+key+" value")
+	}
+
+	return id, nil
+}
+
+// QueryInt returns a query string param as an int, def when missing.
+// The error is a 400 when it is not a number.
+func (c Ctx) QueryInt(key string, def int) (int, error) {
+	s := c.req.URL.Query().Get(key)
+	if s == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def, c.Error(http.StatusBadRequest, "Invalid "+key+" value")
+	}
+
+	return n, nil
+}
+
+// QueryBool returns a query string param as a bool, def when missing.
+// It accepts the values of strconv.ParseBool, the error is a 400 for any other.
+func (c Ctx) QueryBool(key string, def bool) (bool, error) {
+	s := c.req.URL.Query().Get(key)
+	if s == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return def, c.Error(http.StatusBadRequest, "Invalid "+key+" value")
+	}
+
+	return b, nil
+}
+
+// QuerySlice returns the values of a query string param, given repeated
+// (?tag=a&tag=b) or comma separated (?tag=a,b), skipping the empty ones
+func (c Ctx) QuerySlice(key string) []string {
+	var values []string
+	for _, v := range c.req.URL.Query()[key] {
+		for part := range strings.SplitSeq(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+
+	return values
+}
+
+// requiredParam returns Param, the error is a 400 when it is empty
+func (c Ctx) requiredParam(key string) (string, error) {
+	s := c.Param(key)
+	if s == "" {
+		return "", c.Error(http.StatusBadRequest, "Missing "+key)
+	}
+
+	return s, nil
+}
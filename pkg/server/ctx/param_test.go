@@ -0,0 +1,93 @@
+package ctx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/servererror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedParams(t *testing.T) {
+	newCtx := func(target string, pathValues ...string) ctx.Ctx {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i+1 < len(pathValues); i += 2 {
+			req.SetPathValue(pathValues[i], pathValues[i+1])
+		}
+
+		return ctx.New(httptest.NewRecorder(), req)
+	}
+
+	assertBadRequest := func(t *testing.T, err error) {
+		t.Helper()
+
+		var srvErr servererror.Error
+		require.ErrorAs(t, err, &srvErr)
+		assert.Equal(t, http.StatusBadRequest, srvErr.Code)
+	}
+
+	t.Run("path params", func(t *testing.T) {
+		id := uuid.New()
+		c := newCtx("/", "n", "42", "id", id.String(), "bad", "x")
+
+		n, err := c.ParamInt("n")
+		require.NoError(t, err)
+		assert.Equal(t, 42, n)
+
+		got, err := c.ParamUUID("id")
+		require.NoError(t, err)
+		assert.Equal(t, id, got)
+
+		_, err = c.ParamInt("bad")
+		assertBadRequest(t, err)
+
+		_, err = c.ParamUUID("bad")
+		assertBadRequest(t, err)
+
+		_, err = c.ParamInt("missing")
+		assertBadRequest(t, err)
+	})
+
+	t.Run("query fallback", func(t *testing.T) {
+		c := newCtx("/?n=7")
+
+		n, err := c.ParamInt("n")
+		require.NoError(t, err)
+		assert.Equal(t, 7, n)
+
+		assert.Empty(t, c.PathParam("n"))
+		assert.Empty(t, c.WithoutQueryFallback().Param("n"))
+
+		_, err = c.WithoutQueryFallback().ParamInt("n")
+		assertBadRequest(t, err)
+	})
+
+	t.Run("query params", func(t *testing.T) {
+		c := newCtx("/?limit=10&draft=true&tag=go,web&tag=api&tag=&bad=x")
+
+		limit, err := c.QueryInt("limit", 20)
+		require.NoError(t, err)
+		assert.Equal(t, 10, limit)
+
+		page, err := c.QueryInt("page", 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, page)
+
+		draft, err := c.QueryBool("draft", false)
+		require.NoError(t, err)
+		assert.True(t, draft)
+
+		assert.Equal(t, []string{"go", "web", "api"}, c.QuerySlice("tag"))
+		assert.Empty(t, c.QuerySlice("missing"))
+
+		_, err = c.QueryInt("bad", 0)
+		assertBadRequest(t, err)
+
+		_, err = c.QueryBool("bad", false)
+		assertBadRequest(t, err)
+	})
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/jorgefuertes/martian-stack/pkg/helper"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
 )
 
 func (c Ctx) Method() string {
@@ -32,8 +33,14 @@ func (c Ctx) UserIP() string {
 	return host
 }
 
+// Param returns a path param, falling back to the query string param of the same
+// name unless the Ctx was built WithoutQueryFallback
 func (c Ctx) Param(key string) string {
-	value := helper.StringOrString(c.req.PathValue(key), c.req.URL.Query().Get(key))
+	value := c.req.PathValue(key)
+	if !c.noQueryFallback {
+		value = helper.StringOrString(value, c.req.URL.Query().Get(key))
+	}
+
 	// decode url encoded parameters
 	if strings.Contains(value, "%") {
 		decoded, err := url.QueryUnescape(value)
@@ -54,13 +61,47 @@ func (c Ctx) GetCookie(name string) string {
 	return cookie.Value
 }
 
+// FormValue returns the named field of a url-encoded or multipart form body,
+// falling back to the query string, limiting the body size like UnmarshalBody.
+func (c Ctx) FormValue(key string) string {
+	if c.req.Form == nil && c.req.Body != nil {
+		c.req.Body = http.MaxBytesReader(c.wr, c.req.Body, c.BodyLimit())
+	}
+
+	return c.req.FormValue(key)
+}
+
+// IsForm reports whether the request body is an url-encoded or multipart form
+func (c Ctx) IsForm() bool {
+	contentType := c.GetRequestHeader(web.HeaderContentType)
+
+	return strings.HasPrefix(contentType, web.MIMEApplicationForm) ||
+		strings.HasPrefix(contentType, web.MIMEMultipartForm)
+}
+
 // MaxBodySize is the default maximum request body size (1 MB)
 const MaxBodySize int64 = 1 << 20
 
+// WithBodyLimit returns a copy of Ctx whose UnmarshalBody and FormValue
+// read up to n bytes of the request body instead of MaxBodySize.
+func (c Ctx) WithBodyLimit(n int64) Ctx {
+	c.bodyLimit = n
+	return c
+}
+
+// BodyLimit returns the maximum request body size read by UnmarshalBody and FormValue
+func (c Ctx) BodyLimit() int64 {
+	if c.bodyLimit > 0 {
+		return c.bodyLimit
+	}
+
+	return MaxBodySize
+}
+
 // UnmarshalBody deserializes the JSON request body into dest,
 // limiting the body size to prevent abuse.
 func (c Ctx) UnmarshalBody(dest any) error {
-	limited := http.MaxBytesReader(c.wr, c.req.Body, MaxBodySize)
+	limited := http.MaxBytesReader(c.wr, c.req.Body, c.BodyLimit())
 	return json.NewDecoder(limited).Decode(dest)
 }
 
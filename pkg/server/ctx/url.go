@@ -0,0 +1,33 @@
+package ctx
+
+import "errors"
+
+// ErrNoURLBuilder is returned by URLFor when the Ctx was not created by a server
+var ErrNoURLBuilder = errors.New("no url builder")
+
+// URLBuilder builds the URL of a named route, it is implemented by server.Server
+type URLBuilder interface {
+	URL(name string, params ...any) (string, error)
+}
+
+// WithURLBuilder returns a copy of Ctx that builds URLs with b.
+// The server sets it on every request.
+func (c Ctx) WithURLBuilder(b URLBuilder) Ctx {
+	c.urls = b
+	return c
+}
+
+// URLFor builds the URL of the named route, see server.Server.URL for the params.
+//
+//	u, err := c.URLFor("user.show", "id", user.ID)
+//	if err != nil {
+//		return err
+//	}
+//	return c.Redirect(http.StatusSeeOther, u)
+func (c Ctx) URLFor(name string, params ...any) (string, error) {
+	if c.urls == nil {
+		return "", ErrNoURLBuilder
+	}
+
+	return c.urls.URL(name, params...)
+}
@@ -23,13 +23,25 @@ func defaultErrorHandler(c ctx.Ctx, err error) {
 	}
 }
 
-// returns a 404 error if the request path is different from "/"
-// it should be used with a "/" route because that route acts as a catch-all,
-// and overwrites the server previous cath-all.
-func notFoundMiddleware(c ctx.Ctx) error {
-	if c.Path() != "/" {
-		return servererror.ErrNotFound
-	}
+// NotFound sets the handler of the requests that match no route.
+// It runs after the server middleware and its error goes to the ErrorHandler.
+// The default handler returns servererror.ErrNotFound.
+func (s *Server) NotFound(h ctx.Handler) {
+	s.notFound = h
+}
+
+// MethodNotAllowed sets the handler of the requests whose path has routes, but none
+// for the request method. It runs after the server middleware with the Allow header
+// already set, and its error goes to the ErrorHandler.
+// The default handler returns servererror.ErrMethodNotAllowed.
+func (s *Server) MethodNotAllowed(h ctx.Handler) {
+	s.methodNotAllowed = h
+}
+
+func defaultNotFound(_ ctx.Ctx) error {
+	return servererror.ErrNotFound
+}
 
-	return c.Next()
+func defaultMethodNotAllowed(_ ctx.Ctx) error {
+	return servererror.ErrMethodNotAllowed
 }
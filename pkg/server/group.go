@@ -8,6 +8,7 @@ import (
 // Group represents a route group with a shared path prefix and middleware.
 type Group struct {
 	server     *Server
+	host       *Host
 	prefix     string
 	middleware []ctx.Handler
 }
@@ -28,9 +29,9 @@ func (s *Server) Group(prefix string, middleware ...ctx.Handler) *Group {
 
 // Route registers a route within this group.
 // The final path is prefix + path. The handler chain is:
-// server middleware -> group middleware -> handler.
-func (g *Group) Route(method web.Method, path string, h ctx.Handler) {
-	g.server.route(method, g.prefix+path, g.middleware, h)
+// server middleware -> group middleware -> route options -> handler.
+func (g *Group) Route(method web.Method, path string, h ctx.Handler, opts ...RouteOption) *Route {
+	return g.server.route(g, method, path, h, opts)
 }
 
 // Group creates a sub-group with an additional prefix and middleware.
@@ -44,6 +45,7 @@ func (g *Group) Group(prefix string, middleware ...ctx.Handler) *Group {
 
 	return &Group{
 		server:     g.server,
+		host:       g.host,
 		prefix:     g.prefix + prefix,
 		middleware: combined,
 	}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/health"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+)
+
+// Health returns the health check registry of the server, where the components
+// add their checks for the /readyz, /livez and /server/ready endpoints.
+//
+//	srv.Health().Add("database", health.Ping(db), health.WithTimeout(time.Second))
+func (s *Server) Health() *health.Registry {
+	return s.health
+}
+
+// HealthRoutes registers the health reports on the server routes:
+//
+//   - /livez: the JSON report of the liveness checks
+//   - /readyz: the JSON report of all the checks
+//
+// They answer 503 when a critical check is down. They are not registered by
+// default, as the paths may be taken by the routes of the application, see
+// Listener.HealthRoutes to serve them on another listener.
+func (s *Server) HealthRoutes() {
+	s.Route(web.MethodGet, "/livez", s.livez)
+	s.Route(web.MethodGet, "/readyz", s.readyz)
+}
+
+// registerHealth registers /server/ready, used by IsReady: "OK" when ready,
+// else 503. Readiness is down while shutting down too, so the load balancers drain.
+func (s *Server) registerHealth() {
+	s.Route(web.MethodGet, "/server/ready", s.ready)
+}
+
+func (s *Server) livez(c ctx.Ctx) error {
+	return sendReport(c, s.health.Liveness(c.Context()))
+}
+
+func (s *Server) readyz(c ctx.Ctx) error {
+	return sendReport(c, s.readiness(c))
+}
+
+func (s *Server) ready(c ctx.Ctx) error {
+	report := s.readiness(c)
+	if report.Status == health.StatusDown {
+		return c.WithStatus(http.StatusServiceUnavailable).SendString(report.Message)
+	}
+
+	return c.SendString("OK")
+}
+
+// readiness returns the readiness report, down without running the checks when shutting down
+func (s *Server) readiness(c ctx.Ctx) health.Report {
+	if s.ShuttingDown() {
+		return health.Report{Status: health.StatusDown, Message: "Shutting down", Checks: []health.Result{}}
+	}
+
+	report := s.health.Readiness(c.Context())
+	if report.Status == health.StatusDown {
+		report.Message = "Unhealthy"
+	}
+
+	return report
+}
+
+// sendReport sends the report as JSON, with 503 when it is down
+func sendReport(c ctx.Ctx, report health.Report) error {
+	if report.Status == health.StatusDown {
+		c = c.WithHeader(web.HeaderContentType, web.MIMEApplicationJSON).WithStatus(http.StatusServiceUnavailable)
+	}
+
+	return c.SendJSON(report)
+}
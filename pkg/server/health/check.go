@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorgefuertes/martian-stack/pkg/service/cache"
+)
+
+// cacheKeyPrefix prefixes the keys written and read back by the Cache check
+const cacheKeyPrefix = "martian:health:"
+
+var ErrLowDiskSpace = errors.New("low disk space")
+
+// Pinger is implemented by database.Database and the clients that can be pinged.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks a database or any Pinger.
+func Ping(p Pinger) CheckFunc {
+	return p.Ping
+}
+
+// Cache checks a cache service writing, reading back and deleting a key.
+// Each call uses its own key, so replicas sharing the cache do not interfere.
+func Cache(svc cache.Service) CheckFunc {
+	return func(ctx context.Context) error {
+		key := cacheKeyPrefix + uuid.NewString()
+		value := time.Now().Format(time.RFC3339Nano)
+		if err := svc.Set(ctx, key, value, time.Minute); err != nil {
+			return err
+		}
+
+		got, err := svc.GetString(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if got != value {
+			return fmt.Errorf("cache read %q, want %q", got, value)
+		}
+
+		return svc.Delete(ctx, key)
+	}
+}
+
+// DiskSpace checks that the filesystem of path has at least minFree bytes available.
+func DiskSpace(path string, minFree uint64) CheckFunc {
+	return func(context.Context) error {
+		free, err := freeSpace(path)
+		if err != nil {
+			return err
+		}
+
+		if free < minFree {
+			return fmt.Errorf("%w: %s has %d bytes free, want %d", ErrLowDiskSpace, path, free, minFree)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package health
+
+import "errors"
+
+// freeSpace is not supported out of unix systems
+func freeSpace(string) (uint64, error) {
+	return 0, errors.New("disk space check not supported on this platform")
+}
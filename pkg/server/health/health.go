@@ -0,0 +1,262 @@
+// Package health runs named health checks, with timeouts, criticality and cached
+// results, and reports their status as JSON.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Status is the status of a check or of a whole report
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Defaults of the checks
+const (
+	DefaultTimeout  = 2 * time.Second
+	DefaultCacheTTL = time.Second
+)
+
+var ErrDuplicateCheck = errors.New("duplicate health check")
+
+// errCheckTimeout is the cause of the context of a check when its own timeout expires
+var errCheckTimeout = errors.New("check timed out")
+
+// CheckFunc checks a component, returning an error when it is not healthy.
+// The context ends at the check timeout.
+type CheckFunc func(ctx context.Context) error
+
+// Option configures a check at registration time.
+type Option func(c *check)
+
+// WithTimeout sets how long the check can run, DefaultTimeout by default.
+func WithTimeout(d time.Duration) Option {
+	return func(c *check) {
+		c.timeout = d
+	}
+}
+
+// WithCacheTTL sets how long the result of the check is reused,
+// DefaultCacheTTL by default. Zero runs the check on every report.
+func WithCacheTTL(d time.Duration) Option {
+	return func(c *check) {
+		c.ttl = d
+	}
+}
+
+// NonCritical makes a failing check degrade the report instead of taking it down.
+func NonCritical() Option {
+	return func(c *check) {
+		c.critical = false
+	}
+}
+
+// Liveness makes the check part of the liveness report too. Keep them for the
+// failures only a restart fixes, a database down must not restart the service.
+func Liveness() Option {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+// Check is a named check with its options, for the components that declare
+// their checks to be added later, like the server modules.
+type Check struct {
+	Name    string
+	Func    CheckFunc
+	Options []Option
+}
+
+// check is a registered check with its cached result
+type check struct {
+	name     string
+	fn       CheckFunc
+	timeout  time.Duration
+	ttl      time.Duration
+	critical bool
+	liveness bool
+
+	// mu serializes the runs, so concurrent probes share one result
+	mu   sync.Mutex
+	last Result
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name      string    `json:"name"`
+	Status    Status    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMS float64   `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	Cached    bool      `json:"cached,omitempty"`
+}
+
+// Report is the outcome of the checks: down when a critical check is down,
+// degraded when only non critical ones are.
+type Report struct {
+	Status  Status   `json:"status"`
+	Message string   `json:"message,omitempty"`
+	Checks  []Result `json:"checks"`
+}
+
+// Registry holds the named checks of the components.
+type Registry struct {
+	mu     sync.RWMutex
+	checks []*check
+}
+
+func New() *Registry {
+	return &Registry{}
+}
+
+// Add registers a check, critical and only for readiness unless the options say
+// otherwise. It panics if the name is already registered.
+//
+//	srv.Health().Add("database", health.Ping(db))
+//	srv.Health().Add("cache", health.Cache(cacheSvc), health.NonCritical())
+//	srv.Health().Add("disk", health.DiskSpace("/var/data", 1<<30), health.WithCacheTTL(time.Minute))
+func (r *Registry) Add(name string, fn CheckFunc, opts ...Option) {
+	c := &check{name: name, fn: fn, timeout: DefaultTimeout, ttl: DefaultCacheTTL, critical: true}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.checks {
+		if existing.name == name {
+			panic(fmt.Errorf("%w: %s", ErrDuplicateCheck, name))
+		}
+	}
+
+	r.checks = append(r.checks, c)
+}
+
+// Has tells if a check with the name is registered
+func (r *Registry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.ContainsFunc(r.checks, func(c *check) bool {
+		return c.name == name
+	})
+}
+
+// Readiness runs all the checks, concurrently, and reports them in registration order.
+func (r *Registry) Readiness(ctx context.Context) Report {
+	return r.run(ctx, false)
+}
+
+// Liveness runs the checks added with the Liveness option.
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, true)
+}
+
+func (r *Registry) run(ctx context.Context, liveness bool) Report {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		if !liveness || c.liveness {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make([]Result, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		switch {
+		case res.Status == StatusUp:
+		case res.Critical:
+			report.Status = StatusDown
+		case report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// run returns the cached result while fresh, running the check otherwise
+func (c *check) run(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.last.CheckedAt.IsZero() && time.Since(c.last.CheckedAt) < c.ttl {
+		res := c.last
+		res.Cached = true
+
+		return res
+	}
+
+	checkCtx, cancel := context.WithTimeoutCause(ctx, c.timeout, errCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.call(checkCtx)
+	res := Result{
+		Name:      c.name,
+		Status:    StatusUp,
+		Critical:  c.critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt: start,
+	}
+
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+
+	// a result caused by the caller going away says nothing about the component
+	if ctx.Err() == nil {
+		c.last = res
+	}
+
+	return res
+}
+
+// call runs the check function, returning at the timeout even when it ignores the context
+func (c *check) call(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		done <- c.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if cause := context.Cause(ctx); !errors.Is(cause, errCheckTimeout) {
+			return cause
+		}
+
+		return fmt.Errorf("timed out after %s", c.timeout)
+	}
+}
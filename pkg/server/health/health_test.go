@@ -0,0 +1,130 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/health"
+	"github.com/jorgefuertes/martian-stack/pkg/service/cache/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pinger struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (p *pinger) Ping(context.Context) error {
+	p.calls.Add(1)
+	return p.err
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("status", func(t *testing.T) {
+		r := health.New()
+		r.Add("database", health.Ping(&pinger{}), health.Liveness())
+		r.Add("mailer", func(context.Context) error { return errors.New("smtp down") }, health.NonCritical())
+
+		report := r.Readiness(ctx)
+		assert.Equal(t, health.StatusDegraded, report.Status)
+		require.Len(t, report.Checks, 2)
+		assert.Equal(t, "database", report.Checks[0].Name)
+		assert.Equal(t, health.StatusUp, report.Checks[0].Status)
+		assert.True(t, report.Checks[0].Critical)
+		assert.Equal(t, health.StatusDown, report.Checks[1].Status)
+		assert.Equal(t, "smtp down", report.Checks[1].Error)
+
+		r.Add("queue", func(context.Context) error { return errors.New("unreachable") })
+		assert.Equal(t, health.StatusDown, r.Readiness(ctx).Status)
+
+		live := r.Liveness(ctx)
+		assert.Equal(t, health.StatusUp, live.Status)
+		require.Len(t, live.Checks, 1)
+		assert.Equal(t, "database", live.Checks[0].Name)
+
+		assert.PanicsWithError(t, "duplicate health check: queue", func() {
+			r.Add("queue", health.Ping(&pinger{}))
+		})
+	})
+
+	t.Run("cached results", func(t *testing.T) {
+		p := &pinger{}
+		r := health.New()
+		r.Add("database", health.Ping(p), health.WithCacheTTL(time.Hour))
+		r.Add("uncached", health.Ping(p), health.WithCacheTTL(0))
+
+		first := r.Readiness(ctx)
+		assert.False(t, first.Checks[0].Cached)
+		second := r.Readiness(ctx)
+		assert.True(t, second.Checks[0].Cached)
+		assert.Equal(t, first.Checks[0].CheckedAt, second.Checks[0].CheckedAt)
+		assert.False(t, second.Checks[1].Cached)
+		assert.Equal(t, int32(3), p.calls.Load())
+	})
+
+	t.Run("timeout and panic", func(t *testing.T) {
+		r := health.New()
+		r.Add("slow", func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}, health.WithTimeout(50*time.Millisecond))
+		r.Add("broken", func(context.Context) error { panic("nil pool") })
+
+		start := time.Now()
+		report := r.Readiness(ctx)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, "timed out after 50ms", report.Checks[0].Error)
+		assert.Equal(t, "panic: nil pool", report.Checks[1].Error)
+	})
+
+	t.Run("caller canceled", func(t *testing.T) {
+		r := health.New()
+		var calls atomic.Int32
+		r.Add("slow", func(ctx context.Context) error {
+			if calls.Add(1) == 1 {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		}, health.WithCacheTTL(time.Hour))
+
+		canceled, cancel := context.WithCancel(ctx)
+		time.AfterFunc(20*time.Millisecond, cancel)
+		report := r.Readiness(canceled)
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, context.Canceled.Error(), report.Checks[0].Error)
+
+		// the canceled result is not cached
+		report = r.Readiness(ctx)
+		assert.Equal(t, health.StatusUp, report.Status)
+		assert.False(t, report.Checks[0].Cached)
+	})
+}
+
+func TestChecks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("cache", func(t *testing.T) {
+		svc := memory.New()
+		t.Cleanup(func() { _ = svc.Close() })
+
+		require.NoError(t, health.Cache(svc)(ctx))
+		keys, err := svc.Keys(ctx, "martian:health*")
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+
+	t.Run("disk space", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, health.DiskSpace(dir, 1)(ctx))
+		require.ErrorIs(t, health.DiskSpace(dir, 1<<62)(ctx), health.ErrLowDiskSpace)
+		require.Error(t, health.DiskSpace("/does/not/exist", 1)(ctx))
+	})
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+)
+
+// Host is a virtual host: a route group matching the requests by their Host header,
+// with its own routes, middleware and not found handler.
+type Host struct {
+	server   *Server
+	group    *Group
+	pattern  string
+	re       *regexp.Regexp
+	params   []string
+	mux      *http.ServeMux
+	notFound ctx.Handler
+	listener string
+}
+
+// hostParam matches the {param} labels of a host pattern
+var hostParam = regexp.MustCompile(`\{([^}.]+)\}`)
+
+// Host creates a virtual host for the given host pattern and middleware. The pattern is
+// a host name, or a host name with {param} labels, captured as path params and read
+// with Ctx.Param:
+//
+//	api := srv.Host("api.example.com", apiMiddleware)
+//	api.Route(web.MethodGet, "/users", listUsers)  // handles GET api.example.com/users
+//
+//	tenants := srv.Host("{tenant}.example.com")
+//	tenants.Route(web.MethodGet, "/", home)        // c.Param("tenant") is "acme" for acme.example.com
+//
+// Requests are matched case-insensitively and without port, exact host names first
+// and then the patterns in registration order. Each host has its own routes, so
+// requests for a host never reach the routes registered on the server or on other
+// hosts. The chain is: server middleware -> host middleware -> route handler.
+// It panics if the pattern is already registered.
+func (s *Server) Host(pattern string, middleware ...ctx.Handler) *Host {
+	pattern = strings.ToLower(pattern)
+
+	s.endpointsMu.Lock()
+	defer s.endpointsMu.Unlock()
+
+	for _, h := range s.hosts {
+		if h.pattern == pattern {
+			panic(fmt.Sprintf("host %s already registered", pattern))
+		}
+	}
+
+	h := s.newHost(pattern, middleware)
+
+	if matches := hostParam.FindAllStringSubmatchIndex(pattern, -1); matches != nil {
+		var expr strings.Builder
+		last := 0
+		for _, m := range matches {
+			expr.WriteString(regexp.QuoteMeta(pattern[last:m[0]]))
+			expr.WriteString(`([^.]+)`)
+			h.params = append(h.params, pattern[m[2]:m[3]])
+			last = m[1]
+		}
+		expr.WriteString(regexp.QuoteMeta(pattern[last:]))
+		h.re = regexp.MustCompile(`^` + expr.String() + `$`)
+	}
+
+	s.hosts = append(s.hosts, h)
+
+	return h
+}
+
+// newHost creates a router with its own mux, for a Host or the routes of a named Listener
+func (s *Server) newHost(pattern string, middleware []ctx.Handler) *Host {
+	h := &Host{server: s, pattern: pattern, mux: http.NewServeMux()}
+	h.group = &Group{server: s, host: h, middleware: middleware}
+
+	// the requests of the host matching no route
+	h.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		s.serve(w, r, h.group.middleware, h.notFoundHandler())
+	})
+
+	return h
+}
+
+// Route registers a route on this host, like Group.Route.
+func (h *Host) Route(method web.Method, path string, handler ctx.Handler, opts ...RouteOption) *Route {
+	return h.group.Route(method, path, handler, opts...)
+}
+
+// Group creates a group with a path prefix and middleware on this host, like Group.Group.
+func (h *Host) Group(prefix string, middleware ...ctx.Handler) *Group {
+	return h.group.Group(prefix, middleware...)
+}
+
+// NotFound sets the handler of the requests to this host that match no route.
+// It runs after the server and host middleware, the server NotFound handler by default.
+func (h *Host) NotFound(handler ctx.Handler) {
+	h.server.endpointsMu.Lock()
+	defer h.server.endpointsMu.Unlock()
+
+	h.notFound = handler
+}
+
+// notFoundHandler returns the NotFound handler of the host, the server one when unset
+func (h *Host) notFoundHandler() ctx.Handler {
+	h.server.endpointsMu.RLock()
+	defer h.server.endpointsMu.RUnlock()
+
+	if h.notFound != nil {
+		return h.notFound
+	}
+
+	return h.server.notFound
+}
+
+// match reports whether the host name matches the host pattern,
+// setting the captured labels as path params of the request
+func (h *Host) match(name string, r *http.Request) bool {
+	if h.re == nil {
+		return name == h.pattern
+	}
+
+	m := h.re.FindStringSubmatch(name)
+	if m == nil {
+		return false
+	}
+
+	for i, param := range h.params {
+		r.SetPathValue(param, m[i+1])
+	}
+
+	return true
+}
+
+// serveHTTP passes the request to the mux of its host, or to the server mux when
+// it matches no Host, counting the in-flight requests
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
+	if h := s.matchHost(r); h != nil {
+		h.mux.ServeHTTP(w, r)
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// matchHost returns the Host for the request, exact host names first, nil when none matches
+func (s *Server) matchHost(r *http.Request) *Host {
+	s.endpointsMu.RLock()
+	defer s.endpointsMu.RUnlock()
+
+	if len(s.hosts) == 0 {
+		return nil
+	}
+
+	name := r.Host
+	if host, _, err := net.SplitHostPort(name); err == nil {
+		name = host
+	}
+	name = strings.ToLower(name)
+
+	for _, h := range s.hosts {
+		if h.re == nil && h.match(name, r) {
+			return h
+		}
+	}
+
+	for _, h := range s.hosts {
+		if h.re != nil && h.match(name, r) {
+			return h
+		}
+	}
+
+	return nil
+}
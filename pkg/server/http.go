@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+)
+
+// mountParam is the wildcard capturing the path below a Mount prefix
+const mountParam = "mount"
+
+// Handler returns the server as an http.Handler, with its hosts, routes and middleware,
+// to embed it into another mux or serve it with httptest:
+//
+//	ts := httptest.NewServer(srv.Handler())
+//	mux.Handle("/app/", http.StripPrefix("/app", srv.Handler()))
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+// Mount serves the requests under the prefix, for any method, with an http.Handler
+// seeing the path without the prefix. It runs after the server middleware and the route
+// options, and reads the request ID with ctx.RequestID(r.Context()).
+//
+//	srv.Mount("/debug/pprof", pprofMux)   // GET /debug/pprof/heap is served as /heap
+func (s *Server) Mount(prefix string, h http.Handler, opts ...RouteOption) *Route {
+	return s.mount(nil, prefix, h, opts)
+}
+
+// Mount serves the requests under the group prefix + prefix with an http.Handler,
+// like Server.Mount, running the group middleware.
+func (g *Group) Mount(prefix string, h http.Handler, opts ...RouteOption) *Route {
+	return g.server.mount(g, prefix, h, opts)
+}
+
+// Mount serves the requests to this host under the prefix with an http.Handler,
+// like Server.Mount, running the host middleware.
+func (h *Host) Mount(prefix string, handler http.Handler, opts ...RouteOption) *Route {
+	return h.group.Mount(prefix, handler, opts...)
+}
+
+// mount registers the ANY route of a mounted handler, the prefix can hold path params
+func (s *Server) mount(g *Group, prefix string, h http.Handler, opts []RouteOption) *Route {
+	path := strings.TrimSuffix(prefix, "/") + "/{" + mountParam + "...}"
+
+	return s.route(g, web.MethodAny, path, ctx.WrapHandler(stripMountPrefix(h)), opts)
+}
+
+// stripMountPrefix passes the request to h with the path below the mount prefix
+func stripMountPrefix(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = "/" + r.PathValue(mountParam)
+		r2.URL.RawPath = ""
+
+		h.ServeHTTP(w, r2)
+	})
+}
+
+// HTTPMiddleware adapts ctx middleware to a standard func(http.Handler) http.Handler
+// middleware, to use it on other muxes. The middleware run on a Ctx with the server
+// error handler and request ID, without the server middleware, and the next handler
+// runs at the end of their chain:
+//
+//	mux.Handle("/legacy/", srv.HTTPMiddleware(middleware.NewCors(corsOpts))(legacyHandler))
+func (s *Server) HTTPMiddleware(mw ...ctx.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			chain := make([]ctx.Handler, 0, len(mw)+1)
+			chain = append(chain, mw...)
+			chain = append(chain, ctx.WrapHandler(next))
+
+			s.run(w, r, chain)
+		})
+	}
+}
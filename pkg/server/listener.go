@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSocketInUse     = errors.New("unix socket in use")
+	ErrNotASocket      = errors.New("path exists and is not a unix socket")
+	ErrSystemdListener = errors.New("invalid systemd socket activation")
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation
+const listenFDsStart = 3
+
+// ListenUnix listens on a Unix socket at path with the given file mode. A stale socket
+// left by a previous run is removed, but it fails with ErrSocketInUse when another
+// process is still accepting on it. The socket file is removed when the listener closes.
+//
+//	l, err := server.ListenUnix("/run/myapp/http.sock", 0o660)
+//	srv, err := server.NewWithOptions(server.WithListener(l))
+func ListenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// removeStaleSocket removes the socket at path when nobody accepts connections on it
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%w: %s", ErrNotASocket, path)
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%w: %s", ErrSocketInUse, path)
+	}
+
+	return os.Remove(path)
+}
+
+var (
+	systemdOnce      sync.Once
+	systemdListeners []net.Listener
+	systemdErr       error
+)
+
+// SystemdListeners returns the listeners passed by systemd socket activation, in the
+// order of the unit sockets, or none when the process was not socket activated. The
+// LISTEN_* environment is consumed on the first call, so later calls return the same
+// listeners. Start and ListenAndShutdown serve on the first one automatically.
+func SystemdListeners() ([]net.Listener, error) {
+	systemdOnce.Do(func() {
+		systemdListeners, systemdErr = listenFDs()
+	})
+
+	return systemdListeners, systemdErr
+}
+
+// listenFDs builds the listeners of the LISTEN_FDS descriptors, when LISTEN_PID is this process
+func listenFDs() ([]net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	if pid == "" || fds == "" || pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	// the children of the process must not see the activation environment
+	for _, key := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(key)
+	}
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("%w: LISTEN_FDS=%q", ErrSystemdListener, fds)
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	for i := range n {
+		name := "LISTEN_FD_" + strconv.Itoa(listenFDsStart+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		// FileListener dups the descriptor, close the original
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		l, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+
+			return nil, fmt.Errorf("%w: %s: %w", ErrSystemdListener, name, err)
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+// Listen returns the listener Start serves on: the WithListener one, the first systemd
+// socket activation one or a new TCP listener on the server address. It also listens
+// on the addresses of the named listeners, see Server.Listener. Serve it with Serve or
+// ServeTLS, listening first reports errors like an address in use at once.
+func (s *Server) Listen() (net.Listener, error) {
+	l, err := s.listenMain()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.listenNamed(s.namedListeners()); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// listenMain returns the listener of the main server
+func (s *Server) listenMain() (net.Listener, error) {
+	if s.listener != nil {
+		return s.listener, nil
+	}
+
+	listeners, err := SystemdListeners()
+	if err != nil {
+		return nil, err
+	}
+	if len(listeners) > 0 {
+		return listeners[0], nil
+	}
+
+	addr := s.srv.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+// Serve accepts the connections of the listener, Unix sockets included, and of the
+// named listeners (see Server.Listener) until the server stops. Stop closes them all.
+// When one of them fails, the others are closed and Serve returns its error.
+func (s *Server) Serve(l net.Listener) error {
+	return s.serveAll(l, func(l net.Listener) error {
+		s.setActive(l, false)
+
+		return s.srv.Serve(l)
+	})
+}
+
+// ServeTLS is like Serve with TLS on the main listener, using the certificate and key
+// files, which can be empty when the TLS config has the certificates.
+func (s *Server) ServeTLS(l net.Listener, certFile, keyFile string) error {
+	return s.serveAll(l, func(l net.Listener) error {
+		s.setActive(l, true)
+
+		return s.srv.ServeTLS(l, certFile, keyFile)
+	})
+}
+
+// Addr returns the address the server is listening on, nil before it starts.
+// It is the real address when listening on port 0.
+func (s *Server) Addr() net.Addr {
+	s.activeMu.RLock()
+	defer s.activeMu.RUnlock()
+
+	if s.active == nil {
+		return nil
+	}
+
+	return s.active.Addr()
+}
+
+// setActive records the listener being served, for IsReady
+func (s *Server) setActive(l net.Listener, withTLS bool) {
+	s.activeMu.Lock()
+	defer s.activeMu.Unlock()
+
+	s.active = l
+	s.activeTLS = withTLS
+}
+
+// readyClient returns the client and base URL reaching the active listener,
+// false when the server is not listening
+func (s *Server) readyClient() (*http.Client, string, bool) {
+	s.activeMu.RLock()
+	defer s.activeMu.RUnlock()
+
+	if s.active == nil {
+		return nil, "", false
+	}
+
+	addr := s.active.Addr()
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, addr.Network(), addr.String())
+		},
+		// the server checks itself, whatever the certificate names
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}
+
+	scheme := "http"
+	if s.activeTLS {
+		scheme = "https"
+	}
+
+	host := "localhost"
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		host = net.JoinHostPort(tcp.IP.String(), strconv.Itoa(tcp.Port))
+	}
+
+	return &http.Client{Timeout: 2 * time.Second, Transport: transport}, scheme + "://" + host, true
+}
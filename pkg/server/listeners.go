@@ -0,0 +1,410 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+)
+
+// RedirectListener is the name of the listener added by RedirectHTTPS
+const RedirectListener = "redirect"
+
+// Listener is a named listener of the server on its own address, next to the main one,
+// like the HTTP port redirecting to HTTPS or an internal port for metrics and pprof.
+// It serves its own routes, or the http.Handler set with Handle, never the routes
+// of the server, and shuts down with the server.
+type Listener struct {
+	server   *Server
+	name     string
+	addr     string
+	router   *Host
+	handler  http.Handler
+	ln       net.Listener
+	certFile string
+	keyFile  string
+	withTLS  bool
+	srv      *http.Server
+	bound    net.Listener
+	active   net.Listener
+}
+
+// Listener creates a named listener on addr, served by Start and Serve with the main
+// one, with the same timeouts. Its routes run the server middleware, then the given
+// middleware, and the NotFound handler of the server unless it sets its own:
+//
+//	internal := srv.Listener("internal", "127.0.0.1:9090")
+//	internal.HealthRoutes()
+//	internal.Mount("/debug/pprof", pprofMux)
+//
+// It panics if the name is already registered.
+func (s *Server) Listener(name, addr string, middleware ...ctx.Handler) *Listener {
+	s.endpointsMu.Lock()
+	defer s.endpointsMu.Unlock()
+
+	for _, l := range s.listeners {
+		if l.name == name {
+			panic(fmt.Sprintf("listener %s already registered", name))
+		}
+	}
+
+	l := &Listener{server: s, name: name, addr: addr, router: s.newHost("@"+name, middleware)}
+	l.router.listener = name
+	l.srv = &http.Server{
+		Addr:              addr,
+		Handler:           http.HandlerFunc(l.serveHTTP),
+		ReadTimeout:       s.srv.ReadTimeout,
+		ReadHeaderTimeout: s.srv.ReadHeaderTimeout,
+		WriteTimeout:      s.srv.WriteTimeout,
+		IdleTimeout:       s.srv.IdleTimeout,
+		MaxHeaderBytes:    s.srv.MaxHeaderBytes,
+		BaseContext:       s.srv.BaseContext,
+		ErrorLog:          s.srv.ErrorLog,
+	}
+
+	s.listeners = append(s.listeners, l)
+
+	return l
+}
+
+// Name returns the name of the listener
+func (l *Listener) Name() string {
+	return l.name
+}
+
+// Route registers a route on this listener, like Group.Route.
+func (l *Listener) Route(method web.Method, path string, handler ctx.Handler, opts ...RouteOption) *Route {
+	return l.router.Route(method, path, handler, opts...)
+}
+
+// Group creates a group with a path prefix and middleware on this listener, like Group.Group.
+func (l *Listener) Group(prefix string, middleware ...ctx.Handler) *Group {
+	return l.router.Group(prefix, middleware...)
+}
+
+// Mount serves the requests to this listener under the prefix with an http.Handler,
+// like Server.Mount.
+func (l *Listener) Mount(prefix string, handler http.Handler, opts ...RouteOption) *Route {
+	return l.router.Mount(prefix, handler, opts...)
+}
+
+// NotFound sets the handler of the requests to this listener that match no route.
+func (l *Listener) NotFound(handler ctx.Handler) {
+	l.router.NotFound(handler)
+}
+
+// HealthRoutes registers the /livez, /readyz and /server/ready health endpoints
+// of the server on this listener, see Server.Health.
+func (l *Listener) HealthRoutes() {
+	l.Route(web.MethodGet, "/livez", l.server.livez)
+	l.Route(web.MethodGet, "/readyz", l.server.readyz)
+	l.Route(web.MethodGet, "/server/ready", l.server.ready)
+}
+
+// Handle serves every request to this listener with h, instead of its routes.
+// The server middleware does not run.
+func (l *Listener) Handle(h http.Handler) *Listener {
+	l.handler = h
+
+	return l
+}
+
+// TLS serves this listener with TLS, using the certificate and key files, which can
+// be empty when the TLS config of the server has the certificates.
+func (l *Listener) TLS(certFile, keyFile string) *Listener {
+	l.withTLS, l.certFile, l.keyFile = true, certFile, keyFile
+
+	return l
+}
+
+// ListenOn serves this listener on ln, like a Unix socket, instead of listening on its address.
+func (l *Listener) ListenOn(ln net.Listener) *Listener {
+	l.ln = ln
+
+	return l
+}
+
+// Addr returns the address the listener is listening on, nil before it starts.
+func (l *Listener) Addr() net.Addr {
+	l.server.activeMu.RLock()
+	defer l.server.activeMu.RUnlock()
+
+	if l.active == nil {
+		return nil
+	}
+
+	return l.active.Addr()
+}
+
+// listen returns the ListenOn listener, or a new TCP listener on the address
+func (l *Listener) listen() (net.Listener, error) {
+	if l.ln != nil {
+		return l.ln, nil
+	}
+
+	return net.Listen("tcp", l.addr)
+}
+
+// serve accepts the connections of ln until the server stops
+func (l *Listener) serve(ln net.Listener) error {
+	l.server.activeMu.Lock()
+	l.active = ln
+	l.server.activeMu.Unlock()
+
+	if !l.withTLS {
+		return l.srv.Serve(ln)
+	}
+
+	return l.srv.ServeTLS(ln, l.certFile, l.keyFile)
+}
+
+// serveHTTP passes the request to the handler or the routes of the listener,
+// counting the in-flight requests
+func (l *Listener) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	l.server.inFlight.Add(1)
+	defer l.server.inFlight.Add(-1)
+
+	if l.handler != nil {
+		l.handler.ServeHTTP(w, r)
+		return
+	}
+
+	l.router.mux.ServeHTTP(w, r)
+}
+
+// namedListeners returns the named listeners
+func (s *Server) namedListeners() []*Listener {
+	s.endpointsMu.RLock()
+	defer s.endpointsMu.RUnlock()
+
+	return slices.Clone(s.listeners)
+}
+
+// listenNamed listens on the addresses of the listeners not listening yet, so an
+// address in use fails at once. When one fails, it closes the others.
+func (s *Server) listenNamed(listeners []*Listener) error {
+	s.activeMu.Lock()
+	defer s.activeMu.Unlock()
+
+	opened := make([]*Listener, 0, len(listeners))
+	for _, l := range listeners {
+		if l.bound != nil {
+			continue
+		}
+
+		ln, err := l.listen()
+		if err != nil {
+			for _, l := range opened {
+				_ = l.bound.Close()
+				l.bound = nil
+			}
+
+			return fmt.Errorf("listener %s: %w", l.name, err)
+		}
+
+		l.bound = ln
+		opened = append(opened, l)
+	}
+
+	return nil
+}
+
+// takeBound returns the bound network listeners of the listeners,
+// which are not bound anymore
+func (s *Server) takeBound(listeners []*Listener) []net.Listener {
+	s.activeMu.Lock()
+	defer s.activeMu.Unlock()
+
+	lns := make([]net.Listener, 0, len(listeners))
+	for _, l := range listeners {
+		lns = append(lns, l.bound)
+		l.bound = nil
+	}
+
+	return lns
+}
+
+// serveAll serves the main listener with serveMain and the named listeners until the
+// server shuts down, returning http.ErrServerClosed, or until one of them fails, which
+// closes the others and returns its error.
+func (s *Server) serveAll(main net.Listener, serveMain func(net.Listener) error) error {
+	// the named listeners are listening already when main comes from Listen
+	listeners := s.namedListeners()
+	if err := s.listenNamed(listeners); err != nil {
+		_ = main.Close()
+		return err
+	}
+
+	lns := s.takeBound(listeners)
+	for _, l := range listeners {
+		// serving modifies the TLS config, each listener gets its own copy
+		if l.withTLS && l.srv.TLSConfig == nil {
+			l.srv.TLSConfig = s.srv.TLSConfig.Clone()
+		}
+	}
+
+	errCh := make(chan error, len(listeners)+1)
+	go func() {
+		errCh <- serveMain(main)
+	}()
+	for i, l := range listeners {
+		go func() {
+			if err := l.serve(lns[i]); !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("listener %s: %w", l.name, err)
+				return
+			}
+			errCh <- http.ErrServerClosed
+		}()
+	}
+
+	err := http.ErrServerClosed
+	for range len(listeners) + 1 {
+		e := <-errCh
+		if errors.Is(e, http.ErrServerClosed) || !errors.Is(err, http.ErrServerClosed) {
+			continue
+		}
+
+		err = e
+		for _, srv := range s.httpServers() {
+			_ = srv.Close()
+		}
+	}
+
+	return err
+}
+
+// httpServers returns the http.Server of the main listener and of the named ones
+func (s *Server) httpServers() []*http.Server {
+	s.endpointsMu.RLock()
+	defer s.endpointsMu.RUnlock()
+
+	servers := make([]*http.Server, 0, len(s.listeners)+1)
+	servers = append(servers, s.srv)
+	for _, l := range s.listeners {
+		servers = append(servers, l.srv)
+	}
+
+	return servers
+}
+
+// shutdownServers shuts down the main and the named listeners at the same time
+func (s *Server) shutdownServers(ctx context.Context) error {
+	servers := s.httpServers()
+	errs := make([]error, len(servers))
+
+	var wg sync.WaitGroup
+	for i, srv := range servers {
+		wg.Go(func() {
+			errs[i] = srv.Shutdown(ctx)
+		})
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// RedirectConfig configures the HTTP to HTTPS redirect of RedirectHTTPS.
+type RedirectConfig struct {
+	// Address of the plain HTTP listener
+	Addr string
+
+	// HTTPS port of the redirect locations, empty for the default 443
+	Port string
+
+	// Redirect status code, 308 by default, which keeps the method and the body
+	Code int
+
+	// Max age of the Strict-Transport-Security header of the HTTPS responses,
+	// so browsers skip the redirect next time. Zero sends no header.
+	HSTSMaxAge time.Duration
+
+	// Applies the HSTS policy to the subdomains too
+	HSTSIncludeSubdomains bool
+
+	// Asks for the inclusion in the HSTS preload list of the browsers
+	HSTSPreload bool
+}
+
+// DefaultRedirectConfig returns a config redirecting from :80 with a 308,
+// and a one year HSTS max age.
+func DefaultRedirectConfig() RedirectConfig {
+	return RedirectConfig{
+		Addr:       ":80",
+		Code:       http.StatusPermanentRedirect,
+		HSTSMaxAge: 365 * 24 * time.Hour,
+	}
+}
+
+// RedirectHTTPS adds the RedirectListener listener, answering every request on
+// the HTTP address with a redirect to the same URL on HTTPS, served by the main
+// listener with StartTLS:
+//
+//	srv.RedirectHTTPS(server.DefaultRedirectConfig())
+//	srv.ListenAndShutdownTLS(certFile, keyFile)
+//
+// With a HSTS max age, the server middleware adds the Strict-Transport-Security
+// header to the responses served over TLS, never to the plain HTTP ones.
+func (s *Server) RedirectHTTPS(cfg RedirectConfig) *Listener {
+	if cfg.Code == 0 {
+		cfg.Code = http.StatusPermanentRedirect
+	}
+
+	l := s.Listener(RedirectListener, cfg.Addr).Handle(redirectHandler(cfg))
+
+	if cfg.HSTSMaxAge > 0 {
+		s.Use(hsts(cfg))
+	}
+
+	return l
+}
+
+// redirectHandler redirects the requests to the same host and URI on HTTPS
+func redirectHandler(cfg RedirectConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.Trim(r.Host, "[]")
+		}
+
+		if host == "" {
+			http.Error(w, "missing host", http.StatusBadRequest)
+			return
+		}
+
+		if cfg.Port != "" && cfg.Port != "443" {
+			host = net.JoinHostPort(host, cfg.Port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), cfg.Code)
+	})
+}
+
+// hsts sets the Strict-Transport-Security header on the responses served over TLS
+func hsts(cfg RedirectConfig) ctx.Handler {
+	value := "max-age=" + strconv.FormatInt(int64(cfg.HSTSMaxAge/time.Second), 10)
+	if cfg.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if cfg.HSTSPreload {
+		value += "; preload"
+	}
+
+	return func(c ctx.Ctx) error {
+		if c.Request().TLS != nil {
+			c.SetHeader(web.HeaderStrictTransportSecurity, value)
+		}
+
+		return c.Next()
+	}
+}
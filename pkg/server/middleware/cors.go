@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
@@ -46,7 +47,7 @@ func NewCors(options CorsOptions) ctx.Handler {
 		c.SetHeader(web.HeaderAccessControlAllowOrigin, options.Origin)
 
 		if c.Method() == http.MethodOptions {
-			c.WithHeader(web.HeaderAccessControlAllowMethods, strings.Join(options.AllowedMethods, ", ")).
+			c.WithHeader(web.HeaderAccessControlAllowMethods, strings.Join(allowedMethods(c, options), ", ")).
 				WithHeader(web.HeaderAccessControlAllowHeaders, strings.Join(options.AllowedHeaders, ", ")).
 				WithStatus(http.StatusNoContent)
 
@@ -56,3 +57,22 @@ func NewCors(options CorsOptions) ctx.Handler {
 		return c.Next()
 	}
 }
+
+// allowedMethods returns the configured methods, restricted to the ones the route
+// supports when the server answers the OPTIONS request with an Allow header
+func allowedMethods(c ctx.Ctx, options CorsOptions) []string {
+	allow := c.GetResponseHeader(web.HeaderAllow)
+	if allow == "" {
+		return options.AllowedMethods
+	}
+
+	routeMethods := strings.Split(allow, ", ")
+	methods := make([]string, 0, len(options.AllowedMethods))
+	for _, m := range options.AllowedMethods {
+		if slices.Contains(routeMethods, m) {
+			methods = append(methods, m)
+		}
+	}
+
+	return methods
+}
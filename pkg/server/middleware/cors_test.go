@@ -78,3 +78,20 @@ func TestCors_CustomOptions(t *testing.T) {
 	// Must NOT contain method names in the headers
 	assert.NotContains(t, headers.Get(web.HeaderAccessControlAllowHeaders), http.MethodGet)
 }
+
+func TestCors_PreflightRouteMethods(t *testing.T) {
+	mw := middleware.NewCors(middleware.NewCorsOptions())
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodOptions, "/api/test", nil)
+	require.NoError(t, err)
+
+	// the server sets the methods of the route before running the middleware
+	w.Header().Set(web.HeaderAllow, "DELETE, OPTIONS, PUT")
+
+	err = mw(ctx.New(w, req, mw))
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "PUT, DELETE, OPTIONS", w.Result().Header.Get(web.HeaderAccessControlAllowMethods))
+}
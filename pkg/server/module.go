@@ -0,0 +1,199 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/health"
+)
+
+var (
+	ErrDuplicateModule     = errors.New("duplicate module")
+	ErrUnknownModule       = errors.New("unknown module dependency")
+	ErrModuleCycle         = errors.New("module dependency cycle")
+	ErrDuplicateMigration  = errors.New("duplicate migration version")
+	ErrModuleInstallFailed = errors.New("module install failed")
+)
+
+// Module packages a feature: its routes, migrations, middleware and health checks,
+// so a project adds it with a single Install call.
+type Module interface {
+	// Name identifies the module, for the dependencies of other modules
+	Name() string
+	// Dependencies are the names of the modules to install before this one
+	Dependencies() []string
+	// Register registers the routes of the module
+	Register(srv *Server) error
+	// Migrations are the database migrations of the module, see Server.Migrations
+	Migrations() []migration.Migration
+	// HealthChecks are added to the server health registry
+	HealthChecks() []health.Check
+	// Middleware is added to the global middleware, run by every route
+	Middleware() []ctx.Handler
+}
+
+// BaseModule implements the optional parts of a Module with nothing, embed it
+// and define Name, Register and the parts the module has.
+type BaseModule struct{}
+
+func (BaseModule) Dependencies() []string            { return nil }
+func (BaseModule) Register(*Server) error            { return nil }
+func (BaseModule) Migrations() []migration.Migration { return nil }
+func (BaseModule) HealthChecks() []health.Check      { return nil }
+func (BaseModule) Middleware() []ctx.Handler         { return nil }
+
+// Install installs the modules, each after its dependencies, which can be among
+// the given modules or installed before. For every module it calls Register, then
+// adds the middleware and the health checks and keeps the migrations for Migrations.
+//
+//	authModule := auth.NewModule(db, jwtService)
+//	err := srv.Install(authModule, admin.NewModule(db, authModule))
+//
+// Nothing is installed when a name is duplicated, a dependency is missing or
+// circular, or a migration version or a health check name is already used.
+// A failing Register stops the install, leaving the modules before it installed
+// and none of the middleware, checks and migrations of the failing one.
+func (s *Server) Install(modules ...Module) error {
+	s.modulesMu.Lock()
+	defer s.modulesMu.Unlock()
+
+	order, err := s.moduleOrder(modules)
+	if err != nil {
+		return err
+	}
+
+	// the module owning each migration version
+	versions := map[int64]string{}
+	for _, m := range slices.Concat(s.modules, order) {
+		for _, mig := range m.Migrations() {
+			if owner, ok := versions[mig.Version]; ok {
+				return fmt.Errorf("%w: %d of %s, used by %s", ErrDuplicateMigration, mig.Version, m.Name(), owner)
+			}
+			versions[mig.Version] = m.Name()
+		}
+	}
+
+	// the module owning each new health check
+	checks := map[string]string{}
+	for _, m := range order {
+		for _, c := range m.HealthChecks() {
+			if owner, ok := checks[c.Name]; ok {
+				return fmt.Errorf("%w: %s of %s, used by %s", health.ErrDuplicateCheck, c.Name, m.Name(), owner)
+			}
+			if s.health.Has(c.Name) {
+				return fmt.Errorf("%w: %s of %s, already registered", health.ErrDuplicateCheck, c.Name, m.Name())
+			}
+			checks[c.Name] = m.Name()
+		}
+	}
+
+	for _, m := range order {
+		if err := m.Register(s); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrModuleInstallFailed, m.Name(), err)
+		}
+
+		s.Use(m.Middleware()...)
+
+		for _, c := range m.HealthChecks() {
+			s.health.Add(c.Name, c.Func, c.Options...)
+		}
+
+		s.migrations = append(s.migrations, m.Migrations()...)
+		s.modules = append(s.modules, m)
+	}
+
+	return nil
+}
+
+// Modules returns the names of the installed modules, in install order
+func (s *Server) Modules() []string {
+	s.modulesMu.Lock()
+	defer s.modulesMu.Unlock()
+
+	names := make([]string, 0, len(s.modules))
+	for _, m := range s.modules {
+		names = append(names, m.Name())
+	}
+
+	return names
+}
+
+// Migrations returns the migrations of the installed modules, to register in
+// the migrator with the ones of the project:
+//
+//	migrator.RegisterMultiple(srv.Migrations())
+func (s *Server) Migrations() []migration.Migration {
+	s.modulesMu.Lock()
+	defer s.modulesMu.Unlock()
+
+	return append([]migration.Migration(nil), s.migrations...)
+}
+
+// moduleOrder sorts the modules so each one follows its dependencies
+func (s *Server) moduleOrder(modules []Module) ([]Module, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+
+	state := make(map[string]int, len(s.modules)+len(modules))
+	for _, m := range s.modules {
+		state[m.Name()] = done
+	}
+
+	byName := make(map[string]Module, len(modules))
+	for _, m := range modules {
+		if _, ok := state[m.Name()]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateModule, m.Name())
+		}
+		if _, ok := byName[m.Name()]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateModule, m.Name())
+		}
+		byName[m.Name()] = m
+	}
+
+	order := make([]Module, 0, len(modules))
+
+	var visit func(m Module, path []string) error
+	visit = func(m Module, path []string) error {
+		switch state[m.Name()] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrModuleCycle, strings.Join(append(path, m.Name()), " -> "))
+		}
+
+		state[m.Name()] = visiting
+		for _, name := range m.Dependencies() {
+			if state[name] == done {
+				continue
+			}
+
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("%w: %s depends on %s", ErrUnknownModule, m.Name(), name)
+			}
+
+			if err := visit(dep, append(path, m.Name())); err != nil {
+				return err
+			}
+		}
+		state[m.Name()] = done
+		order = append(order, m)
+
+		return nil
+	}
+
+	for _, m := range modules {
+		if err := visit(m, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidOption is returned by NewWithOptions when an option value is not valid
+var ErrInvalidOption = errors.New("invalid server option")
+
+// Defaults of NewWithOptions
+const (
+	DefaultAddr            = "localhost:8080"
+	DefaultTimeout         = 30 * time.Second
+	DefaultIdleTimeout     = 2 * time.Minute
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// options holds the http.Server tuning and the shutdown timeout
+type options struct {
+	addr              string
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	shutdownTimeout   time.Duration
+	drainDelay        time.Duration
+	maxHeaderBytes    int
+	baseContext       context.Context
+	errorLog          *slog.Logger
+	tlsConfig         *tls.Config
+	listener          net.Listener
+}
+
+// Option configures the server built by NewWithOptions.
+//
+//	srv, err := server.NewWithOptions(
+//		server.WithAddr(":8080"),
+//		server.WithReadHeaderTimeout(5*time.Second),
+//		server.WithWriteTimeout(time.Minute),
+//		server.WithErrorLog(l.From("server", "http")),
+//	)
+type Option func(o *options) error
+
+func defaultOptions() options {
+	return options{
+		addr:              DefaultAddr,
+		readTimeout:       DefaultTimeout,
+		readHeaderTimeout: DefaultTimeout,
+		writeTimeout:      DefaultTimeout,
+		idleTimeout:       DefaultIdleTimeout,
+		shutdownTimeout:   DefaultShutdownTimeout,
+	}
+}
+
+// WithAddr sets the host:port address to listen on, the host can be empty
+// to listen on all the interfaces.
+func WithAddr(addr string) Option {
+	return func(o *options) error {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("%w: address %q: %w", ErrInvalidOption, addr, err)
+		}
+
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return fmt.Errorf("%w: address %q: invalid port", ErrInvalidOption, addr)
+		}
+
+		o.addr = addr
+
+		return nil
+	}
+}
+
+// WithReadTimeout sets the maximum duration to read the whole request, body included.
+// Zero means no timeout.
+func WithReadTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		return setDuration(&o.readTimeout, "read timeout", d)
+	}
+}
+
+// WithReadHeaderTimeout sets the maximum duration to read the request headers.
+// Zero means the read timeout.
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		return setDuration(&o.readHeaderTimeout, "read header timeout", d)
+	}
+}
+
+// WithWriteTimeout sets the maximum duration to write the response, from the end of
+// the request headers read. Zero means no timeout.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		return setDuration(&o.writeTimeout, "write timeout", d)
+	}
+}
+
+// WithIdleTimeout sets the maximum duration to wait for the next request on a
+// keep-alive connection. Zero means the read timeout.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		return setDuration(&o.idleTimeout, "idle timeout", d)
+	}
+}
+
+// WithShutdownTimeout sets the maximum duration Stop waits for the active
+// requests to finish.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return fmt.Errorf("%w: shutdown timeout %s must be positive", ErrInvalidOption, d)
+		}
+
+		o.shutdownTimeout = d
+
+		return nil
+	}
+}
+
+// WithDrainDelay sets how long Shutdown keeps serving with /server/ready answering
+// 503 before closing the listeners, so the load balancers stop sending requests.
+// It must be shorter than the shutdown timeout. Zero, the default, closes them at once.
+func WithDrainDelay(d time.Duration) Option {
+	return func(o *options) error {
+		return setDuration(&o.drainDelay, "drain delay", d)
+	}
+}
+
+// WithMaxHeaderBytes sets the maximum size of the request headers,
+// http.DefaultMaxHeaderBytes by default.
+func WithMaxHeaderBytes(n int) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("%w: max header bytes %d must be positive", ErrInvalidOption, n)
+		}
+
+		o.maxHeaderBytes = n
+
+		return nil
+	}
+}
+
+// WithBaseContext sets the parent context of the request contexts,
+// to carry values or cancel them all.
+func WithBaseContext(baseCtx context.Context) Option {
+	return func(o *options) error {
+		if baseCtx == nil {
+			return fmt.Errorf("%w: nil base context", ErrInvalidOption)
+		}
+
+		o.baseContext = baseCtx
+
+		return nil
+	}
+}
+
+// WithErrorLog sends the errors of the http.Server, like the TLS handshake or
+// connection errors, to the logger at error level instead of the standard logger.
+func WithErrorLog(l *slog.Logger) Option {
+	return func(o *options) error {
+		if l == nil {
+			return fmt.Errorf("%w: nil error logger", ErrInvalidOption)
+		}
+
+		o.errorLog = l
+
+		return nil
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used by StartTLS and
+// ListenAndShutdownTLS, like SetTLSConfig.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) error {
+		if cfg == nil {
+			return fmt.Errorf("%w: nil TLS config", ErrInvalidOption)
+		}
+
+		o.tlsConfig = cfg
+
+		return nil
+	}
+}
+
+// WithListener makes Start, StartTLS and the ListenAndShutdown variants serve on the
+// listener instead of the address, like a Unix socket from ListenUnix.
+func WithListener(l net.Listener) Option {
+	return func(o *options) error {
+		if l == nil {
+			return fmt.Errorf("%w: nil listener", ErrInvalidOption)
+		}
+
+		o.listener = l
+
+		return nil
+	}
+}
+
+// setDuration sets a timeout, which can not be negative
+func setDuration(dest *time.Duration, name string, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("%w: %s %s can not be negative", ErrInvalidOption, name, d)
+	}
+
+	*dest = d
+
+	return nil
+}
+
+// validate checks the options that depend on each other
+func (o options) validate() error {
+	if o.readTimeout > 0 && o.readHeaderTimeout > o.readTimeout {
+		return fmt.Errorf("%w: read header timeout %s exceeds the read timeout %s",
+			ErrInvalidOption, o.readHeaderTimeout, o.readTimeout)
+	}
+
+	if o.drainDelay >= o.shutdownTimeout {
+		return fmt.Errorf("%w: drain delay %s must be shorter than the shutdown timeout %s",
+			ErrInvalidOption, o.drainDelay, o.shutdownTimeout)
+	}
+
+	return nil
+}
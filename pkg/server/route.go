@@ -1,53 +1,304 @@
 package server
 
 import (
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/jorgefuertes/martian-stack/pkg/helper"
 	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
 	"github.com/jorgefuertes/martian-stack/pkg/server/web"
 )
 
+// Route is a registered route. Name it to build its URL with Server.URL or Ctx.URLFor.
+type Route struct {
+	server         *Server
+	host           *Host
+	method         web.Method
+	path           string
+	name           string
+	prefix         string
+	static         string
+	handler        ctx.Handler
+	extra          []ctx.Handler
+	description    string
+	tags           []string
+	auth           bool
+	authMiddleware []ctx.Handler
+	middleware     []ctx.Handler
+	timeout        time.Duration
+	maxBodySize    int64
+}
+
+// endpoint holds the routes registered for a path pattern, by method.
+// The pattern is registered once on the mux and the endpoint dispatches by method,
+// so it knows the allowed methods to answer 405 and OPTIONS.
+type endpoint struct {
+	pattern string
+	host    *Host
+	routes  map[web.Method]routeHandler
+}
+
+// routeHandler is the handler of a route with its middleware and param constraints.
+// group is the host and group part of extra, without the route options.
+type routeHandler struct {
+	handler     ctx.Handler
+	extra       []ctx.Handler
+	group       []ctx.Handler
+	constraints constraints
+}
+
 // Route registers a route handler for the given method and path.
-// Path params can be defined as :param or {param}.
-func (s *Server) Route(method web.Method, path string, h ctx.Handler) {
-	s.route(method, path, nil, h)
+// Path params can be defined as :param or {param}, and constrained with
+// {param:constraint}, where constraint is int, uint, uuid, alpha, alnum or a regexp:
+//
+//	srv.Route(web.MethodGet, "/users/{id:uuid}", showUser)
+//	srv.Route(web.MethodGet, "/posts/{slug:[a-z0-9-]+}", showPost)
+//
+// Requests whose params do not satisfy the constraints get the NotFound handler.
+//
+// Requests with a method not registered for the path get a 405 error with an Allow
+// header, OPTIONS requests are answered with the Allow header, after the host and
+// group middleware of the path, like CORS, and HEAD requests run the GET handler
+// without writing the body, unless they have their own routes.
+// The routes of a path must use the same param names.
+//
+// Options add route level middleware, a timeout, a body size limit or a name, tags and
+// a description, see RouteOption. The handler chain is: server middleware -> group
+// middleware -> route options -> handler.
+func (s *Server) Route(method web.Method, path string, h ctx.Handler, opts ...RouteOption) *Route {
+	return s.route(nil, method, path, h, opts)
 }
 
-// route is the internal route registration that supports optional extra middleware
-// inserted between server-level middleware and the handler.
-func (s *Server) route(method web.Method, path string, extra []ctx.Handler, h ctx.Handler) {
+// route is the internal route registration, the group middleware, when given,
+// runs between the server middleware and the route options.
+func (s *Server) route(g *Group, method web.Method, path string, h ctx.Handler, opts []RouteOption) *Route {
 	if !web.IsValidMethod(method) {
 		method = web.MethodGet
 	}
 
-	if !web.IsMethodAny(method) {
-		path = method.String() + " " + path
+	r := &Route{server: s, method: method, handler: h}
+	var group []ctx.Handler
+	if g != nil {
+		r.host = g.host
+		r.prefix = g.prefix
+		path = g.prefix + path
+		r.extra = append(r.extra, g.middleware...)
+		group = g.middleware
+	}
+
+	for _, opt := range opts {
+		opt(r)
 	}
 
-	// replace :param with {param}
+	// replace :param with {param}, after removing the constraints that hold colons
+	path, cs := parseConstraints(path)
 	path = helper.ReplacePathParams(path)
 
-	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		// build chain: server middleware + [notFound] + extra middleware + handler
-		chain := make([]ctx.Handler, 0, len(s.handlers)+len(extra)+2)
-		chain = append(chain, s.handlers...)
+	// "/" would match every path, make it match only the root
+	if path == "/" {
+		path = "/{$}"
+	}
+
+	r.path = path
+	r.extra = append(r.extra, r.chain()...)
+	s.register(r, group, cs)
+
+	if r.name != "" {
+		r.Name(r.name)
+	}
+
+	return r
+}
+
+// register adds the route to the endpoint of its path, registering the endpoint on the mux
+func (s *Server) register(r *Route, group []ctx.Handler, cs constraints) {
+	path, method := r.path, r.method
+
+	// hosts have their own mux, so their endpoints are keyed by host and path
+	mux, key := s.mux, path
+	if r.host != nil {
+		mux, key = r.host.mux, r.host.pattern+path
+	}
+
+	s.endpointsMu.Lock()
+	defer s.endpointsMu.Unlock()
+
+	ep, ok := s.endpoints[key]
+	if !ok {
+		ep = &endpoint{pattern: path, host: r.host, routes: map[web.Method]routeHandler{}}
+		s.endpoints[key] = ep
+		mux.HandleFunc(path, s.dispatch(ep))
+	}
+
+	if _, ok := ep.routes[method]; ok {
+		panic(fmt.Sprintf("route %s %s already registered", method, key))
+	}
+
+	ep.routes[method] = routeHandler{handler: r.handler, extra: r.extra, group: group, constraints: cs}
+	s.routes = append(s.routes, r)
+}
+
+// dispatch returns the mux handler of an endpoint, which picks the route for the
+// request method, answering OPTIONS and calling the MethodNotAllowed handler when there is none
+func (s *Server) dispatch(ep *endpoint) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.endpointsMu.RLock()
+		route, allow, found := ep.match(r)
+		// the requests not reaching a route run the host middleware, and the
+		// automatic OPTIONS reply the group middleware too, like CORS
+		extra := route.extra
+		switch {
+		case route.handler == nil && found && r.Method == http.MethodOptions:
+			extra = ep.optionsMiddleware(r)
+		case route.handler == nil && ep.host != nil:
+			extra = ep.host.group.middleware
+		}
+		s.endpointsMu.RUnlock()
+
+		h := route.handler
+		switch {
+		case !found && ep.host != nil:
+			h = ep.host.notFoundHandler()
+		case !found:
+			h = s.notFound
+		case h == nil && r.Method == http.MethodOptions:
+			w.Header().Set(web.HeaderAllow, allow)
+			h = func(c ctx.Ctx) error {
+				c.WithStatus(http.StatusNoContent)
+				return nil
+			}
+		case h == nil:
+			w.Header().Set(web.HeaderAllow, allow)
+			h = s.methodNotAllowed
+		case r.Method == http.MethodHead:
+			w = headResponseWriter{w}
+		}
+
+		s.serve(w, r, extra, h)
+	}
+}
+
+// serve runs the chain: server middleware + extra middleware + handler,
+// passing its error to the error handler
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, extra []ctx.Handler, h ctx.Handler) {
+	chain := make([]ctx.Handler, 0, len(s.handlers)+len(extra)+1)
+	chain = append(chain, s.handlers...)
+	chain = append(chain, extra...)
+	chain = append(chain, h)
+
+	s.run(w, r, chain)
+}
+
+// run executes the handler chain on a new Ctx, passing its error to the error handler
+// unless it was already handled down the chain
+func (s *Server) run(w http.ResponseWriter, r *http.Request, chain []ctx.Handler) {
+	c := ctx.New(w, r, chain...).WithURLBuilder(s).WithErrorHandler(s.errorHandler)
+	if s.noQueryFallback {
+		c = c.WithoutQueryFallback()
+	}
+
+	// propagate request ID to response for tracing
+	c.SetHeader(web.HeaderXRequestID, c.ID())
+
+	// execute all the handlers in a "next" chain
+	if err := c.Next(); err != nil {
+		c.HandleError(err)
+	}
+}
+
+// match returns the route for the request method, falling back to ANY and, for HEAD,
+// to GET. Only the routes whose constraints the request satisfies are considered,
+// found is false when there is none. When no route matches the method, it returns
+// an empty route and the Allow header value.
+func (ep *endpoint) match(r *http.Request) (route routeHandler, allow string, found bool) {
+	method := web.Method(r.Method)
+
+	candidates := []web.Method{method}
+	if method == web.MethodHead {
+		candidates = append(candidates, web.MethodGet)
+	}
+	candidates = append(candidates, web.MethodAny)
 
-		if helper.IsRootPath(path) {
-			chain = append(chain, notFoundMiddleware)
+	for _, m := range candidates {
+		if route, ok := ep.routes[m]; ok && route.constraints.match(r) {
+			return route, "", true
+		}
+	}
+
+	methods := []string{web.MethodOptions.String()}
+	for m, route := range ep.routes {
+		if !route.constraints.match(r) {
+			continue
 		}
 
-		chain = append(chain, extra...)
-		chain = append(chain, h)
+		found = true
+		methods = append(methods, m.String())
+		if m == web.MethodGet {
+			methods = append(methods, web.MethodHead.String())
+		}
+	}
+	slices.Sort(methods)
 
-		c := ctx.New(w, r, chain...)
+	return routeHandler{}, strings.Join(slices.Compact(methods), ", "), found
+}
 
-		// propagate request ID to response for tracing
-		c.SetHeader(web.HeaderXRequestID, c.ID())
+// optionsMiddleware returns the host and group middleware of the automatic OPTIONS
+// reply: the one of the route for the method of a CORS preflight request, or else of
+// the first route, by method, the request satisfies the constraints of
+func (ep *endpoint) optionsMiddleware(r *http.Request) []ctx.Handler {
+	methods := slices.Sorted(maps.Keys(ep.routes))
+	if m := web.Method(r.Header.Get(web.HeaderAccessControlRequestMethod)); m != "" {
+		methods = slices.Insert(methods, 0, m)
+	}
 
-		// execute all the handlers in a "next" chain
-		if err := c.Next(); err != nil {
-			s.errorHandler(c, err)
+	for _, m := range methods {
+		if route, ok := ep.routes[m]; ok && route.constraints.match(r) {
+			return route.group
 		}
-	})
+	}
+
+	if ep.host != nil {
+		return ep.host.group.middleware
+	}
+
+	return nil
+}
+
+// headResponseWriter discards the body of the GET handlers answering HEAD requests
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Flush keeps streaming working through the wrapper
+func (w headResponseWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap gives http.ResponseController access to the original writer
+func (w headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Name names the route, so its URL can be built with Server.URL or Ctx.URLFor.
+// It panics if the name is already taken, like http.ServeMux does with conflicting patterns.
+func (r *Route) Name(name string) *Route {
+	r.server.namesMu.Lock()
+	defer r.server.namesMu.Unlock()
+
+	if _, ok := r.server.names[name]; ok {
+		panic(fmt.Errorf("%w: %s", ErrDuplicateRouteName, name))
+	}
+
+	r.name = name
+	r.server.names[name] = r
+
+	return r
 }
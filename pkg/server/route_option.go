@@ -0,0 +1,98 @@
+package server
+
+import (
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/middleware"
+)
+
+// RouteOption configures a route at registration time.
+//
+//	srv.Route(web.MethodPost, "/uploads", upload,
+//		server.WithName("upload"),
+//		server.WithAuth(authMw.RequireAuth()),
+//		server.WithTimeout(time.Minute),
+//		server.WithMaxBodySize(32<<20),
+//	)
+type RouteOption func(r *Route)
+
+// WithName names the route, like Route.Name.
+func WithName(name string) RouteOption {
+	return func(r *Route) {
+		r.name = name
+	}
+}
+
+// WithMiddleware adds middleware that runs only for this route,
+// after the server and group middleware.
+func WithMiddleware(mw ...ctx.Handler) RouteOption {
+	return func(r *Route) {
+		r.middleware = append(r.middleware, mw...)
+	}
+}
+
+// WithAuth marks the route as requiring authentication, running the given
+// middleware (e.g. auth RequireAuth and RequireRole) before the route middleware.
+func WithAuth(mw ...ctx.Handler) RouteOption {
+	return func(r *Route) {
+		r.auth = true
+		r.authMiddleware = append(r.authMiddleware, mw...)
+	}
+}
+
+// WithTimeout cancels the request context of the route after d,
+// answering 503 when the handler does not finish in time.
+func WithTimeout(d time.Duration) RouteOption {
+	return func(r *Route) {
+		r.timeout = d
+	}
+}
+
+// WithMaxBodySize changes the request body size read by Ctx.UnmarshalBody
+// and Ctx.FormValue in this route, ctx.MaxBodySize by default.
+func WithMaxBodySize(n int64) RouteOption {
+	return func(r *Route) {
+		r.maxBodySize = n
+	}
+}
+
+// WithTags tags the route, for documentation and introspection.
+func WithTags(tags ...string) RouteOption {
+	return func(r *Route) {
+		r.tags = append(r.tags, tags...)
+	}
+}
+
+// WithDescription describes the route, for documentation and introspection.
+func WithDescription(description string) RouteOption {
+	return func(r *Route) {
+		r.description = description
+	}
+}
+
+// chain returns the route level handlers: body limit, timeout, auth and
+// route middleware, in that order.
+func (r *Route) chain() []ctx.Handler {
+	var chain []ctx.Handler
+
+	if r.maxBodySize > 0 {
+		chain = append(chain, bodyLimit(r.maxBodySize))
+	}
+
+	if r.timeout > 0 {
+		chain = append(chain, middleware.NewTimeout(r.timeout))
+	}
+
+	chain = append(chain, r.authMiddleware...)
+	chain = append(chain, r.middleware...)
+
+	return chain
+}
+
+// bodyLimit sets the request body size read by Ctx.UnmarshalBody and Ctx.FormValue
+func bodyLimit(n int64) ctx.Handler {
+	return func(c ctx.Ctx) error {
+		return c.WithBodyLimit(n).Next()
+	}
+}
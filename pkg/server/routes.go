@@ -0,0 +1,177 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/view"
+)
+
+// RouteInfo describes a registered route, as returned by Server.Routes.
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Host        string   `json:"host,omitempty"`
+	Listener    string   `json:"listener,omitempty"`
+	Pattern     string   `json:"pattern"`
+	Name        string   `json:"name,omitempty"`
+	Prefix      string   `json:"prefix,omitempty"`
+	Handler     string   `json:"handler,omitempty"`
+	Middleware  []string `json:"middleware,omitempty"`
+	Static      string   `json:"static,omitempty"`
+	Auth        bool     `json:"auth,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Routes returns the registered routes sorted by listener, host, pattern and method,
+// including the Static and StaticFS mounts. Middleware lists the server, group and route
+// middleware names in the order they run. Static mounts are served by the mux directly,
+// so they have no middleware and Static holds their directory or fs.FS type.
+func (s *Server) Routes() []RouteInfo {
+	s.endpointsMu.RLock()
+	defer s.endpointsMu.RUnlock()
+
+	routes := make([]RouteInfo, 0, len(s.routes))
+	for _, r := range s.routes {
+		info := RouteInfo{
+			Method:      r.method.String(),
+			Pattern:     r.path,
+			Name:        r.name,
+			Prefix:      r.prefix,
+			Static:      r.static,
+			Auth:        r.auth,
+			Tags:        r.tags,
+			Description: r.description,
+		}
+
+		switch {
+		case r.host != nil && r.host.listener != "":
+			info.Listener = r.host.listener
+		case r.host != nil:
+			info.Host = r.host.pattern
+		}
+
+		if r.handler != nil {
+			info.Handler = handlerName(r.handler)
+			for _, h := range slices.Concat(s.handlers, r.extra) {
+				info.Middleware = append(info.Middleware, handlerName(h))
+			}
+		}
+
+		routes = append(routes, info)
+	}
+
+	slices.SortStableFunc(routes, func(a, b RouteInfo) int {
+		return cmp.Or(
+			strings.Compare(a.Listener, b.Listener),
+			strings.Compare(a.Host, b.Host),
+			strings.Compare(a.Pattern, b.Pattern),
+			strings.Compare(a.Method, b.Method),
+		)
+	})
+
+	return routes
+}
+
+// PrintRoutes writes the route table to w, one route per line.
+func (s *Server) PrintRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATTERN\tNAME\tHANDLER\tMIDDLEWARE")
+
+	for _, r := range routeRows(s.Routes()) {
+		fmt.Fprintln(tw, strings.Join(r, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// RoutesHandler returns a handler rendering the route table as JSON, plain text or
+// HTML, depending on the Accept header. Register it on a debug path, behind auth
+// in production:
+//
+//	srv.Route(web.MethodGet, "/debug/routes", srv.RoutesHandler(), server.WithAuth(requireAdmin))
+func (s *Server) RoutesHandler() ctx.Handler {
+	return func(c ctx.Ctx) error {
+		routes := s.Routes()
+
+		if c.AcceptsJSON() {
+			return c.SendJSON(routes)
+		}
+
+		if c.AcceptsPlainText() {
+			var b strings.Builder
+			if err := s.PrintRoutes(&b); err != nil {
+				return err
+			}
+
+			return c.SendString(b.String())
+		}
+
+		header := []string{"Method", "Pattern", "Name", "Handler", "Middleware"}
+
+		return c.Render(view.Table("Routes", header, routeRows(routes)))
+	}
+}
+
+// routeRows returns the printable columns of the routes
+func routeRows(routes []RouteInfo) [][]string {
+	rows := make([][]string, 0, len(routes))
+	for _, r := range routes {
+		handler := r.Handler
+		if r.Static != "" {
+			handler = "static " + r.Static
+		}
+
+		middleware := strings.Join(r.Middleware, ", ")
+		if middleware == "" {
+			middleware = "-"
+		}
+
+		name := r.Name
+		if name == "" {
+			name = "-"
+		}
+
+		pattern := r.Host + r.Pattern
+		if r.Listener != "" {
+			pattern = "@" + r.Listener + " " + pattern
+		}
+
+		rows = append(rows, []string{r.Method, pattern, name, handler, middleware})
+	}
+
+	return rows
+}
+
+// funcSuffix matches the suffixes of closures (.func1, .func1.2) and method values (-fm)
+var funcSuffix = regexp.MustCompile(`(\.func\d+(\.\d+)*|-fm)$`)
+
+// handlerName returns the package qualified name of the function behind a handler,
+// naming closures after the function that returns them, like middleware.NewCors
+func handlerName(h ctx.Handler) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(h).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+
+	name := fn.Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+
+	for {
+		trimmed := funcSuffix.ReplaceAllString(name, "")
+		if trimmed == name {
+			return name
+		}
+		name = trimmed
+	}
+}
@@ -3,63 +3,154 @@ package server
 import (
 	"context"
 	"crypto/tls"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
 	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
-	"github.com/jorgefuertes/martian-stack/pkg/server/servererror"
-	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/jorgefuertes/martian-stack/pkg/server/health"
 )
 
 type Server struct {
-	srv          *http.Server
-	mux          *http.ServeMux
-	handlers     []ctx.Handler
-	errorHandler ErrorHandler
+	srv              *http.Server
+	mux              *http.ServeMux
+	shutdownTimeout  time.Duration
+	drainDelay       time.Duration
+	shutdownHooks    []ShutdownHook
+	shutdownMu       sync.Mutex
+	shuttingDown     atomic.Bool
+	inFlight         atomic.Int64
+	health           *health.Registry
+	listener         net.Listener
+	active           net.Listener
+	activeTLS        bool
+	activeMu         sync.RWMutex
+	handlers         []ctx.Handler
+	errorHandler     ErrorHandler
+	notFound         ctx.Handler
+	methodNotAllowed ctx.Handler
+	endpoints        map[string]*endpoint
+	endpointsMu      sync.RWMutex
+	routes           []*Route
+	hosts            []*Host
+	listeners        []*Listener
+	names            map[string]*Route
+	namesMu          sync.RWMutex
+	noQueryFallback  bool
+	modules          []Module
+	migrations       []migration.Migration
+	modulesMu        sync.Mutex
 }
 
-const closeTimeoutSeconds = 30
-
+// New creates a server listening on host:port, using timeoutSeconds as its read,
+// read header and write timeouts. It panics on an invalid address or a negative
+// timeout, use NewWithOptions for the full tuning and to get the error instead.
 func New(host, port string, timeoutSeconds int) *Server {
 	t := time.Second * time.Duration(timeoutSeconds)
-	mux := http.NewServeMux()
+
+	s, err := NewWithOptions(
+		WithAddr(net.JoinHostPort(host, port)),
+		WithReadTimeout(t),
+		WithReadHeaderTimeout(t),
+		WithWriteTimeout(t),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// NewWithOptions creates a server configured by the options, see Option.
+// Without options it listens on DefaultAddr with the default timeouts.
+// It returns ErrInvalidOption when an option is not valid.
+func NewWithOptions(opts ...Option) (*Server, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
 
 	httpSrv := &http.Server{
-		Addr:              host + ":" + port,
-		Handler:           mux,
-		ReadTimeout:       t,
-		ReadHeaderTimeout: t,
-		WriteTimeout:      t,
+		Addr:              o.addr,
+		ReadTimeout:       o.readTimeout,
+		ReadHeaderTimeout: o.readHeaderTimeout,
+		WriteTimeout:      o.writeTimeout,
+		IdleTimeout:       o.idleTimeout,
+		MaxHeaderBytes:    o.maxHeaderBytes,
+		TLSConfig:         o.tlsConfig,
 	}
 
+	if o.baseContext != nil {
+		httpSrv.BaseContext = func(net.Listener) context.Context {
+			return o.baseContext
+		}
+	}
+
+	if o.errorLog != nil {
+		httpSrv.ErrorLog = slog.NewLogLogger(o.errorLog.Handler(), slog.LevelError)
+	}
+
+	mux := http.NewServeMux()
 	s := &Server{
-		srv:          httpSrv,
-		mux:          mux,
-		handlers:     []ctx.Handler{},
-		errorHandler: defaultErrorHandler,
+		srv:              httpSrv,
+		mux:              mux,
+		shutdownTimeout:  o.shutdownTimeout,
+		drainDelay:       o.drainDelay,
+		health:           health.New(),
+		listener:         o.listener,
+		handlers:         []ctx.Handler{},
+		errorHandler:     defaultErrorHandler,
+		notFound:         defaultNotFound,
+		methodNotAllowed: defaultMethodNotAllowed,
+		endpoints:        map[string]*endpoint{},
+		names:            map[string]*Route{},
 	}
+	httpSrv.Handler = s.Handler()
 
-	s.Route(web.MethodAny, "/", func(c ctx.Ctx) error {
-		return c.Error(http.StatusNotFound, servererror.ErrNotFound)
+	// the requests matching no route; routes at "/" only match the root path
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		s.serve(w, r, nil, s.notFound)
 	})
 
-	s.Route(web.MethodGet, "/server/ready", func(c ctx.Ctx) error {
-		return c.SendString("OK")
-	})
+	s.registerHealth()
 
-	return s
+	return s, nil
 }
 
+// Start serves on the WithListener listener, on the first systemd socket activation
+// listener when the process was socket activated, or else on the server address,
+// along with the named listeners, see Server.Listener.
 func (s *Server) Start() error {
-	return s.srv.ListenAndServe()
+	l, err := s.Listen()
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
 }
 
-// StartTLS starts the server with TLS using the provided certificate and key files.
+// StartTLS starts the server with TLS using the provided certificate and key files,
+// choosing the listener like Start.
 func (s *Server) StartTLS(certFile, keyFile string) error {
-	return s.srv.ListenAndServeTLS(certFile, keyFile)
+	l, err := s.Listen()
+	if err != nil {
+		return err
+	}
+
+	return s.ServeTLS(l, certFile, keyFile)
 }
 
 // SetTLSConfig sets a custom TLS configuration on the server.
@@ -69,43 +160,29 @@ func (s *Server) SetTLSConfig(cfg *tls.Config) {
 	s.srv.TLSConfig = cfg
 }
 
-// ListenAndShutdown starts the server and blocks until a SIGINT or SIGTERM
-// signal is received, then performs a graceful shutdown. The optional onShutdown
-// callbacks are invoked after the HTTP server stops (use them to close databases,
-// flush logs, etc.). Returns nil when the server shuts down cleanly.
+// ListenAndShutdown starts the server like Start and blocks until a SIGINT or SIGTERM
+// signal is received, then performs a graceful shutdown with Stop. The optional
+// onShutdown callbacks are invoked after the OnShutdown hooks, prefer the hooks,
+// which get the shutdown context and report errors. Returns nil when the server
+// shuts down cleanly.
 func (s *Server) ListenAndShutdown(onShutdown ...func()) error {
-	errCh := make(chan error, 1)
-
-	go func() {
-		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			errCh <- err
-		}
-	}()
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	select {
-	case err := <-errCh:
-		return err
-	case <-quit:
-	}
-
-	shutdownErr := s.Stop()
-
-	for _, fn := range onShutdown {
-		fn()
-	}
-
-	return shutdownErr
+	return s.shutdownOnSignal(s.Start, onShutdown)
 }
 
 // ListenAndShutdownTLS is like ListenAndShutdown but starts the server with TLS.
 func (s *Server) ListenAndShutdownTLS(certFile, keyFile string, onShutdown ...func()) error {
+	return s.shutdownOnSignal(func() error {
+		return s.StartTLS(certFile, keyFile)
+	}, onShutdown)
+}
+
+// shutdownOnSignal runs start in the background until a SIGINT or SIGTERM signal,
+// stopping the server and calling the onShutdown callbacks
+func (s *Server) shutdownOnSignal(start func() error, onShutdown []func()) error {
 	errCh := make(chan error, 1)
 
 	go func() {
-		if err := s.srv.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
+		if err := start(); err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
 	}()
@@ -128,18 +205,18 @@ func (s *Server) ListenAndShutdownTLS(certFile, keyFile string, onShutdown ...fu
 	return shutdownErr
 }
 
+// IsReady reports whether the server answers on its listener, TCP or Unix socket
 func (s *Server) IsReady() bool {
-	req, err := http.NewRequest("GET", "http://"+s.srv.Addr+"/server/ready", nil)
-	if err != nil {
+	client, baseURL, ok := s.readyClient()
+	if !ok {
 		return false
 	}
 
-	client := &http.Client{Timeout: 2 * time.Second}
-
-	resp, err := client.Do(req)
+	resp, err := client.Get(baseURL + "/server/ready")
 	if err != nil {
 		return false
 	}
+	defer func() { _ = resp.Body.Close() }()
 
 	return resp.StatusCode == http.StatusOK
 }
@@ -154,16 +231,23 @@ func (s *Server) WaitUntilReady() {
 	}
 }
 
+// Stop runs the Shutdown sequence bounded by the shutdown timeout.
 func (s *Server) Stop() error {
-	ctx, cancel := context.WithTimeout(context.Background(), closeTimeoutSeconds*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
 	defer cancel()
-	return s.srv.Shutdown(ctx)
+	return s.Shutdown(ctx)
 }
 
 func (s *Server) Use(mw ...ctx.Handler) {
 	s.handlers = append(s.handlers, mw...)
 }
 
+// DisableQueryFallback makes ctx.Param and the typed param accessors only read path
+// params, instead of falling back to the query string param of the same name.
+func (s *Server) DisableQueryFallback() {
+	s.noQueryFallback = true
+}
+
 func (s *Server) ErrorHandler(h ErrorHandler) {
 	s.errorHandler = h
 }
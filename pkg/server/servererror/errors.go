@@ -4,5 +4,6 @@ import "net/http"
 
 var (
 	ErrNotFound          = Error{Code: http.StatusNotFound, Msg: "Resource not found"}
+	ErrMethodNotAllowed  = Error{Code: http.StatusMethodNotAllowed, Msg: "Method not allowed"}
 	ErrSessionNotStarted = Error{Code: http.StatusInternalServerError, Msg: "Session not started"}
 )
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ShutdownHook runs during the shutdown, after the HTTP server stops, to close
+// databases, flush logs, etc. The context ends at the shutdown deadline.
+type ShutdownHook func(ctx context.Context) error
+
+// OnShutdown adds hooks run by Shutdown in registration order, after the in-flight
+// requests finish.
+//
+//	srv.OnShutdown(func(ctx context.Context) error {
+//		return db.Close()
+//	})
+func (s *Server) OnShutdown(hooks ...ShutdownHook) {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+
+	s.shutdownHooks = append(s.shutdownHooks, hooks...)
+}
+
+// Shutdown stops the server gracefully:
+//
+//  1. /server/ready answers 503, so the load balancers stop sending requests
+//  2. it waits the drain delay (WithDrainDelay), while the server keeps serving
+//  3. it closes the main and the named listeners and waits for the in-flight requests to finish
+//  4. it runs the OnShutdown hooks in order
+//
+// The hooks run even when a previous step fails, and all the errors are joined.
+// The context bounds the whole sequence, Stop uses the shutdown timeout.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shuttingDown.Store(true)
+
+	var errs []error
+
+	if s.drainDelay > 0 {
+		t := time.NewTimer(s.drainDelay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+		}
+	}
+
+	if err := s.shutdownServers(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	s.shutdownMu.Lock()
+	hooks := append([]ShutdownHook{}, s.shutdownHooks...)
+	s.shutdownMu.Unlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ShuttingDown reports whether the shutdown started, when /server/ready answers 503.
+func (s *Server) ShuttingDown() bool {
+	return s.shuttingDown.Load()
+}
+
+// InFlight returns the number of requests being served.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
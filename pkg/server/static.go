@@ -1,6 +1,7 @@
 package server
 
 import (
+	"fmt"
 	"io/fs"
 	"net/http"
 	"strings"
@@ -19,6 +20,7 @@ func (s *Server) Static(prefix, dir string) {
 
 	fileServer := http.StripPrefix(prefix, http.FileServer(http.Dir(dir)))
 	s.mux.Handle(web.MethodGet.String()+" "+prefix, fileServer)
+	s.addStatic(prefix, dir)
 }
 
 // StaticFS serves files from the given fs.FS under the specified URL prefix.
@@ -34,4 +36,13 @@ func (s *Server) StaticFS(prefix string, fsys fs.FS) {
 
 	fileServer := http.StripPrefix(prefix, http.FileServerFS(fsys))
 	s.mux.Handle(web.MethodGet.String()+" "+prefix, fileServer)
+	s.addStatic(prefix, fmt.Sprintf("%T", fsys))
+}
+
+// addStatic adds a static mount to the route table, the source is its directory or fs.FS type
+func (s *Server) addStatic(prefix, source string) {
+	s.endpointsMu.Lock()
+	defer s.endpointsMu.Unlock()
+
+	s.routes = append(s.routes, &Route{server: s, method: web.MethodGet, path: prefix, static: source})
 }
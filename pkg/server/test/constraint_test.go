@@ -0,0 +1,90 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerConstraints(t *testing.T) {
+	srv := server.New(host, port, timeoutSeconds)
+	srv.ErrorHandler(testErrorHandlerfunc)
+	srv.DisableQueryFallback()
+
+	srv.Route(web.MethodGet, "/users/{id:uuid}", func(c ctx.Ctx) error {
+		id, err := c.ParamUUID("id")
+		if err != nil {
+			return err
+		}
+
+		return c.SendString("user " + id.String())
+	})
+	srv.Route(web.MethodGet, "/items/{n:int}", func(c ctx.Ctx) error {
+		return c.SendString("item " + c.Param("n"))
+	})
+	srv.Route(web.MethodDelete, "/items/{n:uint}", func(c ctx.Ctx) error {
+		return c.SendString("deleted " + c.Param("n"))
+	})
+	srv.Route(web.MethodGet, "/codes/{code:[A-Z]{3}}", func(c ctx.Ctx) error {
+		return c.SendString("code " + c.Param("code"))
+	})
+	srv.Route(web.MethodGet, "/search/{term}", func(c ctx.Ctx) error {
+		return c.SendString("term " + c.Param("term") + ", page " + c.Param("page"))
+	})
+
+	// background start
+	go func() {
+		t.Log("starting server")
+		err := srv.Start()
+		if err != nil {
+			t.Logf("Server: %s", err.Error())
+		}
+	}()
+	srv.WaitUntilReady()
+
+	t.Cleanup(func() {
+		// stop gracefully
+		err := srv.Stop()
+		require.NoError(t, err, "stopping server")
+	})
+
+	id := uuid.NewString()
+	testCases := []struct {
+		method web.Method
+		path   string
+		code   int
+		body   string
+	}{
+		{web.MethodGet, "/users/" + id, http.StatusOK, "user " + id},
+		{web.MethodGet, "/users/42", http.StatusNotFound, "TestErrorHandler: 404 Resource not found"},
+		{web.MethodGet, "/items/-3", http.StatusOK, "item -3"},
+		{web.MethodGet, "/items/abc", http.StatusNotFound, "TestErrorHandler: 404 Resource not found"},
+		{web.MethodDelete, "/items/3", http.StatusOK, "deleted 3"},
+		{web.MethodDelete, "/items/-3", http.StatusMethodNotAllowed, "TestErrorHandler: 405 Method not allowed"},
+		{web.MethodGet, "/codes/ABC", http.StatusOK, "code ABC"},
+		{web.MethodGet, "/codes/ABCD", http.StatusNotFound, "TestErrorHandler: 404 Resource not found"},
+		{web.MethodGet, "/search/go?term=other&page=2", http.StatusOK, "term go, page "},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method.String()+" "+tc.path, func(t *testing.T) {
+			res, err := call(tc.method, "", nil, tc.path, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.code, res.StatusCode)
+			assert.Equal(t, tc.body, bodyAsString(t, res))
+		})
+	}
+
+	t.Run("allow lists the matching routes", func(t *testing.T) {
+		res, err := call(web.MethodPost, "", nil, "/items/-3", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+		assert.Equal(t, "GET, HEAD, OPTIONS", res.Header.Get(web.HeaderAllow))
+	})
+}
@@ -0,0 +1,110 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/health"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerHealth(t *testing.T) {
+	srv, err := server.NewWithOptions(server.WithAddr("localhost:0"))
+	require.NoError(t, err)
+
+	var dbDown atomic.Bool
+	srv.Health().Add("database", func(context.Context) error {
+		if dbDown.Load() {
+			return errors.New("connection refused")
+		}
+
+		return nil
+	}, health.WithCacheTTL(0))
+	srv.Health().Add("disk", health.DiskSpace(t.TempDir(), 1), health.Liveness())
+	srv.HealthRoutes()
+
+	go func() {
+		if err := srv.Start(); err != nil {
+			t.Logf("Server: %s", err.Error())
+		}
+	}()
+	srv.WaitUntilReady()
+	t.Cleanup(func() {
+		require.NoError(t, srv.Stop())
+	})
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	get := func(t *testing.T, path string, dest any) *http.Response {
+		t.Helper()
+
+		res, err := client.Get("http://" + srv.Addr().String() + path)
+		require.NoError(t, err)
+		if dest != nil {
+			bodyAsJSON(t, res, dest)
+		}
+
+		return res
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		var report health.Report
+		res := get(t, "/readyz", &report)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, health.StatusUp, report.Status)
+		require.Len(t, report.Checks, 2)
+		assert.Equal(t, "database", report.Checks[0].Name)
+		assert.False(t, report.Checks[0].CheckedAt.IsZero())
+
+		res = get(t, "/livez", &report)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, "disk", report.Checks[0].Name)
+	})
+
+	t.Run("database down", func(t *testing.T) {
+		dbDown.Store(true)
+		t.Cleanup(func() { dbDown.Store(false) })
+
+		var report health.Report
+		res := get(t, "/readyz", &report)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, "Unhealthy", report.Message)
+		assert.Equal(t, "connection refused", report.Checks[0].Error)
+		assert.False(t, srv.IsReady())
+
+		res = get(t, "/livez", nil)
+		assert.Equal(t, http.StatusOK, res.StatusCode, "liveness does not depend on the database")
+	})
+}
+
+func TestServerHealthRoutesOnDemand(t *testing.T) {
+	srv, err := server.NewWithOptions(server.WithAddr("localhost:0"))
+	require.NoError(t, err)
+
+	// an application with its own /readyz, registered before the health routes existed
+	require.NotPanics(t, func() {
+		srv.Route(web.MethodGet, "/readyz", func(c ctx.Ctx) error {
+			return c.SendString("mine")
+		})
+	})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, "mine", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.PanicsWithValue(t, "route GET /readyz already registered", srv.HealthRoutes)
+}
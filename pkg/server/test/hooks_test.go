@@ -0,0 +1,76 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerHooks(t *testing.T) {
+	srv := server.New(host, port, timeoutSeconds)
+	srv.Use(func(c ctx.Ctx) error {
+		c.SetHeader("X-Middleware", "yes")
+		return c.Next()
+	})
+	srv.ErrorHandler(testErrorHandlerfunc)
+
+	srv.Route(web.MethodGet, "/", func(c ctx.Ctx) error {
+		return c.SendString("home")
+	})
+	srv.Route(web.MethodGet, "/hello", func(c ctx.Ctx) error {
+		return c.SendString("Hello, World!")
+	})
+
+	srv.NotFound(func(c ctx.Ctx) error {
+		return c.WithStatus(http.StatusNotFound).SendString("nothing at " + c.Path())
+	})
+	srv.MethodNotAllowed(func(c ctx.Ctx) error {
+		return c.Error(http.StatusMethodNotAllowed, "use "+c.GetResponseHeader(web.HeaderAllow))
+	})
+
+	// background start
+	go func() {
+		t.Log("starting server")
+		err := srv.Start()
+		if err != nil {
+			t.Logf("Server: %s", err.Error())
+		}
+	}()
+	srv.WaitUntilReady()
+
+	t.Cleanup(func() {
+		// stop gracefully
+		err := srv.Stop()
+		require.NoError(t, err, "stopping server")
+	})
+
+	t.Run("home is not a catch-all", func(t *testing.T) {
+		res, err := call(web.MethodGet, "", nil, "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "home", bodyAsString(t, res))
+
+		res, err = call(web.MethodGet, "", nil, "/missing/page", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, "yes", res.Header.Get("X-Middleware"))
+		assert.Equal(t, "nothing at /missing/page", bodyAsString(t, res))
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		res, err := call(web.MethodDelete, "", nil, "/hello", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+		assert.Equal(t, "yes", res.Header.Get("X-Middleware"))
+		assert.Equal(t, "TestErrorHandler: 405 use GET, HEAD, OPTIONS", bodyAsString(t, res))
+
+		res, err = call(web.MethodPost, "", nil, "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	})
+}
@@ -0,0 +1,142 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerHosts(t *testing.T) {
+	srv := server.New(host, port, timeoutSeconds)
+	srv.ErrorHandler(testErrorHandlerfunc)
+
+	tag := func(value string) ctx.Handler {
+		return func(c ctx.Ctx) error {
+			c.SetHeader("X-Host", value)
+			return c.Next()
+		}
+	}
+
+	srv.Route(web.MethodGet, "/", func(c ctx.Ctx) error {
+		return c.SendString("default home")
+	})
+
+	api := srv.Host("api.example.com", tag("api"))
+	api.Route(web.MethodGet, "/", func(c ctx.Ctx) error {
+		return c.SendString("api home")
+	})
+	api.Group("/v1").Route(web.MethodGet, "/users/{id:int}", func(c ctx.Ctx) error {
+		return c.SendString("api user " + c.Param("id"))
+	})
+	api.NotFound(func(c ctx.Ctx) error {
+		return c.Error(http.StatusNotFound, "No API endpoint")
+	})
+
+	tenants := srv.Host("{tenant}.example.com", tag("tenant"))
+	tenants.Route(web.MethodGet, "/", func(c ctx.Ctx) error {
+		return c.SendString("home of " + c.Param("tenant"))
+	})
+	tenants.Route(web.MethodGet, "/pages/{page}", func(c ctx.Ctx) error {
+		return c.SendString(c.Param("tenant") + " page " + c.Param("page"))
+	})
+
+	assert.Panics(t, func() { srv.Host("API.example.com") })
+
+	// background start
+	go func() {
+		t.Log("starting server")
+		err := srv.Start()
+		if err != nil {
+			t.Logf("Server: %s", err.Error())
+		}
+	}()
+	srv.WaitUntilReady()
+
+	t.Cleanup(func() {
+		// stop gracefully
+		err := srv.Stop()
+		require.NoError(t, err, "stopping server")
+	})
+
+	get := func(t *testing.T, hostName, path string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, composeURL(path), nil)
+		require.NoError(t, err)
+		req.Host = hostName
+
+		client := &http.Client{Timeout: time.Second * timeoutSeconds}
+		res, err := client.Do(req)
+		require.NoError(t, err)
+
+		return res
+	}
+
+	testCases := []struct {
+		name   string
+		host   string
+		path   string
+		code   int
+		header string
+		body   string
+	}{
+		{"default host", "localhost", "/", http.StatusOK, "", "default home"},
+		{"exact host", "api.example.com", "/", http.StatusOK, "api", "api home"},
+		{"host with port", "API.example.com:8080", "/v1/users/7", http.StatusOK, "api", "api user 7"},
+		{
+			"host not found",
+			"api.example.com",
+			"/v1/users/x",
+			http.StatusNotFound,
+			"api",
+			"TestErrorHandler: 404 No API endpoint",
+		},
+		{
+			"host catch all",
+			"api.example.com",
+			"/missing",
+			http.StatusNotFound,
+			"api",
+			"TestErrorHandler: 404 No API endpoint",
+		},
+		{"wildcard host", "acme.example.com", "/", http.StatusOK, "tenant", "home of acme"},
+		{"wildcard param", "acme.example.com", "/pages/about", http.StatusOK, "tenant", "acme page about"},
+		{
+			"wildcard default not found", "acme.example.com", "/missing",
+			http.StatusNotFound, "tenant", "TestErrorHandler: 404 Resource not found",
+		},
+		{
+			"unknown host",
+			"other.org",
+			"/pages/about",
+			http.StatusNotFound,
+			"",
+			"TestErrorHandler: 404 Resource not found",
+		},
+		{"nested subdomain", "a.b.example.com", "/", http.StatusOK, "", "default home"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := get(t, tc.host, tc.path)
+			assert.Equal(t, tc.code, res.StatusCode)
+			assert.Equal(t, tc.header, res.Header.Get("X-Host"))
+			assert.Equal(t, tc.body, bodyAsString(t, res))
+		})
+	}
+
+	t.Run("routes", func(t *testing.T) {
+		routes := srv.Routes()
+		require.Len(t, routes, 6)
+		assert.Equal(t, "", routes[0].Host)
+		assert.Equal(t, "api.example.com", routes[2].Host)
+		assert.Equal(t, "/v1/users/{id}", routes[2].Pattern)
+		assert.Equal(t, "{tenant}.example.com", routes[5].Host)
+	})
+}
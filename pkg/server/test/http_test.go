@@ -0,0 +1,207 @@
+package server_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/middleware"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerHTTPInterop(t *testing.T) {
+	srv := server.New(host, port, timeoutSeconds)
+	srv.ErrorHandler(testErrorHandlerfunc)
+
+	// standard middleware wrapping the response writer, like the otel or gorilla ones
+	var seenStatus int
+	srv.Use(ctx.WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+			rec.Header().Set("X-Std", "yes")
+			next.ServeHTTP(rec, r)
+			seenStatus = rec.code
+		})
+	}))
+
+	srv.Route(web.MethodGet, "/hello", func(c ctx.Ctx) error {
+		return c.SendString("hello " + c.ID())
+	})
+	srv.Route(web.MethodGet, "/fail", func(c ctx.Ctx) error {
+		return errors.New("boom")
+	})
+
+	std := http.NewServeMux()
+	std.HandleFunc("GET /files/{name}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("file " + r.PathValue("name") + " " + ctx.RequestID(r.Context())))
+	})
+	std.HandleFunc("POST /files/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	srv.Mount("/std/", std)
+	srv.Group("/tenants/{tenant}").Mount("/std", std)
+
+	tagged := srv.HTTPMiddleware(func(c ctx.Ctx) error {
+		if c.GetRequestHeader("X-Deny") != "" {
+			return c.Error(http.StatusForbidden, "Denied")
+		}
+		c.SetHeader("X-Ctx", "yes")
+		return c.Next()
+	})
+
+	outer := http.NewServeMux()
+	outer.Handle("/app/", http.StripPrefix("/app", srv.Handler()))
+	outer.Handle("/legacy", tagged(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("legacy " + ctx.RequestID(r.Context())))
+	})))
+
+	ts := httptest.NewServer(outer)
+	t.Cleanup(ts.Close)
+
+	do := func(t *testing.T, method, path string, header ...string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		require.NoError(t, err)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = res.Body.Close() })
+
+		return res
+	}
+
+	t.Run("embedded handler", func(t *testing.T) {
+		res := do(t, http.MethodGet, "/app/hello")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "yes", res.Header.Get("X-Std"))
+		id := res.Header.Get(web.HeaderXRequestID)
+		assert.NotEmpty(t, id)
+		assert.Equal(t, "hello "+id, bodyAsString(t, res))
+	})
+
+	t.Run("error handled inside the standard middleware", func(t *testing.T) {
+		res := do(t, http.MethodGet, "/app/fail")
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Equal(t, http.StatusInternalServerError, seenStatus)
+		assert.Equal(t, "TestErrorHandler: 500 boom", bodyAsString(t, res))
+	})
+
+	t.Run("mount", func(t *testing.T) {
+		res := do(t, http.MethodGet, "/app/std/files/a.txt")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "yes", res.Header.Get("X-Std"))
+		assert.Equal(t, "file a.txt "+res.Header.Get(web.HeaderXRequestID), bodyAsString(t, res))
+
+		res = do(t, http.MethodPost, "/app/std/files/a.txt")
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, http.StatusCreated, seenStatus)
+	})
+
+	t.Run("group mount with params", func(t *testing.T) {
+		res := do(t, http.MethodGet, "/app/tenants/acme/std/files/b.txt")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, bodyAsString(t, res), "file b.txt ")
+	})
+
+	t.Run("mount not found", func(t *testing.T) {
+		res := do(t, http.MethodGet, "/app/std/missing")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, http.StatusNotFound, seenStatus)
+	})
+
+	t.Run("ctx middleware on a standard mux", func(t *testing.T) {
+		res := do(t, http.MethodGet, "/legacy")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "yes", res.Header.Get("X-Ctx"))
+		assert.Equal(t, "legacy "+res.Header.Get(web.HeaderXRequestID), bodyAsString(t, res))
+
+		res = do(t, http.MethodGet, "/legacy", "X-Deny", "1")
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		assert.Equal(t, "TestErrorHandler: 403 Denied", bodyAsString(t, res))
+	})
+
+	t.Run("routes", func(t *testing.T) {
+		var patterns []string
+		for _, r := range srv.Routes() {
+			patterns = append(patterns, r.Method+" "+r.Pattern)
+		}
+		assert.Contains(t, patterns, "ANY /std/{mount...}")
+		assert.Contains(t, patterns, "ANY /tenants/{tenant}/std/{mount...}")
+	})
+}
+
+func TestServerAutomaticReplies(t *testing.T) {
+	srv, err := server.NewWithOptions()
+	require.NoError(t, err)
+
+	cors := middleware.NewCors(middleware.NewCorsOptions())
+	srv.Group("/api", cors).Route(web.MethodPost, "/items", func(c ctx.Ctx) error {
+		return c.SendString("created")
+	})
+	srv.Host("cors.example.com", cors).Route(web.MethodPut, "/things", func(c ctx.Ctx) error {
+		return c.SendString("updated")
+	})
+	srv.Route(web.MethodGet, "/stream", func(c ctx.Ctx) error {
+		if _, err := c.ResponseWriter().Write([]byte("chunk")); err != nil {
+			return err
+		}
+
+		return http.NewResponseController(c.ResponseWriter()).Flush()
+	})
+
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	preflight := func(target string) *http.Request {
+		req := httptest.NewRequest(http.MethodOptions, target, nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set(web.HeaderAccessControlRequestMethod, http.MethodPost)
+
+		return req
+	}
+
+	t.Run("group preflight", func(t *testing.T) {
+		rec := do(preflight("/api/items"))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "OPTIONS, POST", rec.Header().Get(web.HeaderAllow))
+		assert.Equal(t, "POST, OPTIONS", rec.Header().Get(web.HeaderAccessControlAllowMethods))
+	})
+
+	t.Run("host preflight", func(t *testing.T) {
+		rec := do(preflight("http://cors.example.com/things"))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "PUT, OPTIONS", rec.Header().Get(web.HeaderAccessControlAllowMethods))
+	})
+
+	t.Run("streaming head", func(t *testing.T) {
+		rec := do(httptest.NewRequest(http.MethodHead, "/stream", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, rec.Flushed)
+		assert.Empty(t, rec.Body.String())
+	})
+}
+
+// statusRecorder is a minimal standard middleware response writer wrapper
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
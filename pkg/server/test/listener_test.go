@@ -0,0 +1,154 @@
+package server_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unixClient(path string) *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
+
+func TestServerUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "martian")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "http.sock")
+
+	// a stale socket left by a dead process
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	l, err := server.ListenUnix(path, 0o600)
+	require.NoError(t, err)
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	_, err = server.ListenUnix(path, 0o600)
+	require.ErrorIs(t, err, server.ErrSocketInUse)
+
+	regular := filepath.Join(dir, "regular")
+	require.NoError(t, os.WriteFile(regular, nil, 0o600))
+	_, err = server.ListenUnix(regular, 0o600)
+	require.ErrorIs(t, err, server.ErrNotASocket)
+
+	srv, err := server.NewWithOptions(server.WithListener(l))
+	require.NoError(t, err)
+	assert.Nil(t, srv.Addr())
+
+	srv.Route(web.MethodGet, "/hello", func(c ctx.Ctx) error {
+		return c.SendString("hello over unix")
+	})
+
+	go func() {
+		if err := srv.Start(); err != nil {
+			t.Logf("Server: %s", err.Error())
+		}
+	}()
+	srv.WaitUntilReady()
+	require.True(t, srv.IsReady())
+	assert.Equal(t, "unix", srv.Addr().Network())
+
+	res, err := unixClient(path).Get("http://unix/hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello over unix", bodyAsString(t, res))
+
+	require.NoError(t, srv.Stop())
+	assert.False(t, srv.IsReady())
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist, "the socket is removed on stop")
+}
+
+func TestServerServeListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := server.New(host, port, timeoutSeconds)
+	go func() {
+		if err := srv.Serve(l); err != nil {
+			t.Logf("Server: %s", err.Error())
+		}
+	}()
+	srv.WaitUntilReady()
+	t.Cleanup(func() {
+		require.NoError(t, srv.Stop())
+	})
+
+	require.True(t, srv.IsReady())
+	assert.Equal(t, l.Addr().String(), srv.Addr().String())
+
+	res, err := http.Get("http://" + srv.Addr().String() + "/server/ready")
+	require.NoError(t, err)
+	assert.Equal(t, "OK", bodyAsString(t, res))
+}
+
+// TestServerSystemdActivation runs itself as a socket activated child process,
+// passing a listener as file descriptor 3 like systemd does
+func TestServerSystemdActivation(t *testing.T) {
+	if os.Getenv("MARTIAN_SYSTEMD_CHILD") == "1" {
+		// systemd sets LISTEN_PID to the pid of the child, unknown to the parent
+		require.NoError(t, os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid())))
+
+		srv, err := server.NewWithOptions(server.WithAddr("localhost:1"))
+		require.NoError(t, err)
+		srv.Route(web.MethodGet, "/who", func(c ctx.Ctx) error {
+			return c.SendString("activated " + os.Getenv("LISTEN_FDS"))
+		})
+
+		_ = srv.Start()
+		return
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f, err := l.(*net.TCPListener).File()
+	require.NoError(t, err)
+	_ = l.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestServerSystemdActivation$")
+	cmd.Env = append(os.Environ(), "MARTIAN_SYSTEMD_CHILD=1", "LISTEN_FDS=1", "LISTEN_FDNAMES=http")
+	cmd.ExtraFiles = []*os.File{f}
+	require.NoError(t, cmd.Start())
+	_ = f.Close()
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	client := &http.Client{Timeout: time.Second}
+	url := "http://" + l.Addr().String() + "/who"
+
+	var res *http.Response
+	require.Eventually(t, func() bool {
+		res, err = client.Get(url)
+		return err == nil
+	}, 10*time.Second, 100*time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "activated ", bodyAsString(t, res), "the activation environment is consumed")
+}
@@ -0,0 +1,234 @@
+package server_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer starts srv with start, stopping it at the end of the test,
+// and returns the channel of the start error
+func startServer(t *testing.T, srv *server.Server, start func() error, listeners ...*server.Listener) <-chan error {
+	t.Helper()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- start()
+	}()
+
+	require.Eventually(t, func() bool {
+		for _, l := range listeners {
+			if l.Addr() == nil {
+				return false
+			}
+		}
+
+		return srv.IsReady()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	t.Cleanup(func() {
+		_ = srv.Stop()
+	})
+
+	return errCh
+}
+
+func TestServerListeners(t *testing.T) {
+	srv, err := server.NewWithOptions(server.WithAddr("localhost:0"))
+	require.NoError(t, err)
+
+	srv.Route(web.MethodGet, "/hello", func(c ctx.Ctx) error {
+		return c.SendString("public")
+	})
+
+	internal := srv.Listener("internal", "localhost:0")
+	internal.HealthRoutes()
+	internal.Route(web.MethodGet, "/metrics", func(c ctx.Ctx) error {
+		return c.SendString("metrics")
+	})
+	assert.Equal(t, "internal", internal.Name())
+	assert.Nil(t, internal.Addr())
+
+	assert.PanicsWithValue(t, "listener internal already registered", func() {
+		srv.Listener("internal", "localhost:0")
+	})
+
+	errCh := startServer(t, srv, srv.Start, internal)
+
+	get := func(addr net.Addr, path string) (int, string) {
+		res, err := http.Get("http://" + addr.String() + path)
+		require.NoError(t, err)
+
+		return res.StatusCode, bodyAsString(t, res)
+	}
+
+	code, body := get(srv.Addr(), "/hello")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "public", body)
+
+	code, _ = get(srv.Addr(), "/metrics")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = get(internal.Addr(), "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "metrics", body)
+
+	code, _ = get(internal.Addr(), "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = get(internal.Addr(), "/hello")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	var listenerRoutes []string
+	for _, r := range srv.Routes() {
+		if r.Listener != "" {
+			listenerRoutes = append(listenerRoutes, r.Listener+" "+r.Pattern)
+		}
+	}
+	assert.Equal(t, []string{
+		"internal /livez", "internal /metrics", "internal /readyz", "internal /server/ready",
+	}, listenerRoutes)
+
+	// the shutdown stops every listener
+	addr := internal.Addr().String()
+	require.NoError(t, srv.Stop())
+	select {
+	case err := <-errCh:
+		assert.ErrorIs(t, err, http.ErrServerClosed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return")
+	}
+
+	_, err = net.DialTimeout("tcp", addr, time.Second)
+	assert.Error(t, err)
+}
+
+func TestServerListenerAddressInUse(t *testing.T) {
+	busy, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = busy.Close() })
+
+	srv, err := server.NewWithOptions(server.WithAddr("localhost:0"))
+	require.NoError(t, err)
+	srv.Listener("internal", busy.Addr().String())
+
+	// Listen binds the named listeners too
+	_, err = srv.Listen()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "listener internal")
+
+	err = srv.Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "listener internal")
+	assert.Nil(t, srv.Addr())
+}
+
+func TestServerListenWhileStarting(t *testing.T) {
+	srv, err := server.NewWithOptions(server.WithAddr("localhost:0"))
+	require.NoError(t, err)
+
+	internal := srv.Listener("internal", "localhost:0")
+	internal.Route(web.MethodGet, "/metrics", func(c ctx.Ctx) error {
+		return c.SendString("metrics")
+	})
+
+	// Listen from another goroutine binds the same named listeners
+	listened := make(chan struct{})
+	go func() {
+		defer close(listened)
+		if ln, err := srv.Listen(); err == nil {
+			_ = ln.Close()
+		}
+	}()
+
+	startServer(t, srv, srv.Start, internal)
+	<-listened
+
+	res, err := http.Get("http://" + internal.Addr().String() + "/metrics")
+	require.NoError(t, err)
+	assert.Equal(t, "metrics", bodyAsString(t, res))
+}
+
+func TestServerRedirectHTTPS(t *testing.T) {
+	// borrow the self signed certificate of httptest
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	cert := ts.TLS.Certificates[0]
+	ts.Close()
+
+	srv, err := server.NewWithOptions(
+		server.WithAddr("localhost:0"),
+		server.WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}),
+	)
+	require.NoError(t, err)
+
+	srv.Route(web.MethodGet, "/hello", func(c ctx.Ctx) error {
+		return c.SendString("secure")
+	})
+
+	redirect := srv.RedirectHTTPS(server.RedirectConfig{
+		Addr:                  "localhost:0",
+		Port:                  "8443",
+		HSTSMaxAge:            time.Hour,
+		HSTSIncludeSubdomains: true,
+	})
+	assert.Equal(t, server.RedirectListener, redirect.Name())
+
+	startServer(t, srv, func() error {
+		return srv.StartTLS("", "")
+	}, redirect)
+
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	t.Run("redirect", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "http://"+redirect.Addr().String()+"/hello?a=1", nil)
+		require.NoError(t, err)
+		req.Host = "example.com"
+
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+
+		assert.Equal(t, http.StatusPermanentRedirect, res.StatusCode)
+		assert.Equal(t, "https://example.com:8443/hello?a=1", res.Header.Get(web.HeaderLocation))
+		assert.Empty(t, res.Header.Get(web.HeaderStrictTransportSecurity))
+	})
+
+	t.Run("hsts", func(t *testing.T) {
+		res, err := client.Get("https://" + srv.Addr().String() + "/hello")
+		require.NoError(t, err)
+
+		assert.Equal(t, "max-age=3600; includeSubDomains", res.Header.Get(web.HeaderStrictTransportSecurity))
+		assert.Equal(t, "secure", bodyAsString(t, res))
+	})
+}
+
+func TestServerListenerFailure(t *testing.T) {
+	srv, err := server.NewWithOptions(server.WithAddr("localhost:0"))
+	require.NoError(t, err)
+
+	// a TLS listener without certificates fails once serving, closing the main one
+	srv.Listener("broken", "localhost:0").TLS("", "")
+
+	err = srv.Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "listener broken")
+	assert.False(t, errors.Is(err, http.ErrServerClosed))
+}
@@ -0,0 +1,206 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jorgefuertes/martian-stack/pkg/database/migration"
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/health"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testModule struct {
+	server.BaseModule
+
+	name       string
+	deps       []string
+	versions   []int64
+	check      string
+	registered *[]string
+	err        error
+}
+
+func (m testModule) Name() string {
+	return m.name
+}
+
+func (m testModule) Dependencies() []string {
+	return m.deps
+}
+
+func (m testModule) Register(srv *server.Server) error {
+	if m.registered != nil {
+		*m.registered = append(*m.registered, m.name)
+	}
+	if m.err != nil {
+		return m.err
+	}
+
+	srv.Route(web.MethodGet, "/"+m.name, func(c ctx.Ctx) error {
+		return c.SendString(m.name + ":" + c.Store().GetString("mw"))
+	})
+
+	return nil
+}
+
+func (m testModule) Migrations() []migration.Migration {
+	migs := make([]migration.Migration, 0, len(m.versions))
+	for _, v := range m.versions {
+		migs = append(migs, migration.Migration{Version: v, Name: m.name})
+	}
+
+	return migs
+}
+
+func (m testModule) HealthChecks() []health.Check {
+	name := m.check
+	if name == "" {
+		name = m.name
+	}
+
+	return []health.Check{{Name: name, Func: func(context.Context) error { return nil }}}
+}
+
+func (m testModule) Middleware() []ctx.Handler {
+	return []ctx.Handler{func(c ctx.Ctx) error {
+		c.Store().Set("mw", c.Store().GetString("mw")+m.name)
+		return c.Next()
+	}}
+}
+
+func TestServerInstall(t *testing.T) {
+	newServer := func(t *testing.T) *server.Server {
+		srv, err := server.NewWithOptions()
+		require.NoError(t, err)
+
+		return srv
+	}
+
+	t.Run("dependency order", func(t *testing.T) {
+		srv := newServer(t)
+
+		var registered []string
+		err := srv.Install(
+			testModule{name: "admin", deps: []string{"auth"}, versions: []int64{3}, registered: &registered},
+			testModule{name: "auth", deps: []string{"db"}, versions: []int64{2}, registered: &registered},
+			testModule{name: "db", versions: []int64{1}, registered: &registered},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"db", "auth", "admin"}, registered)
+		assert.Equal(t, []string{"db", "auth", "admin"}, srv.Modules())
+
+		var versions []int64
+		for _, m := range srv.Migrations() {
+			versions = append(versions, m.Version)
+		}
+		assert.Equal(t, []int64{1, 2, 3}, versions)
+
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "admin:dbauthadmin", rec.Body.String())
+
+		report := srv.Health().Readiness(context.Background())
+		require.Len(t, report.Checks, 3)
+		assert.Equal(t, "db", report.Checks[0].Name)
+	})
+
+	t.Run("installed dependency", func(t *testing.T) {
+		srv := newServer(t)
+
+		require.NoError(t, srv.Install(testModule{name: "auth"}))
+		require.NoError(t, srv.Install(testModule{name: "admin", deps: []string{"auth"}}))
+		assert.Equal(t, []string{"auth", "admin"}, srv.Modules())
+
+		err := srv.Install(testModule{name: "auth"})
+		assert.ErrorIs(t, err, server.ErrDuplicateModule)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			modules []server.Module
+			want    error
+			msg     string
+		}{
+			{
+				name:    "duplicate",
+				modules: []server.Module{testModule{name: "a"}, testModule{name: "a"}},
+				want:    server.ErrDuplicateModule,
+			},
+			{
+				name:    "unknown dependency",
+				modules: []server.Module{testModule{name: "admin", deps: []string{"auth"}}},
+				want:    server.ErrUnknownModule,
+				msg:     "admin depends on auth",
+			},
+			{
+				name: "cycle",
+				modules: []server.Module{
+					testModule{name: "a", deps: []string{"b"}},
+					testModule{name: "b", deps: []string{"a"}},
+				},
+				want: server.ErrModuleCycle,
+				msg:  "a -> b -> a",
+			},
+			{
+				name: "duplicate migration",
+				modules: []server.Module{
+					testModule{name: "a", versions: []int64{1}},
+					testModule{name: "b", versions: []int64{1}},
+				},
+				want: server.ErrDuplicateMigration,
+				msg:  "1 of b, used by a",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				srv := newServer(t)
+				srv.Health().Add("server", func(context.Context) error { return nil })
+
+				err := srv.Install(tt.modules...)
+				require.ErrorIs(t, err, tt.want)
+				assert.Contains(t, err.Error(), tt.msg)
+				assert.Empty(t, srv.Modules())
+				assert.Empty(t, srv.Migrations())
+				assert.Len(t, srv.Health().Readiness(context.Background()).Checks, 1)
+				assert.Len(t, srv.Routes(), len(newServer(t).Routes()))
+			})
+		}
+	})
+
+	t.Run("register error", func(t *testing.T) {
+		srv := newServer(t)
+
+		errBoom := errors.New("boom")
+		err := srv.Install(
+			testModule{name: "a", versions: []int64{1}},
+			testModule{name: "b", versions: []int64{2}, err: errBoom},
+		)
+		require.ErrorIs(t, err, server.ErrModuleInstallFailed)
+		assert.Contains(t, err.Error(), "b: boom")
+		assert.Equal(t, []string{"a"}, srv.Modules())
+		assert.Len(t, srv.Migrations(), 1)
+
+		// the failing module left no middleware nor health check behind
+		report := srv.Health().Readiness(context.Background())
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, "a", report.Checks[0].Name)
+
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a", nil))
+		assert.Equal(t, "a:a", rec.Body.String())
+
+		// so a retry installs it
+		require.NoError(t, srv.Install(testModule{name: "b", versions: []int64{2}}))
+		assert.Equal(t, []string{"a", "b"}, srv.Modules())
+	})
+}
@@ -0,0 +1,109 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerOptions(t *testing.T) {
+	t.Run("invalid options", func(t *testing.T) {
+		var nilCtx context.Context
+
+		testCases := []struct {
+			name string
+			opt  server.Option
+		}{
+			{"address without port", server.WithAddr("localhost")},
+			{"invalid port", server.WithAddr("localhost:99999")},
+			{"negative read timeout", server.WithReadTimeout(-time.Second)},
+			{"negative read header timeout", server.WithReadHeaderTimeout(-time.Second)},
+			{"negative write timeout", server.WithWriteTimeout(-time.Second)},
+			{"negative idle timeout", server.WithIdleTimeout(-time.Second)},
+			{"negative drain delay", server.WithDrainDelay(-time.Second)},
+			{"zero shutdown timeout", server.WithShutdownTimeout(0)},
+			{"zero max header bytes", server.WithMaxHeaderBytes(0)},
+			{"nil base context", server.WithBaseContext(nilCtx)},
+			{"nil error log", server.WithErrorLog(nil)},
+			{"nil TLS config", server.WithTLSConfig(nil)},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				srv, err := server.NewWithOptions(tc.opt)
+				require.ErrorIs(t, err, server.ErrInvalidOption)
+				assert.Nil(t, srv)
+			})
+		}
+
+		_, err := server.NewWithOptions(
+			server.WithReadTimeout(time.Second),
+			server.WithReadHeaderTimeout(time.Minute),
+		)
+		require.ErrorIs(t, err, server.ErrInvalidOption)
+
+		_, err = server.NewWithOptions(
+			server.WithShutdownTimeout(time.Second),
+			server.WithDrainDelay(time.Second),
+		)
+		require.ErrorIs(t, err, server.ErrInvalidOption)
+
+		assert.Panics(t, func() { server.New(host, port, -1) })
+	})
+
+	t.Run("configured server", func(t *testing.T) {
+		type key struct{}
+		baseCtx := context.WithValue(context.Background(), key{}, "base")
+
+		srv, err := server.NewWithOptions(
+			server.WithAddr("localhost:8081"),
+			server.WithReadTimeout(10*time.Second),
+			server.WithReadHeaderTimeout(5*time.Second),
+			server.WithWriteTimeout(10*time.Second),
+			server.WithIdleTimeout(time.Minute),
+			server.WithShutdownTimeout(5*time.Second),
+			server.WithMaxHeaderBytes(4096),
+			server.WithBaseContext(baseCtx),
+		)
+		require.NoError(t, err)
+
+		srv.Route(web.MethodGet, "/base", func(c ctx.Ctx) error {
+			value, _ := c.Context().Value(key{}).(string)
+			return c.SendString(value)
+		})
+
+		go func() {
+			if err := srv.Start(); err != nil {
+				t.Logf("Server: %s", err.Error())
+			}
+		}()
+		srv.WaitUntilReady()
+
+		t.Cleanup(func() {
+			require.NoError(t, srv.Stop(), "stopping server")
+		})
+
+		client := &http.Client{Timeout: 5 * time.Second}
+
+		res, err := client.Get("http://localhost:8081/base")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "base", bodyAsString(t, res))
+
+		req, err := http.NewRequest(http.MethodGet, "http://localhost:8081/base", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Large", strings.Repeat("x", 16<<10))
+
+		res, err = client.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, res.StatusCode)
+	})
+}
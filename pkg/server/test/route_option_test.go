@@ -0,0 +1,123 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteOptions(t *testing.T) {
+	srv := server.New(host, port, timeoutSeconds)
+	srv.ErrorHandler(testErrorHandlerfunc)
+
+	trace := func(name string) ctx.Handler {
+		return func(c ctx.Ctx) error {
+			c.AddHeader("X-Trace", name)
+			return c.Next()
+		}
+	}
+	echo := func(c ctx.Ctx) error {
+		var body map[string]string
+		if err := c.UnmarshalBody(&body); err != nil {
+			return c.Error(http.StatusRequestEntityTooLarge, err.Error())
+		}
+
+		return c.SendString(body["msg"])
+	}
+	deny := func(c ctx.Ctx) error {
+		return c.Error(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	api := srv.Group("/api", trace("group"))
+	api.Route(web.MethodGet, "/traced", func(c ctx.Ctx) error {
+		return c.SendString("traced")
+	}, server.WithMiddleware(trace("route")), server.WithAuth(trace("auth")), server.WithName("traced"))
+	api.Route(web.MethodGet, "/plain", func(c ctx.Ctx) error {
+		return c.SendString("plain")
+	})
+	srv.Route(web.MethodGet, "/private", func(c ctx.Ctx) error {
+		return c.SendString("secret")
+	}, server.WithAuth(deny), server.WithTags("private"), server.WithDescription("Denied to everyone"))
+	srv.Route(web.MethodGet, "/slow", func(c ctx.Ctx) error {
+		select {
+		case <-time.After(time.Second):
+			return c.SendString("too late")
+		case <-c.Context().Done():
+			return c.Context().Err()
+		}
+	}, server.WithTimeout(50*time.Millisecond))
+	srv.Route(web.MethodPost, "/small", echo, server.WithMaxBodySize(16))
+	srv.Route(web.MethodPost, "/large", echo, server.WithMaxBodySize(ctx.MaxBodySize*2))
+
+	// background start
+	go func() {
+		t.Log("starting server")
+		err := srv.Start()
+		if err != nil {
+			t.Logf("Server: %s", err.Error())
+		}
+	}()
+	srv.WaitUntilReady()
+
+	t.Cleanup(func() {
+		// stop gracefully
+		err := srv.Stop()
+		require.NoError(t, err, "stopping server")
+	})
+
+	t.Run("middleware order", func(t *testing.T) {
+		res, err := call(web.MethodGet, "", nil, "/api/traced", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, []string{"group", "auth", "route"}, res.Header.Values("X-Trace"))
+		assert.Equal(t, "traced", bodyAsString(t, res))
+
+		res, err = call(web.MethodGet, "", nil, "/api/plain", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"group"}, res.Header.Values("X-Trace"))
+	})
+
+	t.Run("name", func(t *testing.T) {
+		u, err := srv.URL("traced")
+		require.NoError(t, err)
+		assert.Equal(t, "/api/traced", u)
+	})
+
+	t.Run("auth", func(t *testing.T) {
+		res, err := call(web.MethodGet, "", nil, "/private", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.Equal(t, "TestErrorHandler: 401 Unauthorized", bodyAsString(t, res))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		res, err := call(web.MethodGet, "", nil, "/slow", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Equal(t, "TestErrorHandler: 503 Request timed out", bodyAsString(t, res))
+	})
+
+	t.Run("max body size", func(t *testing.T) {
+		res, err := call(web.MethodPost, "", nil, "/small", map[string]string{"msg": "hi"})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "hi", bodyAsString(t, res))
+
+		res, err = call(web.MethodPost, "", nil, "/small", map[string]string{"msg": "too long for the limit"})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+
+		msg := strings.Repeat("x", int(ctx.MaxBodySize))
+		res, err = call(web.MethodPost, "", nil, "/large", map[string]string{"msg": msg})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, msg, bodyAsString(t, res))
+	})
+}
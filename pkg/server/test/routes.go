@@ -24,6 +24,15 @@ func registerRoutes(srv *server.Server) {
 
 	srv.Route(web.MethodGet, "/param-test/:name/:age", func(c ctx.Ctx) error {
 		return c.SendString(fmt.Sprintf("Hello, %s! You are %s years old.", c.Param("name"), c.Param("age")))
+	}).Name("param-test")
+
+	srv.Route(web.MethodGet, "/url-for-test", func(c ctx.Ctx) error {
+		u, err := c.URLFor("param-test", "name", c.Param("name"), "age", 30, "lang", "en")
+		if err != nil {
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, u)
 	})
 
 	srv.Route(web.MethodGet, "/param-query-test", func(c ctx.Ctx) error {
@@ -0,0 +1,106 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/middleware"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listUsers(c ctx.Ctx) error {
+	return c.SendString("users")
+}
+
+func TestRoutes(t *testing.T) {
+	srv := server.New(host, port, timeoutSeconds)
+	srv.Use(middleware.NewRecovery())
+
+	api := srv.Group("/api", middleware.NewSecurityHeaders())
+	api.Route(web.MethodGet, "/users", listUsers,
+		server.WithName("users"),
+		server.WithTags("users"),
+		server.WithDescription("Lists the users"),
+		server.WithAuth(middleware.NewBasicAuth("admin", "secret")),
+	)
+	srv.Route(web.MethodGet, "/debug/routes", srv.RoutesHandler())
+	srv.StaticFS("/assets", fstest.MapFS{})
+
+	routes := srv.Routes()
+	require.Len(t, routes, 4)
+
+	assert.Equal(t, server.RouteInfo{
+		Method:      "GET",
+		Pattern:     "/api/users",
+		Name:        "users",
+		Prefix:      "/api",
+		Handler:     "test.listUsers",
+		Middleware:  []string{"middleware.NewRecovery", "middleware.NewSecurityHeaders", "middleware.NewBasicAuth"},
+		Auth:        true,
+		Tags:        []string{"users"},
+		Description: "Lists the users",
+	}, routes[0])
+
+	assert.Equal(t, "/assets/", routes[1].Pattern)
+	assert.Equal(t, "fstest.MapFS", routes[1].Static)
+	assert.Empty(t, routes[1].Middleware)
+
+	assert.Equal(t, "/debug/routes", routes[2].Pattern)
+	assert.Equal(t, "server.(*Server).RoutesHandler", routes[2].Handler)
+	assert.Equal(t, "/server/ready", routes[3].Pattern)
+	assert.Equal(t, "server.(*Server).ready", routes[3].Handler)
+
+	var b strings.Builder
+	require.NoError(t, srv.PrintRoutes(&b))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Regexp(t, `^METHOD\s+PATTERN\s+NAME\s+HANDLER\s+MIDDLEWARE$`, lines[0])
+	assert.Regexp(t, `^GET\s+/api/users\s+users\s+test.listUsers\s+middleware.NewRecovery, `, lines[1])
+	assert.Regexp(t, `^GET\s+/assets/\s+-\s+static fstest.MapFS\s+-$`, lines[2])
+
+	// background start
+	go func() {
+		t.Log("starting server")
+		err := srv.Start()
+		if err != nil {
+			t.Logf("Server: %s", err.Error())
+		}
+	}()
+	srv.WaitUntilReady()
+
+	t.Cleanup(func() {
+		// stop gracefully
+		err := srv.Stop()
+		require.NoError(t, err, "stopping server")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		res, err := call(web.MethodGet, web.MIMEApplicationJSON, nil, "/debug/routes", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got []server.RouteInfo
+		bodyAsJSON(t, res, &got)
+		assert.Equal(t, routes, got)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		res, err := call(web.MethodGet, web.MIMETextPlain, nil, "/debug/routes", nil)
+		require.NoError(t, err)
+		assert.Equal(t, b.String(), bodyAsString(t, res))
+	})
+
+	t.Run("html", func(t *testing.T) {
+		res, err := call(web.MethodGet, web.MIMETextHTML, nil, "/debug/routes", nil)
+		require.NoError(t, err)
+		body := bodyAsString(t, res)
+		assert.Contains(t, body, "<th>Pattern</th>")
+		assert.Contains(t, body, "<td>/api/users</td>")
+	})
+}
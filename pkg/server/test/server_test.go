@@ -71,6 +71,42 @@ func TestServer(t *testing.T) {
 		checkLogHas(t, logWriter, logger.LevelError, http.StatusNotFound, "Resource not found")
 	})
 
+	t.Run("method not allowed", func(t *testing.T) {
+		res, err := call(web.MethodPost, "", nil, "/hello", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+		assert.Equal(t, "GET, HEAD, OPTIONS", res.Header.Get(web.HeaderAllow))
+		body := bodyAsString(t, res)
+		assert.Equal(t, "TestErrorHandler: 405 Method not allowed", body)
+		checkLogHas(t, logWriter, logger.LevelError, http.StatusMethodNotAllowed, "Method not allowed")
+
+		res, err = call(web.MethodPost, "", nil, "/not-found", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		checkLogHas(t, logWriter, logger.LevelError, http.StatusNotFound, "Resource not found")
+	})
+
+	t.Run("automatic options", func(t *testing.T) {
+		res, err := call(web.MethodOptions, "", nil, "/post-json-test", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		assert.Equal(t, "OPTIONS, POST", res.Header.Get(web.HeaderAllow))
+
+		// answered by the CORS middleware, limited to the route methods
+		methods := res.Header.Get(web.HeaderAccessControlAllowMethods)
+		assert.Contains(t, methods, http.MethodPost)
+		assert.NotContains(t, methods, http.MethodGet)
+	})
+
+	t.Run("head for get routes", func(t *testing.T) {
+		res, err := call(web.MethodHead, "", nil, "/hello", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.NotEmpty(t, res.Header.Get(web.HeaderXRequestID))
+		assert.Empty(t, bodyAsString(t, res))
+		checkLogHas(t, logWriter, logger.LevelInfo, http.StatusOK, "")
+	})
+
 	t.Run("error 500", func(t *testing.T) {
 		res, err := call(http.MethodGet, "", nil, "/error/500", nil)
 		require.NoError(t, err)
@@ -111,6 +147,15 @@ func TestServer(t *testing.T) {
 		checkLogHas(t, logWriter, logger.LevelInfo, http.StatusOK, "")
 	})
 
+	t.Run("url for named route", func(t *testing.T) {
+		res, err := call(http.MethodGet, "", nil, "/url-for-test?name=John%20Smith", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "/param-test/John%20Smith/30?lang=en", res.Request.URL.RequestURI())
+		body := bodyAsString(t, res)
+		assert.Equal(t, "Hello, John Smith! You are 30 years old.", body)
+	})
+
 	t.Run("query params", func(t *testing.T) {
 		res, err := call(http.MethodGet, "", nil, "/param-query-test?name=John&age=30", nil)
 		require.NoError(t, err)
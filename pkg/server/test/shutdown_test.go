@@ -0,0 +1,97 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerShutdown(t *testing.T) {
+	srv, err := server.NewWithOptions(
+		server.WithAddr("localhost:0"),
+		server.WithDrainDelay(300*time.Millisecond),
+		server.WithShutdownTimeout(5*time.Second),
+	)
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	srv.Route(web.MethodGet, "/slow", func(c ctx.Ctx) error {
+		close(started)
+		time.Sleep(600 * time.Millisecond)
+		return c.SendString("done")
+	})
+
+	var order []string
+	errHook := errors.New("cache flush failed")
+	srv.OnShutdown(
+		func(ctx context.Context) error {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			order = append(order, "db")
+			return nil
+		},
+		func(context.Context) error {
+			order = append(order, "cache")
+			return errHook
+		},
+	)
+	srv.OnShutdown(func(context.Context) error {
+		order = append(order, "logs")
+		return nil
+	})
+
+	go func() {
+		if err := srv.Start(); err != nil {
+			t.Logf("Server: %s", err.Error())
+		}
+	}()
+	srv.WaitUntilReady()
+	require.True(t, srv.IsReady())
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	baseURL := "http://" + srv.Addr().String()
+
+	slow := make(chan *http.Response, 1)
+	go func() {
+		res, err := client.Get(baseURL + "/slow")
+		assert.NoError(t, err)
+		slow <- res
+	}()
+	<-started
+	assert.Equal(t, int64(1), srv.InFlight())
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(ctx)
+	}()
+
+	// draining: still serving, but not ready
+	require.Eventually(t, srv.ShuttingDown, time.Second, 10*time.Millisecond)
+	res, err := client.Get(baseURL + "/server/ready")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, "Shutting down", bodyAsString(t, res))
+	assert.False(t, srv.IsReady())
+
+	err = <-shutdownErr
+	require.ErrorIs(t, err, errHook)
+	assert.Equal(t, []string{"db", "cache", "logs"}, order)
+
+	res = <-slow
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "done", bodyAsString(t, res))
+	assert.Equal(t, int64(0), srv.InFlight())
+
+	_, err = client.Get(baseURL + "/server/ready")
+	require.Error(t, err, "the listener is closed")
+}
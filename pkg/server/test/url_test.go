@@ -0,0 +1,75 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/jorgefuertes/martian-stack/pkg/server"
+	"github.com/jorgefuertes/martian-stack/pkg/server/ctx"
+	"github.com/jorgefuertes/martian-stack/pkg/server/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURL(t *testing.T) {
+	srv := server.New(host, port, timeoutSeconds)
+	noop := func(c ctx.Ctx) error { return nil }
+
+	srv.Route(web.MethodGet, "/users/:id", noop).Name("user.show")
+	srv.Route(web.MethodGet, "/files/{path...}", noop).Name("file")
+	srv.Route(web.MethodGet, "/home/{$}", noop).Name("home")
+	srv.Group("/api/v1").Route(web.MethodPut, "/posts/{post}/tags/{tag}", noop).Name("post.tag")
+
+	t.Run("fills path params and query", func(t *testing.T) {
+		u, err := srv.URL("user.show", "id", 42, "tab", "my posts", "sort", "-date")
+		require.NoError(t, err)
+		assert.Equal(t, "/users/42?sort=-date&tab=my+posts", u)
+	})
+
+	t.Run("escapes path params", func(t *testing.T) {
+		u, err := srv.URL("user.show", "id", "a/b c?")
+		require.NoError(t, err)
+		assert.Equal(t, "/users/a%2Fb%20c%3F", u)
+
+		u, err = srv.URL("file", "path", "docs/my file.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "/files/docs/my%20file.txt", u)
+	})
+
+	t.Run("group prefix and exact match", func(t *testing.T) {
+		u, err := srv.URL("post.tag", "post", 7, "tag", "go")
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/posts/7/tags/go", u)
+
+		u, err = srv.URL("home")
+		require.NoError(t, err)
+		assert.Equal(t, "/home/", u)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := srv.URL("unknown")
+		assert.ErrorIs(t, err, server.ErrRouteNotFound)
+
+		_, err = srv.URL("post.tag", "post", 7)
+		assert.ErrorIs(t, err, server.ErrMissingRouteParam)
+		assert.ErrorContains(t, err, "tag")
+
+		_, err = srv.URL("user.show", "id")
+		assert.ErrorIs(t, err, server.ErrInvalidRouteParams)
+
+		_, err = srv.URL("user.show", 1, 2)
+		assert.ErrorIs(t, err, server.ErrInvalidRouteParams)
+	})
+
+	t.Run("duplicate name panics", func(t *testing.T) {
+		assert.PanicsWithError(t, "duplicate route name: user.show", func() {
+			srv.Route(web.MethodDelete, "/users/:id", noop).Name("user.show")
+		})
+	})
+
+	t.Run("ctx without server", func(t *testing.T) {
+		var c ctx.Ctx
+
+		_, err := c.URLFor("user.show", "id", 1)
+		assert.ErrorIs(t, err, ctx.ErrNoURLBuilder)
+	})
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrDuplicateRouteName = errors.New("duplicate route name")
+	ErrRouteNotFound      = errors.New("route not found")
+	ErrMissingRouteParam  = errors.New("missing route param")
+	ErrInvalidRouteParams = errors.New("route params must be key/value pairs")
+)
+
+// pathParam matches the {param}, {param...} and {$} segments of a path pattern
+var pathParam = regexp.MustCompile(`\{([^}]*)\}`)
+
+// URL builds the path of the route with the given name. Params are key/value pairs:
+// the keys naming a path param fill its segment, the rest go to the query string.
+// Values are formatted with fmt.Sprint and escaped.
+//
+//	srv.Route(web.MethodGet, "/users/:id", showUser).Name("user.show")
+//	srv.URL("user.show", "id", 42, "tab", "posts") // "/users/42?tab=posts"
+func (s *Server) URL(name string, params ...any) (string, error) {
+	s.namesMu.RLock()
+	route, ok := s.names[name]
+	s.namesMu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrRouteNotFound, name)
+	}
+
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("%w: %s", ErrInvalidRouteParams, name)
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("%w: %s, key %v is not a string", ErrInvalidRouteParams, name, params[i])
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+
+	var missing []string
+	path := pathParam.ReplaceAllStringFunc(route.path, func(segment string) string {
+		param := segment[1 : len(segment)-1]
+		if param == "$" {
+			return ""
+		}
+
+		param, wildcard := strings.CutSuffix(param, "...")
+		value, ok := values[param]
+		if !ok {
+			missing = append(missing, param)
+			return segment
+		}
+		delete(values, param)
+
+		if !wildcard {
+			return url.PathEscape(value)
+		}
+
+		// a wildcard spans segments, keep its slashes
+		parts := strings.Split(value, "/")
+		for i, part := range parts {
+			parts[i] = url.PathEscape(part)
+		}
+
+		return strings.Join(parts, "/")
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s needs %s", ErrMissingRouteParam, name, strings.Join(missing, ", "))
+	}
+
+	if len(values) > 0 {
+		query := url.Values{}
+		for key, value := range values {
+			query.Set(key, value)
+		}
+		path += "?" + query.Encode()
+	}
+
+	return path, nil
+}
@@ -0,0 +1,86 @@
+// Code generated by GoHT - DO NOT EDIT.
+// https://github.com/stackus/goht
+
+package view
+
+import "context"
+import "io"
+import "github.com/stackus/goht"
+
+func Table(title string, header []string, rows [][]string) goht.Template {
+	return goht.TemplateFunc(func(ctx context.Context, __w io.Writer) (__err error) {
+		__buf, __isBuf := __w.(goht.Buffer)
+		if !__isBuf {
+			__buf = goht.GetBuffer()
+			defer goht.ReleaseBuffer(__buf)
+		}
+		var __children goht.Template
+		ctx, __children = goht.PopChildren(ctx)
+		_ = __children
+		__var1 := goht.TemplateFunc(func(ctx context.Context, __w io.Writer) (__err error) {
+			__buf, __isBuf := __w.(goht.Buffer)
+			if !__isBuf {
+				__buf = goht.GetBuffer()
+				defer goht.ReleaseBuffer(__buf)
+			}
+			if _, __err = __buf.WriteString("<table>\n<thead>\n<tr>\n"); __err != nil {
+				return
+			}
+			for _, h := range header {
+				if _, __err = __buf.WriteString("<th>"); __err != nil {
+					return
+				}
+				var __var2 string
+				if __var2, __err = goht.CaptureErrors(goht.EscapeString(h)); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString(__var2); __err != nil {
+					return
+				}
+				if _, __err = __buf.WriteString("</th>\n"); __err != nil {
+					return
+				}
+			}
+			if _, __err = __buf.WriteString("</tr>\n</thead>\n<tbody>\n"); __err != nil {
+				return
+			}
+			for _, row := range rows {
+				if _, __err = __buf.WriteString("<tr>\n"); __err != nil {
+					return
+				}
+				for _, cell := range row {
+					if _, __err = __buf.WriteString("<td>"); __err != nil {
+						return
+					}
+					var __var3 string
+					if __var3, __err = goht.CaptureErrors(goht.EscapeString(cell)); __err != nil {
+						return
+					}
+					if _, __err = __buf.WriteString(__var3); __err != nil {
+						return
+					}
+					if _, __err = __buf.WriteString("</td>\n"); __err != nil {
+						return
+					}
+				}
+				if _, __err = __buf.WriteString("</tr>\n"); __err != nil {
+					return
+				}
+			}
+			if _, __err = __buf.WriteString("</tbody>\n</table>\n"); __err != nil {
+				return
+			}
+			if !__isBuf {
+				_, __err = io.Copy(__w, __buf)
+			}
+			return
+		})
+		if __err = Layout(title).Render(goht.PushChildren(ctx, __var1), __buf); __err != nil {
+			return
+		}
+		if !__isBuf {
+			_, __err = __w.Write(__buf.Bytes())
+		}
+		return
+	})
+}
@@ -72,6 +72,7 @@ const (
 
 const (
 	HeaderContentEncoding = "Content-Encoding" // Sent in the HTTP response. Indicates the compression method applied to the response body by the server (e.g., gzip, br). This helps the client decompress the received data accurately.
+	HeaderAllow           = "Allow"            // Sent in the HTTP response. Lists the methods supported by the target resource, required with 405 Method Not Allowed and returned to OPTIONS requests.
 	HeaderLocation        = "Location"         // This header is present in the response. Instructs the client to redirect to a different URL, often used for error handling (e.g., 301 Moved Permanently) or load balancing purposes.
 	HeaderRange           = "Range"            // Used to request a specific portion of a resource, rather than the entire content. This is particularly beneficial for large files downloads, enabling the client to download only the desired section (e.g., Range: bytes=100-200).
 	HeaderContentRange    = "Content-Range"    // When responding to a range request, the server includes this header to specify the range of bytes delivered in the partial response. This helps the client understand the extent of the received data and potentially make further requests if necessary.
@@ -80,4 +81,6 @@ const (
 	HeaderAcceptCH        = "Accept-CH"        // (Request) Signals the client's support for Client Hints, a mechanism allowing the server to request specific pieces of information from the client before responding. This can improve efficiency by preemptively fetching resources the client is likely to need.
 	HeaderDNT             = "DNT"              // This provides an optional signal from the user regarding their Do Not Track (DNT) preference. The header value can be either 0 (disabled) or 1 (enabled) and should be sent in the request. While not mandatory for websites to respect this header, it allows users to express their preference for limiting online tracking.
 	HeaderXRequestID      = "X-Request-ID"     // A unique identifier for each request, useful for tracing and debugging across services.
+	HeaderLink            = "Link"             // Sent in the HTTP response. Lists related resources (e.g., next and previous pages) as <url>; rel="next" entries.
+	HeaderXTotalCount     = "X-Total-Count"    // Sent in the HTTP response. Total number of items in a paginated collection, regardless of the page size.
 )